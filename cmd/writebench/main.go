@@ -0,0 +1,69 @@
+// Command writebench measures bulk catalog writes: one commit per item as
+// imports used to write, batched commits one at a time, and batched commits
+// in parallel. It writes to the in-memory repository with a latency added to
+// every commit, and to every item of a commit, standing in for Firestore.
+// BenchmarkCreateItems in the repository package measures the same with go
+// test -bench; this command makes it easy to try other latencies and sizes.
+//
+//	go run ./cmd/writebench -items 2000 -latency 20ms -per-write 1ms
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+)
+
+type scenario struct {
+	name string
+	opts repository.WriteOptions
+}
+
+func main() {
+	items := flag.Int("items", 2000, "number of items written")
+	latency := flag.Duration("latency", 20*time.Millisecond, "time taken by every commit")
+	perWrite := flag.Duration("per-write", time.Millisecond, "time added to a commit for each of its items")
+	concurrency := flag.Int("concurrency", repository.DefaultWriteOptions.Concurrency, "batches committed at a time by the parallel scenario")
+	flag.Parse()
+
+	catalog := make([]models.GroceryItem, *items)
+	for i := range catalog {
+		catalog[i] = models.GroceryItem{ID: i + 1, ProductName: fmt.Sprintf("Item %d", i+1), Category: "Bench", Price: 1.99, ItemPackageQuantity: 1}
+	}
+
+	scenarios := []scenario{
+		{"one commit per item", repository.WriteOptions{BatchSize: 1, Concurrency: 1}},
+		{"batched, sequential", repository.WriteOptions{Concurrency: 1}},
+		{fmt.Sprintf("batched, %d in parallel", *concurrency), repository.WriteOptions{Concurrency: *concurrency}},
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(out, "scenario\telapsed\titems/s\tspeedup\n")
+	var baseline time.Duration
+	for _, s := range scenarios {
+		repo := repository.NewMemoryRepository()
+		repo.SetCommitLatency(*latency, *perWrite)
+		repo.SetWriteOptions(s.opts)
+
+		start := time.Now()
+		for _, err := range repo.CreateItems(context.Background(), catalog) {
+			if err != nil {
+				log.Fatalf("%s: %v", s.name, err)
+			}
+		}
+		elapsed := time.Since(start)
+		if baseline == 0 {
+			baseline = elapsed
+		}
+		fmt.Fprintf(out, "%s\t%v\t%.0f\t%.1fx\n", s.name, elapsed.Round(time.Millisecond),
+			float64(*items)/elapsed.Seconds(), float64(baseline)/float64(elapsed))
+	}
+	out.Flush()
+}
@@ -15,44 +15,52 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/bulkupload": {
+        "/applyPriceSchedules": {
             "post": {
-                "description": "Uploads a file containing grocery items in CSV or JSON format to the server, which then processes and stores the items",
-                "consumes": [
-                    "multipart/form-data"
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
                 ],
+                "description": "Job endpoint, meant to be called periodically (for example by Cloud Scheduler). Applies permanent price changes to the catalog, starts and ends temporary price windows and publishes an audit record for every change. The item stores the price in effect, the price of an active window included, so listings, filters, sorts and search use the price customers pay. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Upload a file with grocery items",
-                "operationId": "bulk-upload",
+                "summary": "Apply scheduled price changes",
+                "operationId": "apply-price-schedules",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "File containing grocery items (CSV or JSON)",
-                        "name": "file",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "File Uploaded to Cloud Storage",
+                    "200": {
+                        "description": "Summary of applied changes",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "string"
+                                "type": "integer"
                             }
                         }
                     },
-                    "400": {
-                        "description": "Invalid request format\" or \"Failed to parse multipart form\" or \"Failed to determine file type\" or \"Failed to get file",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to create Storage client\" or \"Failed to upload file to cloud storage",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -60,17 +68,19 @@ const docTemplate = `{
                 }
             }
         },
-        "/createGroceryItem": {
+        "/backfillUnitPrices": {
             "post": {
-                "description": "Creates a new grocery item and uploads its image to your database. Image is optional, you can add it later by using update method as well. Do provide 'Bearer' before adding authorization token",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
                 ],
+                "description": "Listings sorted by unitPrice are ordered by Firestore on a unit price stored with every item, which items written before it was stored lack and are left out of such listings. This stores it on every item that doesn't have it, or has a stale one. It reads the whole catalog, run it once after deploying. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Create a new grocery item",
-                "operationId": "create-grocery-item",
+                "summary": "Backfill stored unit prices",
+                "operationId": "backfill-unit-prices",
                 "parameters": [
                     {
                         "type": "string",
@@ -78,49 +88,77 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "format": "json",
-                        "description": "JSON data for the grocery item",
-                        "name": "json-data",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Optional: Image file for the grocery item",
-                        "name": "image",
-                        "in": "formData"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Grocery item created successfully",
+                    "200": {
+                        "description": "Number of updated items",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/bulkupload": {
+            "post": {
+                "description": "Stores a file containing grocery items in CSV, JSON, JSON lines or XLSX format in the dataFiles/ folder of the bucket. POST /imports imports a file into the catalog",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Upload a file with grocery items",
+                "operationId": "bulk-upload",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "File containing grocery items (CSV, JSON or XLSX)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "File Uploaded to Cloud Storage",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format\" or \"Failed to parse multipart form\" or \"Failed to determine file type\" or \"Failed to get file",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Failed to create Storage client\" or \"Failed to upload file to cloud storage",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -128,19 +166,19 @@ const docTemplate = `{
                 }
             }
         },
-        "/deleteGroceryItemByID/{id}": {
+        "/cancelPriceChange/{changeId}": {
             "delete": {
                 "security": [
                     {
                         "BearerToken": []
                     }
                 ],
-                "description": "Deletes a grocery item from your database based on the provided ID. Do provide 'Bearer' before adding authorization token",
+                "description": "Cancels a price change that has not been applied yet, or ends a temporary price window early. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete a grocery item by ID",
-                "operationId": "delete-grocery-item-by-id",
+                "summary": "Cancel a scheduled price change",
+                "operationId": "cancel-price-change",
                 "parameters": [
                     {
                         "type": "string",
@@ -150,28 +188,31 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Grocery item ID to be deleted",
-                        "name": "id",
+                        "type": "string",
+                        "description": "ID of the price change",
+                        "name": "changeId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Grocery item deleted successfully",
+                    "200": {
+                        "description": "Price change cancelled",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -182,6 +223,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Price change already applied",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -191,40 +238,37 @@ const docTemplate = `{
                 }
             }
         },
-        "/fetchGroceryItemByID/{id}": {
+        "/cart": {
             "get": {
-                "description": "Fetches a grocery item from the Firestore database based on the provided ID.",
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Returns the cart of the logged in user priced with current prices and promotions. Do provide 'Bearer' before adding authorization token",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Fetch a grocery item by ID",
-                "operationId": "fetch-item-by-id",
+                "summary": "Get the cart",
+                "operationId": "get-cart",
                 "parameters": [
                     {
-                        "minimum": 1,
-                        "type": "integer",
-                        "format": "int64",
-                        "description": "ID of the grocery item",
-                        "name": "id",
-                        "in": "path",
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Grocery item fetched successfully",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.GroceryItem"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "Cart",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/handlers.CartView"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -238,65 +282,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/listGroceryItems": {
-            "get": {
-                "description": "Retrieves a list of grocery items from the Firestore database based on the provided query parameters.",
+        "/cart/items": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Adds quantity units of a grocery item to the cart of the logged in user. Adding an item that is already in the cart increases its quantity. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "List grocery items based on query parameters",
-                "operationId": "list-items-by",
+                "summary": "Add an item to the cart",
+                "operationId": "add-cart-item",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter by product name",
-                        "name": "productName",
-                        "in": "query"
-                    },
-                    {
-                        "type": "number",
-                        "description": "Filter by price",
-                        "name": "price",
-                        "in": "query"
-                    },
-                    {
-                        "type": "number",
-                        "description": "Filter by minimum price",
-                        "name": "price_min",
-                        "in": "query"
-                    },
-                    {
-                        "type": "number",
-                        "description": "Filter by maximum price",
-                        "name": "price_max",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by category",
-                        "name": "Category",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "format": "int32",
-                        "description": "Number of items per page",
-                        "name": "pageSize",
-                        "in": "query"
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "format": "int32",
-                        "description": "Page number",
-                        "name": "pageNumber",
-                        "in": "query"
+                        "description": "Item and quantity",
+                        "name": "line",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.cartLineRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "List Of Grocery Items",
+                    "200": {
+                        "description": "Cart",
                         "schema": {
-                            "type": "Object"
+                            "$ref": "#/definitions/handlers.CartView"
                         }
                     },
                     "400": {
@@ -312,7 +336,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Grocery item not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -326,22 +350,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/updateGroceryItemByID/{id}": {
+        "/cart/items/{id}": {
             "put": {
                 "security": [
                     {
                         "BearerToken": []
                     }
                 ],
-                "description": "Updates an existing grocery item with the provided information and uploads a new image if provided. Do provide 'Bearer' before adding authorization token",
+                "description": "Sets the quantity of a grocery item in the cart, a quantity of 0 removes the line. Do provide 'Bearer' before adding authorization token",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update an existing grocery item",
-                "operationId": "update-grocery-item",
+                "summary": "Update a cart line",
+                "operationId": "update-cart-item",
                 "parameters": [
                     {
                         "type": "string",
@@ -352,56 +376,103 @@ const docTemplate = `{
                     },
                     {
                         "type": "integer",
-                        "description": "ID of the grocery item to be updated",
+                        "description": "ID of the grocery item",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "format": "json",
-                        "description": "JSON data for the updated grocery item",
-                        "name": "json-data",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Optional: New image file for the updated grocery item",
-                        "name": "image",
-                        "in": "formData"
+                        "description": "New quantity, itemID is taken from the path",
+                        "name": "line",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.cartLineRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Grocery item updated successfully",
+                        "description": "Cart",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.CartView"
                         }
                     },
                     "400": {
-                        "description": "Invalid request format\" or \"Missing fields in the request",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "Token not provided\" or \"Invalid token",
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Grocery item not found",
+                        "description": "Item is not in the cart",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to update grocery item in Firestore\" or \"Failed to publish audit record",
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Removes a grocery item from the cart of the logged in user. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Remove a cart line",
+                "operationId": "remove-cart-item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the grocery item",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cart",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CartView"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -409,184 +480,3862 @@ const docTemplate = `{
                 }
             }
         },
-        "/userLogin": {
+        "/cart/validateCoupon": {
             "post": {
-                "description": "Authenticates a user with the provided email and password, returning a JWT token upon success",
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Checks whether a coupon can be used on the current cart and returns the discount it would give. Nothing is redeemed until checkout. Do provide 'Bearer' before adding authorization token",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Log in a user",
-                "operationId": "login-user",
+                "summary": "Validate a coupon for the cart",
+                "operationId": "validate-cart-coupon",
                 "parameters": [
                     {
-                        "description": "Login credentials (email and password)",
-                        "name": "loginCredentials",
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Coupon code",
+                        "name": "coupon",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.LoginUser"
+                            "$ref": "#/definitions/handlers.couponRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Token",
+                        "description": "Coupon is valid, with the discount and the new total",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Invalid request format",
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "Invalid email or password",
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Coupon can't be used on this cart",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to generate token",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users": {
+        "/checkout": {
             "post": {
-                "description": "Creates a new user with the provided information",
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Places an order for everything in the cart of the logged in user. Stock is reserved, except for items without a stock which are not tracked, line prices are snapshotted on the order and the cart is emptied. An optional coupon is redeemed in the same transaction, so a single-use coupon can't be used by two checkouts at once. The order total is then authorized and captured with the payment gateway; the order only moves to placed when the capture succeeds, otherwise it is marked payment-failed and the stock is released. An order a coupon makes free is placed without calling the gateway. Do provide 'Bearer' before adding authorization token",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Create a new user",
-                "operationId": "create-new-user",
+                "summary": "Place an order",
+                "operationId": "checkout",
                 "parameters": [
                     {
-                        "description": "User object to be created",
-                        "name": "user",
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment token from the gateway and an optional coupon code",
+                        "name": "payment",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/handlers.checkoutRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "User Created Successfully. userID",
+                        "description": "Order placed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Order"
                         }
                     },
                     "400": {
-                        "description": "Invalid request format\" or \"Missing fields in the request",
+                        "description": "Cart is empty",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment failed",
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    },
+                    "409": {
+                        "description": "Not enough stock, or the order was released while it was being paid",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Coupon can't be used on this cart",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error\" or \"Failed to create user in Firestore",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Payments are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "integer"
-                },
-                "message": {
-                    "type": "string"
-                }
-            }
         },
-        "handlers.GroceryItem": {
-            "type": "object",
-            "properties": {
-                "brand": {
-                    "type": "string"
-                },
-                "category": {
-                    "type": "string"
-                },
-                "countryOfOrigin": {
-                    "type": "string"
-                },
-                "expDate": {
-                    "type": "object",
-                    "properties": {
-                        "month": {
-                            "type": "integer"
-                        },
-                        "year": {
-                            "type": "integer"
-                        }
+        "/coupons": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
                     }
-                },
-                "itemPackageQuantity": {
-                    "type": "integer"
-                },
-                "manufacturer": {
-                    "type": "string"
-                },
-                "mfgDate": {
-                    "type": "object",
-                    "properties": {
-                        "month": {
-                            "type": "integer"
-                        },
-                        "year": {
-                            "type": "integer"
+                ],
+                "description": "Lists every coupon with its redemption count. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List coupons",
+                "operationId": "list-coupons",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Coupons",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Coupon"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
-                },
-                "packageInformation": {
-                    "type": "string"
-                },
-                "price": {
-                    "type": "number"
-                },
-                "productName": {
-                    "type": "string"
-                },
-                "vegetarian": {
-                    "type": "boolean"
-                },
-                "weight": {
-                    "type": "number"
-                },
-                "weightUnit": {
-                    "type": "string"
                 }
-            }
-        },
-        "models.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "integer"
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Creates a coupon code. type is percentage or flat. maxRedemptions 1 makes it single-use, 0 unlimited. Restrict it to categories or brands to only discount those lines. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a coupon",
+                "operationId": "create-coupon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Coupon",
+                        "name": "coupon",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Coupon"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Coupon created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Coupon"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Coupon code already exists",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/coupons/{code}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Deactivates a coupon. It is kept so its redemption history stays available. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Deactivate a coupon",
+                "operationId": "deactivate-coupon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Coupon code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Coupon deactivated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/coupons/{code}/redemptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Lists the redemptions of a coupon, newest first, with the order each one belongs to. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Coupon redemption history",
+                "operationId": "coupon-redemptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Coupon code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Redemptions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CouponRedemption"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/createGroceryItem": {
+            "post": {
+                "description": "Creates a new grocery item and uploads its image to your database. Image is optional, you can add it later by using update method as well. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a new grocery item",
+                "operationId": "create-grocery-item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "json",
+                        "description": "JSON data for the grocery item",
+                        "name": "json-data",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Optional: Image file for the grocery item",
+                        "name": "image",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Grocery item created successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/createPromotion": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Creates a promotion rule. type is one of percentage, flat, bogo (buy one get one) or multibuy (buyQuantity for the price of payQuantity). Scope it with itemIDs, categories, brands or tags; an empty scope applies to the whole catalog. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a promotion",
+                "operationId": "create-promotion",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Promotion rule",
+                        "name": "promotion",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Promotion"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Promotion created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Promotion"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/deleteGroceryItemByID/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Deletes a grocery item from your database based on the provided ID. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a grocery item by ID",
+                "operationId": "delete-grocery-item-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Grocery item ID to be deleted",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Grocery item deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/deletePromotion/{promotionId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Deletes a promotion so it no longer applies. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a promotion",
+                "operationId": "delete-promotion",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the promotion",
+                        "name": "promotionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Promotion deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Streams the grocery items matching the filters as CSV, a JSON array, JSON lines or an XLSX workbook. Filters and sort take the same form as in /listGroceryItems,\ne.g. category=Snacks\u0026price[lte]=100\u0026sort=-price; items are in ID order unless sorted. fields selects the columns, all of them by default.\nDates are MM/YYYY and tags are separated by ; in CSV and XLSX; the workbook has a frozen header row and typed number, boolean and date cells. Every format can be imported back with POST /imports.\nThe items are read a page at a time while the file is sent, a failure half way cuts the download short. Large exports are better run in the background with POST /exports.\nDo provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "text/csv",
+                    "application/json",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "summary": "Export the catalog",
+                "operationId": "export-catalog",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv (default), json, jsonl or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated columns, e.g. id,productName,price,stock",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields, - for descending order, e.g. category,-price",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter, any field of /listGroceryItems can be used with the same operators",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Catalog file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/exports": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Exports the grocery items matching the filters to a file kept in the bucket, with the parameters of GET /export.\nThe response is the queued job, follow it with GET /exports/{jobId}; once succeeded its downloadURL serves the file. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Start a background export",
+                "operationId": "create-export-job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv (default), json, jsonl or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated columns, e.g. id,productName,price,stock",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields, - for descending order, e.g. category,-price",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter, any field of /listGroceryItems can be used with the same operators",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Queued export job",
+                        "schema": {
+                            "$ref": "#/definitions/exporter.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/exports/{jobId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Returns the status of an export job (queued, running, succeeded or failed) and the items written so far, with the downloadURL of the file once succeeded. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Fetch an export job",
+                "operationId": "fetch-export-job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the export job",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Export job",
+                        "schema": {
+                            "$ref": "#/definitions/exporter.Job"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/exports/{jobId}/download": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Downloads the file written by a succeeded export job. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "text/csv",
+                    "application/json",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "summary": "Download an export",
+                "operationId": "download-export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the export job",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Catalog file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The job has not succeeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/fetchGroceryItemByID/{id}": {
+            "get": {
+                "description": "Fetches a grocery item from the Firestore database based on the provided ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Fetch a grocery item by ID",
+                "operationId": "fetch-item-by-id",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "format": "int64",
+                        "description": "ID of the grocery item",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 timestamp or date (2006-01-02), returns the price effective at that time instead of now",
+                        "name": "asOf",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also return originalPrice, effectivePrice and appliedPromotions",
+                        "name": "withPromotions",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to return, e.g. id,productName,price,brand,expDate. Every field is returned by default",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Grocery item fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GroceryItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/imageImports": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Attaches every image (JPEG or PNG) of a ZIP archive to a grocery item, replacing its image and thumbnail.\nA manifest.csv in the archive says which item each file is for: a file column, and either an id column or columns of other fields matching exactly one item (e.g. barcode, or productName and brand).\nWithout a manifest, file names say it: 12.jpg or 12_front.jpg for item 12, and names of 8 digits or more, e.g. 8901058851472.jpg, for the item with that barcode.\nEach image is hashed, and skipped when it is a duplicate: the same image as an earlier file of the archive, already the image of the item, or the image of another item.\nThe report gives the outcome of every file with the reason of the skipped and failed ones. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Import images from a ZIP archive",
+                "operationId": "import-images",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "ZIP archive of images, with an optional manifest.csv",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outcome of every file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ImageImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/importProfiles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Lists the saved import profiles by name. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List import profiles",
+                "operationId": "list-import-profiles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import profiles",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/importer.Profile"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Saves how the files of a supplier are read, to import them with profile={profileId}. columns maps headers to fields (e.g. \"MRP\": \"price\", \"Net Wt\": \"weight\"),\ntransforms reads a field with unit (weight with its unit, e.g. \"500 g\"), currency (e.g. \"₹1,299.00\"), lowercase or uppercase,\ndateFormats are tried for mfgDate and expDate before the usual formats (e.g. DD/MM/YYYY, MMM-YY), trueWords and falseWords are read as vegetarian values (e.g. Veg, Non-Veg),\nand defaults fill fields the rows have no value for (e.g. \"countryOfOrigin\": \"India\"). Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Save an import profile",
+                "operationId": "create-import-profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Import profile, id, createdBy and createdAt are set by the server",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/importer.Profile"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Saved profile",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Profile"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/importProfiles/{profileId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Deletes an import profile. Jobs created with it keep the profile as it was. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete an import profile",
+                "operationId": "delete-import-profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the import profile",
+                        "name": "profileId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Imports a CSV file with a header row, a JSON array, JSON lines or an XLSX workbook, sent as the file field of a multipart form or as the request body.\nA workbook is read from its first sheet unless sheet is set; the header is the first row naming at least two fields, rows above it such as a title are skipped.\nDate cells and dates typed as text both work, numbers are read as stored rather than as displayed.\nThe format is detected from the file name or content unless format is set. Columns are matched to fields by name ignoring case and punctuation,\ne.g. Product Name or product_name for productName. Dates are MM/YYYY or YYYY-MM, tags in CSV are separated by ; or |.\nRows are matched with the catalog by key, productName, brand, weight and weightUnit unless set (e.g. key=barcode); the first key field has to match exactly, the others ignoring case.\nonConflict says what happens to a matching row: skip (default), overwrite the fields the file has columns for, merge the non-empty values of the row, or fail the row.\nWithout a column for every required field (after the mapping of the profile and its defaults), the import stops before the first row unless onConflict is merge or overwrite.\nRows matching nothing are validated and created with a new ID, rows repeating the key of an earlier row are skipped. Every update is audited.\nWith dryRun=true nothing is written: the job ends previewed, its report (GET /imports/{jobId}/report) says what each row would do with the changes of every updated item,\nand POST /imports/{jobId}/approve runs the import of the same file with the same options.\nThe file is imported in the background: the response is the queued job, follow it with GET /imports/{jobId}.\nWith sync=true small files are imported right away and the report is returned instead. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Import grocery items",
+                "operationId": "import-grocery-items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "CSV, JSON, JSON lines or XLSX file, the request body is read when missing",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv, json, jsonl or xlsx, detected when not set",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name or 1-based position of the sheet of a workbook to import",
+                        "name": "sheet",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields identifying a product, e.g. barcode",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "skip, overwrite, merge or fail",
+                        "name": "onConflict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the import profile reading the columns, see POST /importProfiles",
+                        "name": "profile",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Parse, validate and match the rows without writing anything",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Import before responding and return the report",
+                        "name": "sync",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import report, with sync=true",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Report"
+                        }
+                    },
+                    "202": {
+                        "description": "Queued import job",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "With sync=true, the file could not be read to the end, the report covers the rows before",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Report"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/{jobId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Returns the status of an import job (queued, running, succeeded, failed or cancelled) and the rows handled so far. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Fetch an import job",
+                "operationId": "fetch-import-job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the import job",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import job",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Job"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/{jobId}/approve": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Queues the import of the file of a previewed dry run, with the same options. The job goes back to queued and ends like any import. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Approve an import preview",
+                "operationId": "approve-import-job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the import job",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Queued import job",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Job"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The job is not a previewed dry run",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/{jobId}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Cancels a queued import job or a previewed dry run, or stops a running one after its current row. Items already written stay in the catalog. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Cancel an import job",
+                "operationId": "cancel-import-job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the import job",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import job",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Job"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The job already finished",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/{jobId}/errors": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Lists the failed and skipped rows of a finished import job with the reasons, as JSON or as CSV with format=csv. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "summary": "Import error report",
+                "operationId": "import-job-errors",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the import job",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Failed and skipped rows",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/importer.RowResult"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The job has not finished",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/{jobId}/report": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Returns the report of a finished or previewed import job: the counts and the outcome of every row, with the field changes of updated items.\nstatus keeps the rows with that outcome only, e.g. status=updated to review the changes of a dry run. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Import report",
+                "operationId": "fetch-import-report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the import job",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "created, updated, unchanged, skipped or failed",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import report",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Report"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The job has not finished",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/items": {
+            "get": {
+                "description": "Fetches up to 500 grocery items in one call. Items come back in the order of ids, IDs that don't exist are listed in missing.\nUse POST /items/batch when the list doesn't fit in a URL.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Fetch grocery items by ID",
+                "operationId": "fetch-items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated item IDs, e.g. 1,5,9",
+                        "name": "ids",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to return, e.g. id,productName,price. Every field is returned by default",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also return originalPrice, effectivePrice and appliedPromotions for each item",
+                        "name": "withPromotions",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Grocery items",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BatchFetchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/items/batch": {
+            "post": {
+                "description": "Same as GET /items, with the IDs in the body.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Fetch grocery items by ID",
+                "operationId": "fetch-items-batch",
+                "parameters": [
+                    {
+                        "description": "IDs to fetch",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.batchFetchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Grocery items",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BatchFetchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/listGroceryItems": {
+            "get": {
+                "description": "Retrieves a list of grocery items based on the provided query parameters.\nFilters take the form field=value or field[op]=value with op one of eq, ne, gt, gte, lt, lte or in (comma separated values),\ne.g. price[gte]=10\u0026price[lt]=50\u0026vegetarian=true\u0026category[in]=Snacks,Dairy. Every filter has to match.\nFilterable fields: id, productName, category, price, weight, weightUnit, vegetarian, manufacturer, brand, itemPackageQuantity,\npackageInformation, countryOfOrigin, stock and tags (eq means contains, in means contains any). Unknown fields are rejected.\nsort takes a comma separated list of fields, prefix a field with - for descending order, e.g. sort=category,-price,productName.\nAny filterable field except tags can be sorted on, as well as unitPrice, the price per kg or litre.\nfacets counts the items matching the filters per value of category, brand, vegetarian, countryOfOrigin or any other text field,\nand per range of a number field given as field:bound-bound-..., e.g. price:0-50-100-500 counts 0-50, 50-100, 100-500 and 500+.\nRange and yes/no facets are exact. Text facets are counted over the first 5000 matching items, and marked partial when more items match.\nWithout sort, items are ordered by the fields with range filters. id always breaks ties. Pass nextPageToken back as pageToken to get the next page.\nRange filters (ne, gt, gte, lt, lte) can only be on one field, and a sort has to start with that field. Other combinations are rejected with 400.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List grocery items based on query parameters",
+                "operationId": "list-items-by",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by product name",
+                        "name": "productName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Filter by price",
+                        "name": "price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Filter by minimum price, same as price[gte]",
+                        "name": "price_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Filter by maximum price, same as price[lte]",
+                        "name": "price_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by vegetarian",
+                        "name": "vegetarian",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to return, keyed by their names, e.g. id,productName,price,brand,expDate. Without it items have the keys ID, ProductName, Price, Category and Thumbnail, as before fields was added",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order, e.g. category,-price,productName",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Facets to count over all matching items, e.g. category,brand,vegetarian,countryOfOrigin,price:0-50-100-500",
+                        "name": "facets",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "format": "int32",
+                        "description": "Number of items per page, 10 by default and at most 100",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextPageToken of the previous page",
+                        "name": "pageToken",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "format": "int32",
+                        "description": "Page number, for older clients, can't be combined with pageToken",
+                        "name": "pageNumber",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also return totalCount, the number of items matching the filters",
+                        "name": "includeTotal",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also return originalPrice, effectivePrice and appliedPromotions for each item, OriginalPrice, EffectivePrice and AppliedPromotions without fields",
+                        "name": "withPromotions",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of grocery items",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GroceryItemPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Page tokens are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/listPromotions": {
+            "get": {
+                "description": "Lists all active promotions, including the ones that have not started yet or have already ended",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List promotions",
+                "operationId": "list-promotions",
+                "responses": {
+                    "200": {
+                        "description": "Promotions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Promotion"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Lists the latest 100 saved search notifications of the logged in user, newest first. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Notification inbox",
+                "operationId": "list-notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only return notifications that weren't marked read",
+                        "name": "unread",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Notifications",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Notification"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/{notificationId}/read": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Marks a notification as read so it no longer shows with unread=true. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Mark a notification read",
+                "operationId": "mark-notification-read",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the notification",
+                        "name": "notificationId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Marked read",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Lists the orders of the logged in user, newest first. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List my orders",
+                "operationId": "list-orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Orders",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Order"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{orderId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Fetches an order of the logged in user by its ID. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Fetch an order",
+                "operationId": "fetch-order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the order",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order",
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{orderId}/status": {
+            "put": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Moves a paid order through packed, out-for-delivery and delivered. Customers can only cancel their own orders before they are out for delivery; other changes need an admin or manager. Orders waiting for their payment can't be cancelled. Cancelling releases the reserved stock and refunds captured payments. placed and payment-failed are only set by the payment flow. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update the status of an order",
+                "operationId": "update-order-status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the order",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.orderStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order",
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Invalid status transition",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/webhook": {
+            "post": {
+                "description": "Receives signed notifications from the payment gateway. The signature is read from the X-Payment-Signature header. A capture.succeeded event moves an order waiting for payment to placed, capture.failed marks it payment-failed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Payment gateway webhook",
+                "operationId": "payment-webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Signature of the payload",
+                        "name": "X-Payment-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook event",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentWebhookEvent"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook processed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Payments are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/priceHistory/{id}": {
+            "get": {
+                "description": "Returns every recorded and scheduled price change of a grocery item ordered by effectiveFrom, together with the price effective now or at asOf",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Price history of a grocery item",
+                "operationId": "price-history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the grocery item",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 timestamp or date (2006-01-02) to resolve the price at",
+                        "name": "asOf",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Price history",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/releaseStaleOrders": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Job endpoint, meant to be called periodically (for example by Cloud Scheduler). Orders waiting for their payment for more than 30 minutes, e.g. because the server stopped during checkout, are marked payment-failed, which releases their stock and coupon. A payment captured for such an order is refunded. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Release orders stuck waiting for payment",
+                "operationId": "release-stale-orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of released and refunded orders",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Payments are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/savedSearches": {
+            "get": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Lists the searches saved by the logged in user, newest first. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List saved searches",
+                "operationId": "list-saved-searches",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Saved searches",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SavedSearch"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Saves list filters under a name. The user gets a notification in their inbox when an item starts matching them (new-match),\nor gets cheaper while matching them (price-drop). At most 20 searches per user. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Save a search",
+                "operationId": "create-saved-search",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Name and filters, e.g. {\\",
+                        "name": "search",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.savedSearchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Saved search",
+                        "schema": {
+                            "$ref": "#/definitions/models.SavedSearch"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/savedSearches/{searchId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Deletes a saved search, its notifications stay in the inbox. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a saved search",
+                "operationId": "delete-saved-search",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the saved search",
+                        "name": "searchId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedulePriceChange/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Schedules a price change for a grocery item. effectiveFrom and effectiveTo are RFC3339 timestamps, leave effectiveTo empty for a permanent change. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Schedule a price change",
+                "operationId": "schedule-price-change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the grocery item",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Price and effective window",
+                        "name": "priceChange",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.priceChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Price change scheduled",
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceChange"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/search": {
+            "get": {
+                "description": "Full-text search over productName, brand, manufacturer, category and packageInformation.\nWords are matched regardless of case and plural form, partial words match as prefixes and small typos are tolerated.\nItems matching every word come first, ranked by relevance. The list filters (e.g. price[lte]=100\u0026vegetarian=true) can be combined with q.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Search grocery items",
+                "operationId": "search-items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search text, e.g. baby care oil",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to return, e.g. id,productName,price. Defaults to id,productName,brand,category,price,thumbnailURL",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Facets to count over all hits, same syntax as in listGroceryItems, e.g. category,vegetarian,price:0-50-100-500",
+                        "name": "facets",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "format": "int32",
+                        "description": "Number of hits per page, 10 by default and at most 100",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "format": "int32",
+                        "description": "Page number",
+                        "name": "pageNumber",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matching grocery items",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SearchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/search/rebuild": {
+            "post": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Reindexes the whole catalog and the typeahead suggestions. The index is kept in sync by the item endpoints and rebuilt periodically, this is for changes made directly in the database. Do provide 'Bearer' before adding authorization token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Rebuild the search index",
+                "operationId": "rebuild-search-index",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of indexed items",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/expiry": {
+            "get": {
+                "description": "Number of items matching the filters expiring in each month, earliest first. Takes the same filters as listGroceryItems.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Expiry statistics",
+                "operationId": "expiry-statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Item counts per month",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repository.MonthCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/prices": {
+            "get": {
+                "description": "Count, min, max, average and median price of the items matching the filters, overall and per category, brand or countryOfOrigin.\nTakes the same filters as listGroceryItems, e.g. price[lte]=100\u0026vegetarian=true. Range filters can only be on price.\nAt most 50 groups are returned, the first ones by value, and truncated is true when more groups match.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Price statistics",
+                "operationId": "price-statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "category (default), brand or countryOfOrigin",
+                        "name": "groupBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by vegetarian",
+                        "name": "vegetarian",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Price statistics",
+                        "schema": {
+                            "$ref": "#/definitions/repository.PriceStatsResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/vegetarian": {
+            "get": {
+                "description": "Number of vegetarian and non-vegetarian items matching the filters, which are the same as in listGroceryItems.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Vegetarian statistics",
+                "operationId": "vegetarian-statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Item counts",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VegetarianStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/suggest": {
+            "get": {
+                "description": "Completes a prefix with product names, brands and categories, e.g. \"hal\" gives Haldiram's (brand) and Haldiram's Bhujia (product).\nAny word of a name can be completed; completions of the start of a name rank first, then brands and categories before products, bigger ones first.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Typeahead suggestions",
+                "operationId": "suggest-items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Typed prefix",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "format": "int32",
+                        "description": "Number of suggestions, 8 by default and at most 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Suggestions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/search.Suggestion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/updateGroceryItemByID/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerToken": []
+                    }
+                ],
+                "description": "Updates an existing grocery item with the provided information and uploads a new image if provided. Do provide 'Bearer' before adding authorization token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update an existing grocery item",
+                "operationId": "update-grocery-item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the grocery item to be updated",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "json",
+                        "description": "JSON data for the updated grocery item",
+                        "name": "json-data",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Optional: New image file for the updated grocery item",
+                        "name": "image",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Grocery item updated successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format\" or \"Missing fields in the request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Token not provided\" or \"Invalid token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Grocery item not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update grocery item in Firestore\" or \"Failed to publish audit record",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/userLogin": {
+            "post": {
+                "description": "Authenticates a user with the provided email and password, returning a JWT token upon success",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Log in a user",
+                "operationId": "login-user",
+                "parameters": [
+                    {
+                        "description": "Login credentials (email and password)",
+                        "name": "loginCredentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginUser"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid email or password",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to generate token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "post": {
+                "description": "Creates a new user with the provided information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a new user",
+                "operationId": "create-new-user",
+                "parameters": [
+                    {
+                        "description": "User object to be created",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User Created Successfully. userID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request format\" or \"Missing fields in the request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error\" or \"Failed to create user in Firestore",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "exporter.Job": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "downloadURL": {
+                    "description": "set when the file can be downloaded",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "description": "written so far",
+                    "type": "integer"
+                },
+                "request": {
+                    "$ref": "#/definitions/exporter.Request"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "exporter.Request": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "description": "comma separated columns, DefaultColumns when empty",
+                    "type": "string"
+                },
+                "filters": {
+                    "description": "filter parameters, e.g. category=Snacks\u0026price[lte]=100",
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "sort": {
+                    "description": "e.g. category,-price, by id when empty",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.BatchFetchResult": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {}
+                },
+                "missing": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "handlers.CartView": {
+            "type": "object",
+            "properties": {
+                "discount": {
+                    "type": "number"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrderLine"
+                    }
+                },
+                "subtotal": {
+                    "type": "number"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GroceryItem": {
+            "type": "object",
+            "properties": {
+                "brand": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "countryOfOrigin": {
+                    "type": "string"
+                },
+                "expDate": {
+                    "type": "object",
+                    "properties": {
+                        "month": {
+                            "type": "integer"
+                        },
+                        "year": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "itemPackageQuantity": {
+                    "type": "integer"
+                },
+                "manufacturer": {
+                    "type": "string"
+                },
+                "mfgDate": {
+                    "type": "object",
+                    "properties": {
+                        "month": {
+                            "type": "integer"
+                        },
+                        "year": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "packageInformation": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "productName": {
+                    "type": "string"
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "vegetarian": {
+                    "type": "boolean"
+                },
+                "weight": {
+                    "type": "number"
+                },
+                "weightUnit": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GroceryItemPage": {
+            "type": "object",
+            "properties": {
+                "facets": {
+                    "description": "only when facets is set",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.FacetResult"
+                    }
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "additionalProperties": true
+                    }
+                },
+                "nextPageToken": {
+                    "description": "empty on the last page",
+                    "type": "string"
+                },
+                "totalCount": {
+                    "description": "only when includeTotal=true",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ImageImportReport": {
+            "type": "object",
+            "properties": {
+                "attached": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "files": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ImageImportResult"
+                    }
+                },
+                "skipped": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ImageImportResult": {
+            "type": "object",
+            "properties": {
+                "file": {
+                    "type": "string"
+                },
+                "itemId": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "description": "why the file was skipped or failed",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "attached, skipped or failed",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PaymentWebhookEvent": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "orderID": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "capture.succeeded, capture.failed or refund.succeeded",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SearchResult": {
+            "type": "object",
+            "properties": {
+                "facets": {
+                    "description": "counted over every hit, not only this page",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.FacetResult"
+                    }
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "additionalProperties": true
+                    }
+                },
+                "totalCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.VegetarianStats": {
+            "type": "object",
+            "properties": {
+                "nonVegetarian": {
+                    "type": "integer"
+                },
+                "vegetarian": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.batchFetchRequest": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "description": "same as the fields query parameter",
+                    "type": "string"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "withPromotions": {
+                    "description": "same as the withPromotions query parameter",
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.cartLineRequest": {
+            "type": "object",
+            "properties": {
+                "itemID": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.checkoutRequest": {
+            "type": "object",
+            "properties": {
+                "couponCode": {
+                    "type": "string"
+                },
+                "paymentToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.couponRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.orderStatusRequest": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.priceChangeRequest": {
+            "type": "object",
+            "properties": {
+                "effectiveFrom": {
+                    "type": "string"
+                },
+                "effectiveTo": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.savedSearchRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "notifyOn": {
+                    "description": "new-match and/or price-drop, both when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "query": {
+                    "description": "list filters, e.g. category=Snacks\u0026price[lt]=50\u0026vegetarian=true",
+                    "type": "string"
+                }
+            }
+        },
+        "importer.Change": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "from": {},
+                "to": {}
+            }
+        },
+        "importer.Job": {
+            "type": "object",
+            "properties": {
+                "approvedAt": {
+                    "type": "string"
+                },
+                "approvedBy": {
+                    "type": "string"
+                },
+                "cancelRequested": {
+                    "type": "boolean"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "dryRun": {
+                    "description": "cleared when the preview is approved",
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "format": {
+                    "description": "detected when the job runs if empty",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "onConflict": {
+                    "type": "string"
+                },
+                "profile": {
+                    "description": "as it was when the job was created",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/importer.Profile"
+                        }
+                    ]
+                },
+                "progress": {
+                    "$ref": "#/definitions/importer.Progress"
+                },
+                "sheet": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "blob holding the file",
+                    "type": "string"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "importer.Profile": {
+            "type": "object",
+            "properties": {
+                "columns": {
+                    "description": "header to field, e.g. \"MRP\": \"price\", matched ignoring case and punctuation",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "dateFormats": {
+                    "description": "tried before the usual formats, e.g. DD/MM/YYYY or MMM-YY",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "defaults": {
+                    "description": "field to value for rows without one, e.g. \"countryOfOrigin\": \"India\"",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "falseWords": {
+                    "description": "read as false, e.g. Non-Veg",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "transforms": {
+                    "description": "field to transform, e.g. \"weight\": \"unit\"",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "trueWords": {
+                    "description": "read as true, e.g. Veg",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "importer.Progress": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "unchanged": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "importer.Report": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "dryRun": {
+                    "description": "nothing was written, the outcomes are what the import would do",
+                    "type": "boolean"
+                },
+                "error": {
+                    "description": "why the file wasn't read to the end",
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "missingFields": {
+                    "description": "required fields no column or default fills, CSV only",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/importer.RowResult"
+                    }
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "unchanged": {
+                    "type": "integer"
+                },
+                "unmappedColumns": {
+                    "description": "columns that don't fill any field",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "importer.RowResult": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "what an updated row changes in the item",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/importer.Change"
+                    }
+                },
+                "itemID": {
+                    "type": "integer"
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "row": {
+                    "description": "1-based, not counting the CSV header",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Coupon": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "brands": {
+                    "description": "when set, only lines of these brands are discounted",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "categories": {
+                    "description": "when set, only lines of these categories are discounted",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "code": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "description": "zero value - never expires",
+                    "type": "string"
+                },
+                "maxRedemptions": {
+                    "description": "1 for single-use, 0 for unlimited",
+                    "type": "integer"
+                },
+                "minCartValue": {
+                    "description": "cart total after promotions",
+                    "type": "number"
+                },
+                "perUserLimit": {
+                    "description": "0 for unlimited",
+                    "type": "integer"
+                },
+                "redemptions": {
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "percentage or flat",
+                    "type": "string"
+                },
+                "value": {
+                    "description": "percent off or amount off the eligible lines",
+                    "type": "number"
+                }
+            }
+        },
+        "models.CouponRedemption": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "discount": {
+                    "type": "number"
+                },
+                "orderID": {
+                    "type": "string"
+                },
+                "redeemedAt": {
+                    "type": "string"
+                },
+                "released": {
+                    "description": "the order was cancelled or not paid, the use was given back",
+                    "type": "boolean"
+                },
+                "userEmail": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
                 },
                 "message": {
                     "type": "string"
@@ -604,6 +4353,295 @@ const docTemplate = `{
                 }
             }
         },
+        "models.Notification": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "event": {
+                    "description": "new-match or price-drop",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "itemID": {
+                    "type": "integer"
+                },
+                "previousPrice": {
+                    "description": "price-drop only",
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "productName": {
+                    "type": "string"
+                },
+                "read": {
+                    "type": "boolean"
+                },
+                "savedSearchID": {
+                    "type": "string"
+                },
+                "searchName": {
+                    "type": "string"
+                },
+                "userEmail": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Order": {
+            "type": "object",
+            "properties": {
+                "couponCode": {
+                    "type": "string"
+                },
+                "couponDiscount": {
+                    "description": "included in Discount",
+                    "type": "number"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "discount": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrderLine"
+                    }
+                },
+                "paymentProvider": {
+                    "type": "string"
+                },
+                "paymentReference": {
+                    "description": "capture reference at the provider, used for refunds",
+                    "type": "string"
+                },
+                "paymentStatus": {
+                    "description": "authorized, captured, failed, refunded or refund-failed",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending-payment, payment-failed, placed, packed, out-for-delivery, delivered or cancelled",
+                    "type": "string"
+                },
+                "statusHistory": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrderStatusChange"
+                    }
+                },
+                "subtotal": {
+                    "type": "number"
+                },
+                "total": {
+                    "type": "number"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "userEmail": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OrderLine": {
+            "type": "object",
+            "properties": {
+                "appliedPromotions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "discount": {
+                    "type": "number"
+                },
+                "effectivePrice": {
+                    "description": "average unit price after promotions",
+                    "type": "number"
+                },
+                "itemID": {
+                    "type": "integer"
+                },
+                "lineTotal": {
+                    "type": "number"
+                },
+                "productName": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "reserved": {
+                    "description": "stock was taken at checkout, and is given back when the order is released",
+                    "type": "boolean"
+                },
+                "unitPrice": {
+                    "description": "price effective at the time, before promotions",
+                    "type": "number"
+                }
+            }
+        },
+        "models.OrderStatusChange": {
+            "type": "object",
+            "properties": {
+                "changedAt": {
+                    "type": "string"
+                },
+                "changedBy": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PriceChange": {
+            "type": "object",
+            "properties": {
+                "appliedAt": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "effectiveFrom": {
+                    "type": "string"
+                },
+                "effectiveTo": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "itemID": {
+                    "type": "integer"
+                },
+                "previousPrice": {
+                    "description": "filled in when the change is applied, an active window gives it back when it ends",
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "source": {
+                    "description": "schedule - created by a price schedule, update - direct edit of the item",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, active, applied, expired or cancelled",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Promotion": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "brands": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "buyQuantity": {
+                    "description": "multibuy only - buy this many ...",
+                    "type": "integer"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "endsAt": {
+                    "description": "zero value - no end date",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "itemIDs": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "payQuantity": {
+                    "description": "... and pay for this many, e.g. 3 for 2",
+                    "type": "integer"
+                },
+                "priority": {
+                    "description": "higher priority promotions are evaluated first",
+                    "type": "integer"
+                },
+                "stackable": {
+                    "description": "can be combined with other stackable promotions",
+                    "type": "boolean"
+                },
+                "startsAt": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "description": "percentage, flat, bogo or multibuy",
+                    "type": "string"
+                },
+                "value": {
+                    "description": "percent off for percentage, amount off per unit for flat",
+                    "type": "number"
+                }
+            }
+        },
+        "models.SavedSearch": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "notifyOn": {
+                    "description": "new-match and/or price-drop, both when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "query": {
+                    "type": "string"
+                },
+                "userEmail": {
+                    "type": "string"
+                }
+            }
+        },
         "models.User": {
             "type": "object",
             "properties": {
@@ -622,6 +4660,110 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "repository.FacetResult": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "partial": {
+                    "type": "boolean"
+                },
+                "values": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.FacetValue"
+                    }
+                }
+            }
+        },
+        "repository.FacetValue": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.MonthCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "month": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.PriceStats": {
+            "type": "object",
+            "properties": {
+                "avg": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "group": {
+                    "description": "value of the grouping field, empty for all items",
+                    "type": "string"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "median": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                }
+            }
+        },
+        "repository.PriceStatsResult": {
+            "type": "object",
+            "properties": {
+                "groupBy": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.PriceStats"
+                    }
+                },
+                "overall": {
+                    "$ref": "#/definitions/repository.PriceStats"
+                },
+                "truncated": {
+                    "description": "more than maxStatsGroups groups, the rest are left out",
+                    "type": "boolean"
+                }
+            }
+        },
+        "search.Suggestion": {
+            "type": "object",
+            "properties": {
+                "itemCount": {
+                    "description": "items of the brand or category, 1 for products",
+                    "type": "integer"
+                },
+                "itemID": {
+                    "description": "the product, for product suggestions",
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "product, brand or category",
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,171 @@
+// Package exporter writes grocery items of the catalog to files, one item at
+// a time, with the columns and value formats the importer reads back.
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+)
+
+// File formats
+const (
+	FormatCSV   = "csv"
+	FormatJSON  = "json"  // a JSON array of objects
+	FormatJSONL = "jsonl" // one JSON object per line
+	FormatXLSX  = "xlsx"  // an Excel workbook with one sheet
+)
+
+// ErrUnknownFormat is returned for a format that can't be exported
+var ErrUnknownFormat = errors.New("format must be csv, json, jsonl or xlsx")
+
+// DefaultColumns are exported when no columns are asked for, in the order of
+// the fields of an item
+var DefaultColumns = []string{
+	"id", "productName", "category", "price", "weight", "weightUnit", "vegetarian",
+	"imageURL", "thumbnailURL", "manufacturer", "brand", "itemPackageQuantity",
+	"packageInformation", "mfgDate", "expDate", "countryOfOrigin", "tags", "stock", "barcode",
+}
+
+// Columns parses a comma separated list of columns to export, e.g.
+// id,productName,price. An empty value selects DefaultColumns.
+func Columns(value string) ([]repository.Field, error) {
+	return repository.ParseFields(value, DefaultColumns...)
+}
+
+// Request describes an export: the format, the columns, and which items in
+// which order, with the filter and sort syntax of the catalog listing
+type Request struct {
+	Format  string `json:"format"`
+	Fields  string `json:"fields,omitempty"`  // comma separated columns, DefaultColumns when empty
+	Sort    string `json:"sort,omitempty"`    // e.g. category,-price, by id when empty
+	Filters string `json:"filters,omitempty"` // filter parameters, e.g. category=Snacks&price[lte]=100
+}
+
+// requestOptions are the parameters of an export request that are not filters
+var requestOptions = []string{"format", "fields", "sort"}
+
+// ParseRequest reads an export request from query parameters, the format
+// defaults to CSV
+func ParseRequest(values url.Values) (Request, error) {
+	filters := url.Values{}
+	for key, value := range values {
+		filters[key] = value
+	}
+	for _, key := range requestOptions {
+		filters.Del(key)
+	}
+
+	request := Request{
+		Format:  strings.ToLower(values.Get("format")),
+		Fields:  values.Get("fields"),
+		Sort:    values.Get("sort"),
+		Filters: filters.Encode(),
+	}
+	if request.Format == "" {
+		request.Format = FormatCSV
+	}
+	if _, _, err := request.Query(); err != nil {
+		return Request{}, err
+	}
+	return request, nil
+}
+
+// Query compiles the request into the listing query and the columns
+func (r Request) Query() (repository.Query, []repository.Field, error) {
+	switch r.Format {
+	case FormatCSV, FormatJSON, FormatJSONL, FormatXLSX:
+	default:
+		return repository.Query{}, nil, ErrUnknownFormat
+	}
+	columns, err := Columns(r.Fields)
+	if err != nil {
+		return repository.Query{}, nil, err
+	}
+	values, err := url.ParseQuery(r.Filters)
+	if err != nil {
+		return repository.Query{}, nil, err
+	}
+	filters, err := repository.ParseFilters(values)
+	if err != nil {
+		return repository.Query{}, nil, err
+	}
+	sortKeys, err := repository.ParseSort(r.Sort)
+	if err != nil {
+		return repository.Query{}, nil, err
+	}
+	return repository.Query{Filters: filters, Sort: sortKeys, Fields: columns}, columns, nil
+}
+
+// Writer writes items to a file
+type Writer interface {
+	Write(item models.GroceryItem) error
+	// Close writes what is left of the file, it has to be called once all
+	// items are written
+	Close() error
+}
+
+// NewWriter writes the given columns of items to w in format
+func NewWriter(format string, w io.Writer, columns []repository.Field) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatJSON:
+		return newJSONWriter(w, columns, true), nil
+	case FormatJSONL:
+		return newJSONWriter(w, columns, false), nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType is the media type of files in format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSON:
+		return "application/json"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// pageSize is how many items are read from the catalog at a time
+const pageSize = 500
+
+// Export writes the items selected by q to w, reading the catalog a page at a
+// time after the last item written, so the catalog is never held in memory.
+// It returns how many items were written. q.Limit and q.Offset are ignored.
+func Export(ctx context.Context, repo repository.GroceryItemRepository, q repository.Query, w Writer) (int, error) {
+	q.Limit, q.Offset, q.After = pageSize, 0, nil
+	written := 0
+	for {
+		items, err := repo.List(ctx, q)
+		if err != nil {
+			return written, err
+		}
+		for _, item := range items {
+			if err := w.Write(item); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if len(items) < pageSize {
+			return written, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		q.After = repository.SortValues(q, items[len(items)-1])
+	}
+}
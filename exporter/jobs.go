@@ -0,0 +1,170 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Job states. queued and running jobs are active, the others are final.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// ErrJobNotFound is returned for an unknown job ID
+var ErrJobNotFound = errors.New("export job not found")
+
+// Job is an export running in the background, the file of which is stored
+// in the blob store
+type Job struct {
+	ID          string    `json:"id" firestore:"-"`
+	Status      string    `json:"status"`
+	Request     Request   `json:"request"`
+	Filename    string    `json:"filename"`
+	Items       int       `json:"items"`                               // written so far
+	Blob        string    `json:"-"`                                   // file in the blob store, once succeeded
+	DownloadURL string    `json:"downloadURL,omitempty" firestore:"-"` // set when the file can be downloaded
+	Error       string    `json:"error,omitempty"`
+	CreatedBy   string    `json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt"`
+}
+
+// JobStore keeps export jobs
+type JobStore interface {
+	CreateJob(ctx context.Context, job Job) (string, error)
+	Job(ctx context.Context, id string) (Job, error)
+	// UpdateJob applies change to the stored job atomically. An error returned
+	// by change aborts the update and is returned.
+	UpdateJob(ctx context.Context, id string, change func(job *Job) error) (Job, error)
+	Close() error
+}
+
+// MemoryJobStore keeps jobs in memory, for local development
+type MemoryJobStore struct {
+	mu     sync.Mutex
+	jobs   map[string]Job
+	nextID int
+}
+
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: map[string]Job{}}
+}
+
+func (m *MemoryJobStore) CreateJob(ctx context.Context, job Job) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	job.ID = fmt.Sprintf("export-%d", m.nextID)
+	m.jobs[job.ID] = job
+	return job.ID, nil
+}
+
+func (m *MemoryJobStore) Job(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+func (m *MemoryJobStore) UpdateJob(ctx context.Context, id string, change func(job *Job) error) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	if err := change(&job); err != nil {
+		return Job{}, err
+	}
+	m.jobs[id] = job
+	return job, nil
+}
+
+// Close is a no-op, the jobs live as long as the process
+func (m *MemoryJobStore) Close() error {
+	return nil
+}
+
+const exportJobsCollection = "exportJobs"
+
+// FirestoreJobStore keeps jobs in the exportJobs collection
+type FirestoreJobStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreJobStore takes ownership of client, Close closes it
+func NewFirestoreJobStore(client *firestore.Client) *FirestoreJobStore {
+	return &FirestoreJobStore{client: client}
+}
+
+func (f *FirestoreJobStore) CreateJob(ctx context.Context, job Job) (string, error) {
+	ref, _, err := f.client.Collection(exportJobsCollection).Add(ctx, job)
+	if err != nil {
+		return "", err
+	}
+	return ref.ID, nil
+}
+
+func (f *FirestoreJobStore) Job(ctx context.Context, id string) (Job, error) {
+	doc, err := f.client.Collection(exportJobsCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return Job{}, ErrJobNotFound
+	}
+	if err != nil {
+		return Job{}, err
+	}
+
+	var job Job
+	if err := doc.DataTo(&job); err != nil {
+		return Job{}, err
+	}
+	job.ID = doc.Ref.ID
+	return job, nil
+}
+
+func (f *FirestoreJobStore) UpdateJob(ctx context.Context, id string, change func(job *Job) error) (Job, error) {
+	ref := f.client.Collection(exportJobsCollection).Doc(id)
+
+	var job Job
+	err := f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		job = Job{}
+		if err := doc.DataTo(&job); err != nil {
+			return err
+		}
+		job.ID = id
+		if err := change(&job); err != nil {
+			return err
+		}
+		return tx.Set(ref, job)
+	})
+	return job, err
+}
+
+func (f *FirestoreJobStore) Close() error {
+	return f.client.Close()
+}
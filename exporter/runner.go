@@ -0,0 +1,147 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"example.com/capstone/importer"
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+)
+
+// progressInterval is how many items are written between two updates of the
+// progress of a job
+const progressInterval = 5000
+
+var errJobNotQueued = errors.New("export job is not queued")
+
+// RepositoryFactory opens the catalog for one job
+type RepositoryFactory func() (repository.GroceryItemRepository, error)
+
+// Runner runs export jobs on a pool of workers, writing their files to the
+// blob store under exports/
+type Runner struct {
+	jobs    JobStore
+	blobs   importer.BlobStore
+	newRepo RepositoryFactory
+	queue   chan string
+}
+
+func NewRunner(jobs JobStore, blobs importer.BlobStore, newRepo RepositoryFactory) *Runner {
+	return &Runner{jobs: jobs, blobs: blobs, newRepo: newRepo, queue: make(chan string, 100)}
+}
+
+// Start starts workers goroutines running the enqueued jobs
+func (r *Runner) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for id := range r.queue {
+				if err := r.Process(context.Background(), id); err != nil {
+					log.Printf("Export job %s: %v", id, err)
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue hands a queued job to the workers without waiting for one to be free
+func (r *Runner) Enqueue(id string) {
+	go func() { r.queue <- id }()
+}
+
+// Process runs a queued job to the end. A job that isn't queued any more is
+// left alone.
+func (r *Runner) Process(ctx context.Context, id string) error {
+	job, err := r.jobs.UpdateJob(ctx, id, func(job *Job) error {
+		if job.Status != JobQueued {
+			return errJobNotQueued
+		}
+		job.Status = JobRunning
+		job.StartedAt = time.Now().UTC()
+		return nil
+	})
+	if err == errJobNotQueued {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	blob := "exports/" + job.ID + "/" + job.Filename
+	count, runErr := r.run(ctx, job, blob)
+	if runErr != nil {
+		r.blobs.Delete(ctx, blob)
+	}
+
+	_, err = r.jobs.UpdateJob(ctx, id, func(job *Job) error {
+		job.FinishedAt = time.Now().UTC()
+		job.Items = count
+		if runErr != nil {
+			job.Status = JobFailed
+			job.Error = runErr.Error()
+			return nil
+		}
+		job.Status = JobSucceeded
+		job.Blob = blob
+		return nil
+	})
+	return err
+}
+
+func (r *Runner) run(ctx context.Context, job Job, blob string) (int, error) {
+	query, columns, err := job.Request.Query()
+	if err != nil {
+		return 0, err
+	}
+	repo, err := r.newRepo()
+	if err != nil {
+		return 0, err
+	}
+	defer repo.Close()
+
+	file, err := r.blobs.Create(ctx, blob)
+	if err != nil {
+		return 0, err
+	}
+	writer, err := NewWriter(job.Request.Format, file, columns)
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+
+	count, err := Export(ctx, repo, query, &progressWriter{Writer: writer, job: job.ID, jobs: r.jobs})
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return count, err
+}
+
+// progressWriter records in the job how many items were written, now and then
+type progressWriter struct {
+	Writer
+	job     string
+	jobs    JobStore
+	written int
+}
+
+func (p *progressWriter) Write(item models.GroceryItem) error {
+	if err := p.Writer.Write(item); err != nil {
+		return err
+	}
+	p.written++
+	if p.written%progressInterval == 0 {
+		_, err := p.jobs.UpdateJob(context.Background(), p.job, func(job *Job) error {
+			job.Items = p.written
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to record the progress of export job %s: %v", p.job, err)
+		}
+	}
+	return nil
+}
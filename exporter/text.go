@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+)
+
+// csvWriter writes a header row of field names, then one row per item with
+// dates as MM/YYYY and tags separated by ;
+type csvWriter struct {
+	writer  *csv.Writer
+	columns []repository.Field
+	record  []string
+}
+
+func newCSVWriter(w io.Writer, columns []repository.Field) (*csvWriter, error) {
+	c := &csvWriter{writer: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		c.record[i] = column.Name
+	}
+	if err := c.writer.Write(c.record); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvWriter) Write(item models.GroceryItem) error {
+	for i, column := range c.columns {
+		c.record[i] = textValue(column, item)
+	}
+	return c.writer.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func textValue(column repository.Field, item models.GroceryItem) string {
+	switch value := column.Value(item).(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case []string:
+		return strings.Join(value, "; ")
+	case models.MonthYear:
+		if value.Year == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%02d/%04d", int(value.Month), value.Year)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// jsonWriter writes one object per item, the fields of which are those of
+// the listing, either as the elements of an array or one per line
+type jsonWriter struct {
+	out     *bufio.Writer
+	columns []repository.Field
+	array   bool
+	written int
+}
+
+func newJSONWriter(w io.Writer, columns []repository.Field, array bool) *jsonWriter {
+	return &jsonWriter{out: bufio.NewWriter(w), columns: columns, array: array}
+}
+
+func (j *jsonWriter) Write(item models.GroceryItem) error {
+	data, err := json.Marshal(repository.Project(item, j.columns))
+	if err != nil {
+		return err
+	}
+
+	prefix, suffix := "", "\n"
+	if j.array {
+		prefix, suffix = ",\n", ""
+		if j.written == 0 {
+			prefix = "[\n"
+		}
+	}
+	j.written++
+
+	// the writer keeps the first error, returned by the last write
+	j.out.WriteString(prefix)
+	j.out.Write(data)
+	_, err = j.out.WriteString(suffix)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if j.array {
+		closing := "\n]\n"
+		if j.written == 0 {
+			closing = "[]\n"
+		}
+		if _, err := j.out.WriteString(closing); err != nil {
+			return err
+		}
+	}
+	return j.out.Flush()
+}
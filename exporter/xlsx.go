@@ -0,0 +1,124 @@
+package exporter
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetName is the name of the sheet items are written to
+const sheetName = "Catalog"
+
+// wideColumns get more room than the others
+var wideColumns = map[string]float64{
+	"productName": 32, "packageInformation": 40, "imageURL": 40, "thumbnailURL": 40,
+	"manufacturer": 24, "brand": 20, "tags": 24,
+}
+
+// xlsxWriter streams the rows to the sheet, large sheets go through
+// temporary files, and the workbook is written out on Close
+type xlsxWriter struct {
+	out       io.Writer
+	file      *excelize.File
+	stream    *excelize.StreamWriter
+	columns   []repository.Field
+	dateStyle int
+	row       int
+}
+
+func newXLSXWriter(w io.Writer, columns []repository.Field) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	x, err := setUpSheet(file, columns)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	x.out = w
+	return x, nil
+}
+
+// setUpSheet names the sheet, sizes the columns and writes the header row,
+// frozen so it stays in view when scrolling
+func setUpSheet(file *excelize.File, columns []repository.Field) (*xlsxWriter, error) {
+	if err := file.SetSheetName(file.GetSheetName(0), sheetName); err != nil {
+		return nil, err
+	}
+	headerStyle, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	monthYear := "mm/yyyy"
+	dateStyle, err := file.NewStyle(&excelize.Style{CustomNumFmt: &monthYear})
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := file.NewStreamWriter(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return nil, err
+	}
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		width := 14.0
+		if wide, ok := wideColumns[column.Name]; ok {
+			width = wide
+		}
+		if err := stream.SetColWidth(i+1, i+1, width); err != nil {
+			return nil, err
+		}
+		header[i] = excelize.Cell{StyleID: headerStyle, Value: column.Name}
+	}
+	if err := stream.SetRow("A1", header); err != nil {
+		return nil, err
+	}
+
+	return &xlsxWriter{file: file, stream: stream, columns: columns, dateStyle: dateStyle, row: 1}, nil
+}
+
+func (x *xlsxWriter) Write(item models.GroceryItem) error {
+	x.row++
+	cells := make([]interface{}, len(x.columns))
+	for i, column := range x.columns {
+		cells[i] = x.cell(column, item)
+	}
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.stream.SetRow(cell, cells)
+}
+
+// cell is the value of column as a typed cell: numbers, booleans and dates
+// are stored as such, tags are separated by ;
+func (x *xlsxWriter) cell(column repository.Field, item models.GroceryItem) interface{} {
+	value := column.Value(item)
+	switch value := value.(type) {
+	case models.MonthYear:
+		if value.Year == 0 {
+			return nil
+		}
+		return excelize.Cell{StyleID: x.dateStyle, Value: time.Date(value.Year, value.Month, 1, 0, 0, 0, 0, time.UTC)}
+	case []string:
+		return strings.Join(value, "; ")
+	case float64:
+		if column.Kind == repository.KindInteger {
+			return int(value)
+		}
+	}
+	return value
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.out)
+}
@@ -1,43 +1,76 @@
-// Package helloworld provides a set of Cloud Functions samples.
+// Package helloworld imports the data files dropped in the data bucket.
 package helloworld
 
 import (
-  "context"
-  "fmt"
-  "log"
-  "time"
+	"context"
+	"fmt"
+	"log"
+	"time"
 
-  "github.com/GoogleCloudPlatform/functions-framework-go/functions"
-  "github.com/cloudevents/sdk-go/v2/event"
+	"example.com/capstone/handlers"
+	"example.com/capstone/importer"
+	"example.com/capstone/utils"
+	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+	"github.com/cloudevents/sdk-go/v2/event"
 )
 
 func init() {
-  functions.CloudEvent("HelloStorage", helloStorage)
+	functions.CloudEvent("HelloStorage", helloStorage)
 }
 
 // StorageObjectData contains metadata of the Cloud Storage object.
 type StorageObjectData struct {
-  Bucket         string    `json:"bucket,omitempty"`
-  Name           string    `json:"name,omitempty"`
-  Metageneration int64     `json:"metageneration,string,omitempty"`
-  TimeCreated    time.Time `json:"timeCreated,omitempty"`
-  Updated        time.Time `json:"updated,omitempty"`
+	Bucket         string    `json:"bucket,omitempty"`
+	Name           string    `json:"name,omitempty"`
+	Generation     int64     `json:"generation,string,omitempty"`
+	Metageneration int64     `json:"metageneration,string,omitempty"`
+	TimeCreated    time.Time `json:"timeCreated,omitempty"`
+	Updated        time.Time `json:"updated,omitempty"`
 }
 
-// helloStorage consumes a CloudEvent message and logs details about the changed object.
+// helloStorage consumes a CloudEvent message about a finalized object and
+// imports it into the catalog when it is under dataFiles/. The file is then
+// moved to processed/ or failed/ with a report next to it. The search index
+// and caches of the servers catch up with the imported items on their next
+// refresh.
 func helloStorage(ctx context.Context, e event.Event) error {
-  log.Printf("Event ID: %s", e.ID())
-  log.Printf("Event Type: %s", e.Type())
-
-  var data StorageObjectData
-  if err := e.DataAs(&data); err != nil {
-    return fmt.Errorf("event.DataAs: %v", err)
-  }
-
-  log.Printf("Bucket: %s", data.Bucket)
-  log.Printf("File: %s", data.Name)
-  log.Printf("Metageneration: %d", data.Metageneration)
-  log.Printf("Created: %s", data.TimeCreated)
-  log.Printf("Updated: %s", data.Updated)
-  return nil
+	log.Printf("Event ID: %s", e.ID())
+	log.Printf("Event Type: %s", e.Type())
+
+	var data StorageObjectData
+	if err := e.DataAs(&data); err != nil {
+		return fmt.Errorf("event.DataAs: %v", err)
+	}
+
+	log.Printf("Bucket: %s", data.Bucket)
+	log.Printf("File: %s", data.Name)
+	log.Printf("Generation: %d", data.Generation)
+	if data.Bucket != utils.DataBucket {
+		return nil
+	}
+
+	blobs, err := utils.CreateBlobStore()
+	if err != nil {
+		return err
+	}
+	defer blobs.Close()
+
+	ledger, err := utils.CreateIngestLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	ingester := &importer.Ingester{Blobs: blobs, Ledger: ledger, NewImporter: handlers.NewImporter}
+	ingest, err := ingester.Ingest(ctx, data.Name, data.Generation)
+	if err != nil {
+		// returning the error has the event delivered again
+		return err
+	}
+	if ingest == nil {
+		log.Printf("Skipped %s", data.Name)
+		return nil
+	}
+	log.Printf("Ingested %s: %s", data.Name, ingest.Status)
+	return nil
 }
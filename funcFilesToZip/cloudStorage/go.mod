@@ -1,6 +0,0 @@
-module example.com/gcf
-
-require (
-  github.com/GoogleCloudPlatform/functions-framework-go v1.5.2
-  github.com/cloudevents/sdk-go/v2 v2.5.0
-)
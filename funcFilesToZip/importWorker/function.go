@@ -0,0 +1,66 @@
+// Package importworker runs catalog import jobs as a Cloud Function, for
+// deployments where the server queues jobs with IMPORT_WORKER=function.
+package importworker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"example.com/capstone/handlers"
+	"example.com/capstone/importer"
+	"example.com/capstone/utils"
+	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+// MessagePublishedData contains the full Pub/Sub message
+type MessagePublishedData struct {
+	Message PubSubMessage `json:"message"`
+}
+
+// PubSubMessage is the payload of a Pub/Sub event.
+type PubSubMessage struct {
+	Data []byte `json:"data"`
+}
+
+func init() {
+	functions.CloudEvent("ProcessImportJob", processImportJob)
+}
+
+// processImportJob runs the job named in a message of the importJobs topic.
+// The search index and caches of the servers catch up with the imported items
+// on their next refresh.
+func processImportJob(ctx context.Context, e event.Event) error {
+	var msg MessagePublishedData
+	if err := e.DataAs(&msg); err != nil {
+		return fmt.Errorf("event.DataAs: %v", err)
+	}
+
+	var payload struct {
+		JobID string `json:"jobId"`
+	}
+	if err := json.Unmarshal(msg.Message.Data, &payload); err != nil || payload.JobID == "" {
+		// retrying can't fix a malformed message
+		log.Printf("Ignoring malformed import job message %q", msg.Message.Data)
+		return nil
+	}
+
+	jobs, err := utils.CreateImportJobStore()
+	if err != nil {
+		return err
+	}
+	defer jobs.Close()
+
+	blobs, err := utils.CreateBlobStore()
+	if err != nil {
+		return err
+	}
+	defer blobs.Close()
+
+	runner := importer.NewRunner(jobs, blobs, handlers.NewImporter)
+
+	log.Printf("Processing import job %s", payload.JobID)
+	return runner.Process(ctx, payload.JobID)
+}
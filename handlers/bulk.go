@@ -1,29 +1,25 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
-
-	"encoding/json"
-	"errors"
 	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
-	"strings"
 
 	"cloud.google.com/go/storage"
 
+	"example.com/capstone/importer"
 	"example.com/capstone/utils"
 )
 
 // BulkUpload uploads a file containing grocery items in CSV or JSON format.
 // @Summary Upload a file with grocery items
-// @Description Uploads a file containing grocery items in CSV or JSON format to the server, which then processes and stores the items
+// @Description Stores a file containing grocery items in CSV, JSON, JSON lines or XLSX format in the dataFiles/ folder of the bucket. POST /imports imports a file into the catalog
 // @ID bulk-upload
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "File containing grocery items (CSV or JSON)"
+// @Param file formData file true "File containing grocery items (CSV, JSON or XLSX)"
 // @Success 201 {object} map[string]string "File Uploaded to Cloud Storage"
 // @Failure 400 {object} ErrorResponse "Invalid request format" or "Failed to parse multipart form" or "Failed to determine file type" or "Failed to get file"
 // @Failure 500 {object} ErrorResponse "Failed to create Storage client" or "Failed to upload file to cloud storage"
@@ -57,7 +53,7 @@ func BulkUpload(w http.ResponseWriter, r *http.Request) {
 	defer file.Close()
 
 	// Determine the file type (CSV or JSON) based on content type or file extension
-	fileType, err := determineFileType(file)
+	fileType, err := determineFileType(file, fileHeader.Filename)
 	if err != nil {
 		log.Println("Failed to determine file type:", err)
 		respondWithError(w, http.StatusBadRequest, "Failed to determine file type")
@@ -87,7 +83,7 @@ func BulkUpload(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("File uploaded successfully to %s", cloudStoragePath)
 	respondWithJSON(w, http.StatusCreated, map[string]string{"message": "File Uploaded to Cloud Storage"})
-	log.Print("Response Sent: CreateBulkGroceryItems")
+	log.Print("Response Sent: BulkUpload")
 }
 
 // Function to upload the file to cloud storage
@@ -113,40 +109,15 @@ func uploadToCloudStorage(client *storage.Client, cloudStoragePath string, file
 	return nil
 }
 
-func determineFileType(file multipart.File) (string, error) {
-	// Read the first 512 bytes to determine the file type
-	buffer := make([]byte, 512)
-	_, err := file.Read(buffer)
-	if err != nil {
+// determineFileType tells csv, json, jsonl or xlsx apart and rewinds the file
+func determineFileType(file multipart.File, filename string) (string, error) {
+	head := make([]byte, 512)
+	n, err := file.Read(head)
+	if err != nil && err != io.EOF {
 		return "", err
 	}
-
-	// Check for CSV file signature
-	if isCSV(buffer) {
-		return "csv", nil
-	}
-
-	// Check for JSON file signature
-	if isJSON(buffer) {
-		return "json", nil
-	}
-
-	// If the file type is not recognized, return an error
-	return "", errors.New("unsupported file type")
-}
-
-func isCSV(buffer []byte) bool {
-	// Check if the file starts with the CSV signature
-	csvSignature := []byte{0xEF, 0xBB, 0xBF} // CSV files may have a UTF-8 BOM
-	return bytes.HasPrefix(buffer, csvSignature) || strings.HasPrefix(string(buffer), "sep=") || strings.Contains(string(buffer), ",")
-}
-
-func isJSON(buffer []byte) bool {
-	var js map[string]interface{}
-	err := json.Unmarshal(buffer, &js)
-	if err != nil {
-		log.Printf("JSON Unmarshal error: %v", err)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
 	}
-
-	return err == nil
+	return importer.DetectFormat(filename, head[:n])
 }
@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errItemNotFound = errors.New("grocery item not found")
+
+// CartView is a cart priced with the current prices and promotions
+type CartView struct {
+	Lines    []models.OrderLine `json:"lines"`
+	Subtotal float64            `json:"subtotal"`
+	Discount float64            `json:"discount"`
+	Total    float64            `json:"total"`
+}
+
+type cartLineRequest struct {
+	ItemID   int `json:"itemID"`
+	Quantity int `json:"quantity"`
+}
+
+// GetCart returns the cart of the logged in user.
+// @Summary Get the cart
+// @Description Returns the cart of the logged in user priced with current prices and promotions. Do provide 'Bearer' before adding authorization token
+// @ID get-cart
+// @Produce json
+// @Param Authorization header string true "token"
+// @Success 200 {object} CartView "Cart"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /cart [get]
+// @Security BearerToken
+func GetCart(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	respondWithCart(w, client, email, http.StatusOK)
+}
+
+// AddCartItem adds a grocery item to the cart.
+// @Summary Add an item to the cart
+// @Description Adds quantity units of a grocery item to the cart of the logged in user. Adding an item that is already in the cart increases its quantity. Do provide 'Bearer' before adding authorization token
+// @ID add-cart-item
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param line body cartLineRequest true "Item and quantity"
+// @Success 200 {object} CartView "Cart"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Grocery item not found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /cart/items [post]
+// @Security BearerToken
+func AddCartItem(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	var req cartLineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if req.Quantity < 1 {
+		respondWithError(w, http.StatusBadRequest, "quantity must be at least 1")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	if _, _, err := findGroceryItem(ctx, client, req.ItemID); err == errItemNotFound {
+		respondWithError(w, http.StatusNotFound, "Grocery item not found")
+		return
+	} else if err != nil {
+		log.Print("Failed to read grocery item data from Firestore:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read grocery item data from Firestore")
+		return
+	}
+
+	err = updateCart(ctx, client, email, func(cart *models.Cart) {
+		for i := range cart.Lines {
+			if cart.Lines[i].ItemID == req.ItemID {
+				cart.Lines[i].Quantity += req.Quantity
+				return
+			}
+		}
+		cart.Lines = append(cart.Lines, models.CartLine{ItemID: req.ItemID, Quantity: req.Quantity})
+	})
+	if err != nil {
+		log.Print("Failed to update cart:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update cart")
+		return
+	}
+
+	respondWithCart(w, client, email, http.StatusOK)
+}
+
+// UpdateCartItem sets the quantity of a cart line.
+// @Summary Update a cart line
+// @Description Sets the quantity of a grocery item in the cart, a quantity of 0 removes the line. Do provide 'Bearer' before adding authorization token
+// @ID update-cart-item
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param id path integer true "ID of the grocery item"
+// @Param line body cartLineRequest true "New quantity, itemID is taken from the path"
+// @Success 200 {object} CartView "Cart"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Item is not in the cart"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /cart/items/{id} [put]
+// @Security BearerToken
+func UpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	id, err := itemIDFromPath(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Item ID")
+		return
+	}
+
+	var req cartLineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if req.Quantity < 0 {
+		respondWithError(w, http.StatusBadRequest, "quantity can not be negative")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	found := false
+	err = updateCart(context.Background(), client, email, func(cart *models.Cart) {
+		lines := cart.Lines[:0]
+		for _, line := range cart.Lines {
+			if line.ItemID == id {
+				found = true
+				line.Quantity = req.Quantity
+			}
+			if line.Quantity > 0 {
+				lines = append(lines, line)
+			}
+		}
+		cart.Lines = lines
+	})
+	if err != nil {
+		log.Print("Failed to update cart:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update cart")
+		return
+	}
+	if !found {
+		respondWithError(w, http.StatusNotFound, "Item is not in the cart")
+		return
+	}
+
+	respondWithCart(w, client, email, http.StatusOK)
+}
+
+// RemoveCartItem removes a line from the cart.
+// @Summary Remove a cart line
+// @Description Removes a grocery item from the cart of the logged in user. Do provide 'Bearer' before adding authorization token
+// @ID remove-cart-item
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param id path integer true "ID of the grocery item"
+// @Success 200 {object} CartView "Cart"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /cart/items/{id} [delete]
+// @Security BearerToken
+func RemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	id, err := itemIDFromPath(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Item ID")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	err = updateCart(context.Background(), client, email, func(cart *models.Cart) {
+		lines := cart.Lines[:0]
+		for _, line := range cart.Lines {
+			if line.ItemID != id {
+				lines = append(lines, line)
+			}
+		}
+		cart.Lines = lines
+	})
+	if err != nil {
+		log.Print("Failed to update cart:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update cart")
+		return
+	}
+
+	respondWithCart(w, client, email, http.StatusOK)
+}
+
+func respondWithCart(w http.ResponseWriter, client *firestore.Client, email string, code int) {
+	ctx := context.Background()
+
+	cart, err := fetchCart(ctx, client, email)
+	if err != nil {
+		log.Print("Failed to read cart:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read cart")
+		return
+	}
+
+	view, _, err := priceCart(ctx, client, cart.Lines, time.Now().UTC())
+	if err != nil {
+		log.Print("Failed to price cart:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to price cart")
+		return
+	}
+
+	respondWithJSON(w, code, view)
+}
+
+// fetchCart returns the cart of a user, or an empty one if they don't have one yet
+func fetchCart(ctx context.Context, client *firestore.Client, email string) (models.Cart, error) {
+	cart := models.Cart{UserEmail: email, Lines: []models.CartLine{}}
+
+	doc, err := client.Collection("carts").Doc(email).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return cart, nil
+	}
+	if err != nil {
+		return cart, err
+	}
+
+	err = doc.DataTo(&cart)
+	return cart, err
+}
+
+// updateCart applies change to the cart of a user inside a transaction
+func updateCart(ctx context.Context, client *firestore.Client, email string, change func(cart *models.Cart)) error {
+	cartRef := client.Collection("carts").Doc(email)
+
+	return client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		cart := models.Cart{UserEmail: email}
+
+		doc, err := tx.Get(cartRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := doc.DataTo(&cart); err != nil {
+				return err
+			}
+		}
+
+		change(&cart)
+		cart.UpdatedAt = time.Now().UTC()
+
+		return tx.Set(cartRef, cart)
+	})
+}
+
+// priceCart prices every cart line with the price effective at and the active
+// promotions, and returns the items it loaded on the way
+func priceCart(ctx context.Context, client *firestore.Client, lines []models.CartLine, at time.Time) (CartView, map[int]models.GroceryItem, error) {
+	promotions, err := fetchActivePromotions(ctx, client)
+	if err != nil {
+		return CartView{}, nil, err
+	}
+
+	items := make(map[int]models.GroceryItem, len(lines))
+	changes := make(map[int][]models.PriceChange, len(lines))
+	for _, line := range lines {
+		item, _, err := findGroceryItem(ctx, client, line.ItemID)
+		if err == errItemNotFound {
+			// the item was deleted after it was put in the cart
+			continue
+		}
+		if err != nil {
+			return CartView{}, nil, err
+		}
+		items[line.ItemID] = item
+
+		if changes[line.ItemID], err = fetchPriceChanges(ctx, client, line.ItemID); err != nil {
+			return CartView{}, nil, err
+		}
+	}
+
+	return priceLines(lines, items, changes, promotions, at), items, nil
+}
+
+// priceLines builds the priced view of cart lines from already loaded data,
+// lines whose item is missing are left out
+func priceLines(lines []models.CartLine, items map[int]models.GroceryItem, changes map[int][]models.PriceChange, promotions []models.Promotion, at time.Time) CartView {
+	view := CartView{Lines: []models.OrderLine{}}
+
+	for _, line := range lines {
+		item, ok := items[line.ItemID]
+		if !ok {
+			continue
+		}
+
+		price := resolvePrice(item.Price, changes[line.ItemID], at)
+		breakdown := evaluatePromotions(item, price, line.Quantity, promotions, at)
+
+		view.Lines = append(view.Lines, models.OrderLine{
+			ItemID:            item.ID,
+			ProductName:       item.ProductName,
+			Quantity:          line.Quantity,
+			UnitPrice:         breakdown.OriginalPrice,
+			EffectivePrice:    breakdown.EffectivePrice,
+			LineTotal:         breakdown.LineTotal,
+			Discount:          breakdown.Discount,
+			AppliedPromotions: breakdown.AppliedPromotions,
+		})
+		view.Subtotal += breakdown.OriginalPrice * float64(line.Quantity)
+		view.Discount += breakdown.Discount
+		view.Total += breakdown.LineTotal
+	}
+
+	view.Subtotal = repository.RoundPrice(view.Subtotal)
+	view.Discount = repository.RoundPrice(view.Discount)
+	view.Total = repository.RoundPrice(view.Total)
+
+	return view
+}
+
+// findGroceryItem looks up a grocery item by its ID field
+func findGroceryItem(ctx context.Context, client *firestore.Client, id int) (models.GroceryItem, *firestore.DocumentRef, error) {
+	var item models.GroceryItem
+
+	doc, err := client.Collection("groceryItems").Where("ID", "==", id).Limit(1).Documents(ctx).Next()
+	if err == iterator.Done {
+		return item, nil, errItemNotFound
+	}
+	if err != nil {
+		return item, nil, err
+	}
+
+	err = doc.DataTo(&item)
+	return item, doc.Ref, err
+}
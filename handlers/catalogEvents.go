@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"example.com/capstone/models"
+)
+
+// catalogChanged is called after a grocery item is written so the derived
+// in-memory views of the catalog stay in sync. prev is nil for a new item and
+// cur is nil for a deleted one.
+func catalogChanged(prev, cur *models.GroceryItem) {
+	syncSearchIndex(prev, cur)
+	facetCache.Invalidate()
+	notifySavedSearches(prev, cur)
+}
+
+// itemChange is a write of an item kept for later, with copies of the items
+// since the caller's may change once it returns
+type itemChange struct {
+	prev, cur *models.GroceryItem
+}
+
+func newItemChange(prev, cur *models.GroceryItem) itemChange {
+	var change itemChange
+	if prev != nil {
+		prev := *prev
+		change.prev = &prev
+	}
+	if cur != nil {
+		cur := *cur
+		change.cur = &cur
+	}
+	return change
+}
@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// couponError is a reason a coupon can't be used on a cart
+type couponError struct {
+	reason string
+}
+
+func (e *couponError) Error() string {
+	return e.reason
+}
+
+type couponRequest struct {
+	Code string `json:"code"`
+}
+
+// CreateCoupon creates a coupon code.
+// @Summary Create a coupon
+// @Description Creates a coupon code. type is percentage or flat. maxRedemptions 1 makes it single-use, 0 unlimited. Restrict it to categories or brands to only discount those lines. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID create-coupon
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param coupon body models.Coupon true "Coupon"
+// @Success 201 {object} models.Coupon "Coupon created"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 409 {object} ErrorResponse "Coupon code already exists"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /coupons [post]
+// @Security BearerToken
+func CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isStaff(w, r) {
+		return
+	}
+
+	var coupon models.Coupon
+	if err := json.NewDecoder(r.Body).Decode(&coupon); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	if err := validateCoupon(coupon); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	coupon.Redemptions = 0
+	coupon.Active = true
+	coupon.CreatedAt = time.Now().UTC()
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	_, err = client.Collection("coupons").Doc(coupon.Code).Create(context.Background(), coupon)
+	if status.Code(err) == codes.AlreadyExists {
+		respondWithError(w, http.StatusConflict, "Coupon code already exists")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to create coupon in Firestore:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create coupon in Firestore")
+		return
+	}
+
+	auditRecord := GenerateAuditRecord("coupon-create", coupon.Code)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	respondWithJSON(w, http.StatusCreated, coupon)
+}
+
+// ListCoupons lists coupons.
+// @Summary List coupons
+// @Description Lists every coupon with its redemption count. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID list-coupons
+// @Produce json
+// @Param Authorization header string true "token"
+// @Success 200 {array} models.Coupon "Coupons"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /coupons [get]
+// @Security BearerToken
+func ListCoupons(w http.ResponseWriter, r *http.Request) {
+	if !isStaff(w, r) {
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	iter := client.Collection("coupons").Documents(context.Background())
+
+	coupons := []models.Coupon{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Print("Failed to read coupons from Firestore:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to read coupons from Firestore")
+			return
+		}
+		var coupon models.Coupon
+		if err := doc.DataTo(&coupon); err != nil {
+			log.Print("Failed to parse coupon:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to parse coupon")
+			return
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	respondWithJSON(w, http.StatusOK, coupons)
+}
+
+// DeactivateCoupon stops a coupon from being used.
+// @Summary Deactivate a coupon
+// @Description Deactivates a coupon. It is kept so its redemption history stays available. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID deactivate-coupon
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param code path string true "Coupon code"
+// @Success 200 {object} map[string]string "Coupon deactivated"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /coupons/{code} [delete]
+// @Security BearerToken
+func DeactivateCoupon(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isStaff(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	code := strings.ToUpper(parts[len(parts)-1])
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	_, err = client.Collection("coupons").Doc(code).Update(context.Background(), []firestore.Update{
+		{Path: "Active", Value: false},
+	})
+	if status.Code(err) == codes.NotFound {
+		respondWithError(w, http.StatusNotFound, "Coupon not found")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to deactivate coupon:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to deactivate coupon")
+		return
+	}
+
+	auditRecord := GenerateAuditRecord("coupon-deactivate", code)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Coupon deactivated"})
+}
+
+// CouponRedemptions lists the orders a coupon was used on.
+// @Summary Coupon redemption history
+// @Description Lists the redemptions of a coupon, newest first, with the order each one belongs to. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID coupon-redemptions
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param code path string true "Coupon code"
+// @Success 200 {array} models.CouponRedemption "Redemptions"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /coupons/{code}/redemptions [get]
+// @Security BearerToken
+func CouponRedemptions(w http.ResponseWriter, r *http.Request) {
+	if !isStaff(w, r) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimSuffix(r.URL.Path, "/redemptions"), "/")
+	code := strings.ToUpper(parts[len(parts)-1])
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	iter := client.Collection("couponRedemptions").Where("Code", "==", code).Documents(context.Background())
+
+	redemptions := []models.CouponRedemption{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Print("Failed to read coupon redemptions:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to read coupon redemptions")
+			return
+		}
+		var redemption models.CouponRedemption
+		if err := doc.DataTo(&redemption); err != nil {
+			log.Print("Failed to parse coupon redemption:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to parse coupon redemption")
+			return
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	sort.SliceStable(redemptions, func(i, j int) bool {
+		return redemptions[i].RedeemedAt.After(redemptions[j].RedeemedAt)
+	})
+
+	respondWithJSON(w, http.StatusOK, redemptions)
+}
+
+// ValidateCartCoupon checks a coupon against the cart of the logged in user.
+// @Summary Validate a coupon for the cart
+// @Description Checks whether a coupon can be used on the current cart and returns the discount it would give. Nothing is redeemed until checkout. Do provide 'Bearer' before adding authorization token
+// @ID validate-cart-coupon
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param coupon body couponRequest true "Coupon code"
+// @Success 200 {object} map[string]interface{} "Coupon is valid, with the discount and the new total"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 422 {object} ErrorResponse "Coupon can't be used on this cart"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /cart/validateCoupon [post]
+// @Security BearerToken
+func ValidateCartCoupon(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	var req couponRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	cart, err := fetchCart(ctx, client, email)
+	if err != nil {
+		log.Print("Failed to read cart:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read cart")
+		return
+	}
+
+	view, items, err := priceCart(ctx, client, cart.Lines, now)
+	if err != nil {
+		log.Print("Failed to price cart:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to price cart")
+		return
+	}
+
+	doc, err := client.Collection("coupons").Doc(code).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		respondWithError(w, http.StatusUnprocessableEntity, "Coupon does not exist")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to read coupon:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read coupon")
+		return
+	}
+	var coupon models.Coupon
+	if err := doc.DataTo(&coupon); err != nil {
+		log.Print("Failed to parse coupon:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to parse coupon")
+		return
+	}
+
+	used, err := countCouponUses(client.Collection("couponRedemptions").Where("Code", "==", code).Where("UserEmail", "==", email).Documents(ctx))
+	if err != nil {
+		log.Print("Failed to read coupon redemptions:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read coupon redemptions")
+		return
+	}
+
+	discount, err := evaluateCoupon(coupon, view, items, used, now)
+	if err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"code":     coupon.Code,
+		"discount": discount,
+		"subtotal": view.Subtotal,
+		"total":    repository.RoundPrice(view.Total - discount),
+	})
+}
+
+func validateCoupon(coupon models.Coupon) error {
+	if coupon.Code == "" || strings.ContainsAny(coupon.Code, "/ ") {
+		return &couponError{"code is required and can not contain spaces or slashes"}
+	}
+
+	switch coupon.Type {
+	case promotionTypePercentage:
+		if coupon.Value <= 0 || coupon.Value > 100 {
+			return &couponError{"value of a percentage coupon must be between 0 and 100"}
+		}
+	case promotionTypeFlat:
+		if coupon.Value <= 0 {
+			return &couponError{"value of a flat coupon must be greater than zero"}
+		}
+	default:
+		return &couponError{"type must be percentage or flat"}
+	}
+
+	if coupon.MaxRedemptions < 0 || coupon.PerUserLimit < 0 || coupon.MinCartValue < 0 {
+		return &couponError{"maxRedemptions, perUserLimit and minCartValue can not be negative"}
+	}
+
+	return nil
+}
+
+// evaluateCoupon returns the discount a coupon gives on a priced cart.
+// usedByUser is how many times the user has already redeemed it.
+func evaluateCoupon(coupon models.Coupon, view CartView, items map[int]models.GroceryItem, usedByUser int, at time.Time) (float64, error) {
+	switch {
+	case !coupon.Active:
+		return 0, &couponError{"coupon is no longer active"}
+	case !coupon.ExpiresAt.IsZero() && !at.Before(coupon.ExpiresAt):
+		return 0, &couponError{"coupon has expired"}
+	case coupon.MaxRedemptions > 0 && coupon.Redemptions >= coupon.MaxRedemptions:
+		return 0, &couponError{"coupon has already been used up"}
+	case coupon.PerUserLimit > 0 && usedByUser >= coupon.PerUserLimit:
+		return 0, &couponError{"you have already used this coupon the maximum number of times"}
+	case view.Total < coupon.MinCartValue:
+		return 0, &couponError{fmt.Sprintf("cart total must be at least %.2f to use this coupon", coupon.MinCartValue)}
+	}
+
+	restricted := len(coupon.Categories) > 0 || len(coupon.Brands) > 0
+
+	eligible := 0.0
+	for _, line := range view.Lines {
+		item := items[line.ItemID]
+		if !restricted || containsFold(coupon.Categories, item.Category) || containsFold(coupon.Brands, item.Brand) {
+			eligible += line.LineTotal
+		}
+	}
+	if eligible <= 0 {
+		return 0, &couponError{"no item in the cart is eligible for this coupon"}
+	}
+
+	discount := coupon.Value
+	if coupon.Type == promotionTypePercentage {
+		discount = eligible * coupon.Value / 100
+	}
+
+	return repository.RoundPrice(math.Min(discount, eligible)), nil
+}
+
+// countCouponUses counts redemptions that were not given back
+func countCouponUses(iter *firestore.DocumentIterator) (int, error) {
+	used := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return used, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		var redemption models.CouponRedemption
+		if err := doc.DataTo(&redemption); err != nil {
+			return 0, err
+		}
+		if !redemption.Released {
+			used++
+		}
+	}
+}
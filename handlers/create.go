@@ -20,7 +20,6 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/nfnt/resize"
-	"google.golang.org/api/iterator"
 )
 
 type ErrorResponse struct {
@@ -98,16 +97,15 @@ func CreateGroceryItem(w http.ResponseWriter, r *http.Request) {
 	// 	return
 	// }
 
-	// Create a Firestore client
-	client, err := utils.CreateFirestoreClient()
+	repo, err := utils.CreateGroceryRepository()
 	if err != nil {
-		log.Print("Failed to create Firestore client:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
 		return
 	}
-	defer client.Close()
+	defer repo.Close()
 
-	log.Print("Firestore client created")
+	log.Print("Grocery repository created")
 
 	// Parse the form data with a max of 10 MB limit for the entire request
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
@@ -219,42 +217,20 @@ func CreateGroceryItem(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	// Read existing grocery items from Firestore - collection : "groceryItems"
-	iter := client.Collection("groceryItems").Documents(context.Background())
-	var existingGroceryItems []models.GroceryItem
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Print("Failed to read grocery item data from Firestore:", err)
-			respondWithError(w, http.StatusInternalServerError, "Failed to read grocery item data from Firestore")
-			return
-		}
-		var item models.GroceryItem
-		if err := doc.DataTo(&item); err != nil {
-			log.Print("Failed to parse grocery item data from Firestore:", err)
-			respondWithError(w, http.StatusInternalServerError, "Failed to parse grocery item data from Firestore")
-			return
-		}
-		existingGroceryItems = append(existingGroceryItems, item)
+	// IDs are allocated from a counter, so items created at the same time
+	// don't get the same ID
+	newItemID, err := repo.AllocateIDs(context.Background(), 1)
+	if err != nil {
+		log.Print("Failed to allocate grocery item ID:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to allocate grocery item ID")
+		return
 	}
 
-	log.Print("Existing grocery items read from Firestore")
-
-	// a unique ID for the new grocery item
-	newItemID := generateUniqueGroceryItemID(existingGroceryItems)
-
 	// Set the new grocery item ID
 	groceryItem.ID = newItemID
 
-	// Set the Firestore document ID to the product name
-	// docRef := client.Collection("groceryItems").Doc(groceryItem.ProductName)
-
 	// Add the new grocery item to Firestore
-	_, _, err = client.Collection("groceryItems").Add(context.Background(), groceryItem)
-	if err != nil {
+	if err := repo.CreateItems(context.Background(), []models.GroceryItem{groceryItem})[0]; err != nil {
 		log.Print("Failed to create grocery item in Firestore:", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery item in Firestore")
 		return
@@ -262,25 +238,13 @@ func CreateGroceryItem(w http.ResponseWriter, r *http.Request) {
 
 	log.Print("Grocery item created successfully in Firestore")
 
+	catalogChanged(nil, &groceryItem)
+
 	respondWithJSON(w, http.StatusCreated, map[string]string{"message": "Grocery item created successfully"})
 	log.Print("Response Sent: CreateGroceryItem")
 
 }
 
-func generateUniqueGroceryItemID(existingGroceryItems []models.GroceryItem) int {
-	highestID := 0
-
-	// Find the highest existing ID from the Firestore data
-	for _, item := range existingGroceryItems {
-		if item.ID > highestID {
-			highestID = item.ID
-		}
-	}
-
-	// Increment the highest existing ID to generate a new unique ID
-	return highestID + 1
-}
-
 // Function to upload the image to Cloud Storage
 func uploadImageAndThumbailToCloudStorage(file multipart.File, item models.GroceryItem) (string, string, error) {
 	ctx := context.Background()
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"example.com/capstone/models"
 	"example.com/capstone/utils"
 	"github.com/dgrijalva/jwt-go"
 	"google.golang.org/grpc/codes"
@@ -114,6 +115,12 @@ func DeleteItemByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var deletedItem models.GroceryItem
+	if err := doc.DataTo(&deletedItem); err != nil {
+		log.Print("Failed to parse grocery item data from Firestore:", err)
+		deletedItem.ID = id
+	}
+
 	_, err = doc.Ref.Delete(context.Background())
 	if err != nil {
 		log.Print("failed to delete item from Firestore database:", err)
@@ -121,6 +128,8 @@ func DeleteItemByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	catalogChanged(&deletedItem, nil)
+
 	log.Print("Item deleted successfully")
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Item deleted successfully"})
 	log.Print("Response Sent")
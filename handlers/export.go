@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/capstone/exporter"
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+)
+
+var (
+	exportRunner     *exporter.Runner
+	exportRunnerErr  error
+	exportRunnerOnce sync.Once
+)
+
+// ExportCatalog downloads the catalog as a file.
+// @Summary Export the catalog
+// @Description Streams the grocery items matching the filters as CSV, a JSON array, JSON lines or an XLSX workbook. Filters and sort take the same form as in /listGroceryItems,
+// @Description e.g. category=Snacks&price[lte]=100&sort=-price; items are in ID order unless sorted. fields selects the columns, all of them by default.
+// @Description Dates are MM/YYYY and tags are separated by ; in CSV and XLSX; the workbook has a frozen header row and typed number, boolean and date cells. Every format can be imported back with POST /imports.
+// @Description The items are read a page at a time while the file is sent, a failure half way cuts the download short. Large exports are better run in the background with POST /exports.
+// @Description Do provide 'Bearer' before adding authorization token
+// @ID export-catalog
+// @Produce text/csv
+// @Produce application/json
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param Authorization header string true "token"
+// @Param format query string false "csv (default), json, jsonl or xlsx"
+// @Param fields query string false "Comma separated columns, e.g. id,productName,price,stock"
+// @Param sort query string false "Comma separated fields, - for descending order, e.g. category,-price"
+// @Param category query string false "Filter, any field of /listGroceryItems can be used with the same operators"
+// @Success 200 {file} file "Catalog file"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /export [get]
+// @Security BearerToken
+func ExportCatalog(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	request, err := exporter.ParseRequest(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query, columns, _ := request.Query()
+
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	defer repo.Close()
+
+	if err := repository.CheckQuery(repo, query); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filename := exportFilename(request.Format)
+	w.Header().Set("Content-Type", exporter.ContentType(request.Format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	writer, err := exporter.NewWriter(request.Format, w, columns)
+	if err != nil {
+		log.Print("Failed to create export file:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create export file")
+		return
+	}
+
+	// the status is sent with the first bytes of the file, a failure after that
+	// can only cut the download short
+	count, err := exporter.Export(r.Context(), repo, query, writer)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Printf("Export by %s failed after %d items: %v", email, count, err)
+		return
+	}
+	log.Printf("Exported %d items for %s", count, email)
+
+	auditRecord := GenerateAuditRecord("catalog-export", filename)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+}
+
+// CreateExportJob starts an export in the background.
+// @Summary Start a background export
+// @Description Exports the grocery items matching the filters to a file kept in the bucket, with the parameters of GET /export.
+// @Description The response is the queued job, follow it with GET /exports/{jobId}; once succeeded its downloadURL serves the file. Do provide 'Bearer' before adding authorization token
+// @ID create-export-job
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param format query string false "csv (default), json, jsonl or xlsx"
+// @Param fields query string false "Comma separated columns, e.g. id,productName,price,stock"
+// @Param sort query string false "Comma separated fields, - for descending order, e.g. category,-price"
+// @Param category query string false "Filter, any field of /listGroceryItems can be used with the same operators"
+// @Success 202 {object} exporter.Job "Queued export job"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /exports [post]
+// @Security BearerToken
+func CreateExportJob(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	request, err := exporter.ParseRequest(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the job would fail on a query the catalog can't run, better say so now
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	query, _, _ := request.Query()
+	err = repository.CheckQuery(repo, query)
+	repo.Close()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	runner, err := loadExportRunner()
+	if err != nil {
+		log.Print("Failed to start export runner:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start export runner")
+		return
+	}
+
+	jobs, err := utils.CreateExportJobStore()
+	if err != nil {
+		log.Print("Failed to create export job store:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create export job store")
+		return
+	}
+	defer jobs.Close()
+
+	job := exporter.Job{
+		Status:    exporter.JobQueued,
+		Request:   request,
+		Filename:  exportFilename(request.Format),
+		CreatedBy: email,
+		CreatedAt: time.Now().UTC(),
+	}
+	job.ID, err = jobs.CreateJob(context.Background(), job)
+	if err != nil {
+		log.Print("Failed to create export job:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create export job")
+		return
+	}
+	runner.Enqueue(job.ID)
+
+	auditRecord := GenerateAuditRecord("catalog-export", job.ID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	w.Header().Set("Location", "/exports/"+job.ID)
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// FetchExportJob returns the state of an export job.
+// @Summary Fetch an export job
+// @Description Returns the status of an export job (queued, running, succeeded or failed) and the items written so far, with the downloadURL of the file once succeeded. Do provide 'Bearer' before adding authorization token
+// @ID fetch-export-job
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param jobId path string true "ID of the export job"
+// @Success 200 {object} exporter.Job "Export job"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /exports/{jobId} [get]
+// @Security BearerToken
+func FetchExportJob(w http.ResponseWriter, r *http.Request) {
+	if !isAuthorized(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	job, ok := fetchExportJob(w, parts[len(parts)-1])
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// DownloadExport downloads the file of an export job.
+// @Summary Download an export
+// @Description Downloads the file written by a succeeded export job. Do provide 'Bearer' before adding authorization token
+// @ID download-export
+// @Produce text/csv
+// @Produce application/json
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param Authorization header string true "token"
+// @Param jobId path string true "ID of the export job"
+// @Success 200 {file} file "Catalog file"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 409 {object} ErrorResponse "The job has not succeeded"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /exports/{jobId}/download [get]
+// @Security BearerToken
+func DownloadExport(w http.ResponseWriter, r *http.Request) {
+	if !isAuthorized(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	job, ok := fetchExportJob(w, parts[len(parts)-2])
+	if !ok {
+		return
+	}
+	if job.Status != exporter.JobSucceeded {
+		respondWithError(w, http.StatusConflict, "Export job has not succeeded")
+		return
+	}
+
+	blobs, err := utils.CreateBlobStore()
+	if err != nil {
+		log.Print("Failed to create blob store:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create blob store")
+		return
+	}
+	defer blobs.Close()
+
+	ctx := context.Background()
+	file, err := blobs.Open(ctx, job.Blob)
+	if err != nil {
+		log.Print("Failed to open export file:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to open export file")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", exporter.ContentType(job.Request.Format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.Filename))
+	if attrs, err := blobs.Attrs(ctx, job.Blob); err == nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(attrs.Size, 10))
+	}
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Failed to send export file of job %s: %v", job.ID, err)
+	}
+}
+
+// fetchExportJob reads a job, with its download URL when it succeeded, and
+// responds with the error when it can't
+func fetchExportJob(w http.ResponseWriter, jobID string) (exporter.Job, bool) {
+	jobs, err := utils.CreateExportJobStore()
+	if err != nil {
+		log.Print("Failed to create export job store:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create export job store")
+		return exporter.Job{}, false
+	}
+	defer jobs.Close()
+
+	job, err := jobs.Job(context.Background(), jobID)
+	if err == exporter.ErrJobNotFound {
+		respondWithError(w, http.StatusNotFound, "Export job not found")
+		return exporter.Job{}, false
+	}
+	if err != nil {
+		log.Print("Failed to read export job:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read export job")
+		return exporter.Job{}, false
+	}
+	if job.Status == exporter.JobSucceeded {
+		job.DownloadURL = "/exports/" + job.ID + "/download"
+	}
+	return job, true
+}
+
+func loadExportRunner() (*exporter.Runner, error) {
+	exportRunnerOnce.Do(func() {
+		jobs, err := utils.CreateExportJobStore()
+		if err != nil {
+			exportRunnerErr = err
+			return
+		}
+		blobs, err := utils.CreateBlobStore()
+		if err != nil {
+			jobs.Close()
+			exportRunnerErr = err
+			return
+		}
+
+		exportRunner = exporter.NewRunner(jobs, blobs, utils.CreateGroceryRepository)
+		workers, err := strconv.Atoi(os.Getenv("EXPORT_WORKERS"))
+		if err != nil || workers < 1 {
+			workers = 1
+		}
+		exportRunner.Start(workers)
+	})
+	return exportRunner, exportRunnerErr
+}
+
+func exportFilename(format string) string {
+	return fmt.Sprintf("catalog-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+}
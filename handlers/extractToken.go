@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"example.com/capstone/utils"
 	"github.com/dgrijalva/jwt-go"
 )
 
@@ -30,6 +33,77 @@ func ExtractToken(r *http.Request) string {
 	return tokenParts[1]
 }
 
+// authenticateRequest validates the bearer token of the request and returns the
+// email of the user it was issued to
+func authenticateRequest(r *http.Request) (string, error) {
+	tokenString := ExtractToken(r)
+	if tokenString == "" {
+		return "", errors.New("token not provided")
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(tokenSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return "", errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", errors.New("invalid token claims")
+	}
+
+	email, _ := claims["sub"].(string)
+	return email, nil
+}
+
+// isAuthorized responds with 401 and returns false when the request does not carry a valid token
+func isAuthorized(w http.ResponseWriter, r *http.Request) bool {
+	if _, err := authenticateRequest(r); err != nil {
+		log.Println("Unauthorized request:", err)
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return false
+	}
+	return true
+}
+
+// isStaffRole reports whether role may manage the catalog, prices and orders
+func isStaffRole(role string) bool {
+	return role == "admin" || role == "manager"
+}
+
+// isStaff responds with 401 or 403 and returns false unless the request carries
+// a valid token of an admin or a manager
+func isStaff(w http.ResponseWriter, r *http.Request) bool {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthorized request:", err)
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return false
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return false
+	}
+	defer client.Close()
+
+	role, err := fetchUserRole(context.Background(), client, email)
+	if err != nil {
+		log.Print("Failed to read user role:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read user role")
+		return false
+	}
+	if !isStaffRole(role) {
+		log.Printf("Forbidden request by %s with role %q", email, role)
+		respondWithError(w, http.StatusForbidden, "Only staff can do this")
+		return false
+	}
+	return true
+}
+
 func RefreshToken(w http.ResponseWriter, r *http.Request) {
 	// Extract the token from the request header
 	tokenString := ExtractToken(r)
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"example.com/capstone/models"
+	"example.com/capstone/repository"
 	"example.com/capstone/utils"
 )
 
@@ -17,6 +18,9 @@ import (
 // @ID fetch-item-by-id
 // @Produce json
 // @Param id path integer true "ID of the grocery item" format(int64) minimum(1)
+// @Param asOf query string false "RFC3339 timestamp or date (2006-01-02), returns the price effective at that time instead of now"
+// @Param withPromotions query boolean false "Also return originalPrice, effectivePrice and appliedPromotions"
+// @Param fields query string false "Fields to return, e.g. id,productName,price,brand,expDate. Every field is returned by default"
 // @Success 200 {object} GroceryItem "Grocery item fetched successfully"
 // @Failure 400 {object} ErrorResponse "Bad Request"
 // @Failure 404 {object} ErrorResponse "Not Found"
@@ -33,7 +37,8 @@ func FetchItemByID(w http.ResponseWriter, r *http.Request) {
 
 	utils.InitLogger()
 
-	uri := r.RequestURI
+	// path only, the query string may carry asOf
+	uri := r.URL.Path
 
 	// Split url in parts "/"
 	parts := strings.Split(uri, "/")
@@ -50,40 +55,106 @@ func FetchItemByID(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Print("Requested Id is invalid", err)
 		respondWithError(w, http.StatusBadRequest, "Request Id is invalid")
+		return
+	}
+
+	asOf, err := parseAsOf(r.URL.Query().Get("asOf"))
+	if err != nil {
+		log.Print("Invalid asOf parameter: ", err)
+		respondWithError(w, http.StatusBadRequest, "asOf must be an RFC3339 timestamp or a date (2006-01-02)")
+		return
+	}
+
+	// all fields unless fields is set
+	fields, err := repository.ParseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
+	withPromotions := r.URL.Query().Get("withPromotions") == "true"
 
 	log.Print("Request received: FetchItemByID, ID:", id)
 
-	// connection to firestore
-	client, err := utils.CreateFirestoreClient()
+	repo, err := utils.CreateGroceryRepository()
 	if err != nil {
-		log.Print("Failed to create Firestore client ", err)
-		respondWithError(w, http.StatusBadRequest, "failed to create firestore client")
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
 		return
 	}
-	defer client.Close()
+	defer repo.Close()
 
 	// collection - groceryItem- query by id - return info & img
-	query := client.Collection("groceryItems").Where("ID", "==", id)
-
-	iter := query.Documents(context.Background())
-	doc, err := iter.Next()
+	readFields := fields
+	if withPromotions && len(fields) > 0 {
+		readFields = repository.WithFields(fields, promotionFields...)
+	}
+	groceryItem, err := repo.Get(context.Background(), id, readFields...)
 	if err != nil {
 		log.Print("GroceryItem not found:", err)
 		respondWithError(w, http.StatusBadRequest, "GroceryItem not found, maybe it does not exist")
 		return
 	}
 
-	var groceryItem models.GroceryItem
-	if err := doc.DataTo(&groceryItem); err != nil {
-		log.Print("Failed to parse data: ", err)
-		respondWithError(w, http.StatusBadRequest, "Failed to parse data")
+	// scheduled price changes decide the price at asOf
+	changes, err := repo.PriceChanges(context.Background(), id)
+	if err != nil {
+		log.Print("Failed to read price changes: ", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read price changes")
+		return
+	}
+	groceryItem.Price = resolvePrice(groceryItem.Price, changes, asOf)
+
+	if withPromotions {
+		client, err := utils.CreateFirestoreClient()
+		if err != nil {
+			log.Print("Failed to create Firestore client ", err)
+			respondWithError(w, http.StatusBadRequest, "failed to create firestore client")
+			return
+		}
+		defer client.Close()
+
+		promotions, err := fetchActivePromotions(context.Background(), client)
+		if err != nil {
+			log.Print("Failed to read promotions: ", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to read promotions")
+			return
+		}
+
+		breakdown := evaluatePromotions(groceryItem, groceryItem.Price, 1, promotions, asOf)
+
+		log.Print("Sending response: FetchItemByID")
+		if len(fields) > 0 {
+			projected := repository.Project(groceryItem, fields)
+			projected["originalPrice"] = breakdown.OriginalPrice
+			projected["effectivePrice"] = breakdown.EffectivePrice
+			projected["appliedPromotions"] = breakdown.AppliedPromotions
+			respondWithJSON(w, http.StatusOK, projected)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, promotedGroceryItem{
+			GroceryItem:       groceryItem,
+			OriginalPrice:     breakdown.OriginalPrice,
+			EffectivePrice:    breakdown.EffectivePrice,
+			AppliedPromotions: breakdown.AppliedPromotions,
+		})
 		return
 	}
 
 	log.Print("Sending response: FetchItemByID")
+	if len(fields) > 0 {
+		respondWithJSON(w, http.StatusOK, repository.Project(groceryItem, fields))
+		return
+	}
 	respondWithJSON(w, http.StatusOK, groceryItem)
 
 }
 
+// promotedGroceryItem is a grocery item along with its promotional price
+type promotedGroceryItem struct {
+	models.GroceryItem
+	OriginalPrice     float64  `json:"originalPrice"`
+	EffectivePrice    float64  `json:"effectivePrice"`
+	AppliedPromotions []string `json:"appliedPromotions"`
+}
+
 // may be go routine to return image and thumbnail - async - no need
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+)
+
+// maxBatchIDs is the largest number of IDs one batch fetch accepts
+const maxBatchIDs = 500
+
+// batchFetchRequest is the body of the POST variant of the batch fetch
+type batchFetchRequest struct {
+	IDs            []int  `json:"ids"`
+	Fields         string `json:"fields"`         // same as the fields query parameter
+	WithPromotions bool   `json:"withPromotions"` // same as the withPromotions query parameter
+}
+
+// BatchFetchResult holds the items found, in the order they were requested,
+// and the requested IDs that don't exist
+type BatchFetchResult struct {
+	Items   []interface{} `json:"items"`
+	Missing []int         `json:"missing"`
+}
+
+// FetchItems fetches several grocery items by ID.
+// @Summary Fetch grocery items by ID
+// @Description Fetches up to 500 grocery items in one call. Items come back in the order of ids, IDs that don't exist are listed in missing.
+// @Description Use POST /items/batch when the list doesn't fit in a URL.
+// @ID fetch-items
+// @Produce json
+// @Param ids query string true "Comma separated item IDs, e.g. 1,5,9"
+// @Param fields query string false "Fields to return, e.g. id,productName,price. Every field is returned by default"
+// @Param withPromotions query boolean false "Also return originalPrice, effectivePrice and appliedPromotions for each item"
+// @Success 200 {object} BatchFetchResult "Grocery items"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /items [get]
+func FetchItems(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	var ids []int
+	for _, part := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("ids must be comma separated integers, got %q", part))
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	fetchItems(w, ids, r.URL.Query().Get("fields"), r.URL.Query().Get("withPromotions") == "true")
+}
+
+// FetchItemsBatch fetches several grocery items by ID, for lists too long for a URL.
+// @Summary Fetch grocery items by ID
+// @Description Same as GET /items, with the IDs in the body.
+// @ID fetch-items-batch
+// @Accept json
+// @Produce json
+// @Param request body batchFetchRequest true "IDs to fetch"
+// @Success 200 {object} BatchFetchResult "Grocery items"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /items/batch [post]
+func FetchItemsBatch(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	var req batchFetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	fetchItems(w, req.IDs, req.Fields, req.WithPromotions)
+}
+
+func fetchItems(w http.ResponseWriter, ids []int, fieldsParam string, withPromotions bool) {
+	// a repeated ID is returned once, at its first position
+	var unique []int
+	seen := map[int]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		respondWithError(w, http.StatusBadRequest, "ids is required")
+		return
+	}
+	if len(unique) > maxBatchIDs {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("at most %d ids can be fetched at once", maxBatchIDs))
+		return
+	}
+
+	// all fields unless fields is set
+	fields, err := repository.ParseFields(fieldsParam)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Request received: FetchItems, %d IDs", len(unique))
+
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	readFields := fields
+	if withPromotions && len(fields) > 0 {
+		readFields = repository.WithFields(fields, promotionFields...)
+	}
+	found, err := repo.GetMany(ctx, unique, readFields...)
+	if err != nil {
+		log.Print("Failed to read grocery items:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read grocery items")
+		return
+	}
+
+	var promotions []models.Promotion
+	if withPromotions {
+		client, err := utils.CreateFirestoreClient()
+		if err != nil {
+			log.Print("Failed to create Firestore client:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+			return
+		}
+		defer client.Close()
+
+		promotions, err = fetchActivePromotions(ctx, client)
+		if err != nil {
+			log.Print("Failed to read promotions from Firestore:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to read promotions from Firestore")
+			return
+		}
+	}
+
+	byID := make(map[int]models.GroceryItem, len(found))
+	for _, item := range found {
+		byID[item.ID] = item
+	}
+
+	result := BatchFetchResult{Items: []interface{}{}, Missing: []int{}}
+	now := time.Now().UTC()
+	for _, id := range unique {
+		item, ok := byID[id]
+		if !ok {
+			result.Missing = append(result.Missing, id)
+			continue
+		}
+
+		if !withPromotions {
+			if len(fields) > 0 {
+				result.Items = append(result.Items, repository.Project(item, fields))
+			} else {
+				result.Items = append(result.Items, item)
+			}
+			continue
+		}
+
+		breakdown := evaluatePromotions(item, item.Price, 1, promotions, now)
+		if len(fields) > 0 {
+			projected := repository.Project(item, fields)
+			projected["originalPrice"] = breakdown.OriginalPrice
+			projected["effectivePrice"] = breakdown.EffectivePrice
+			projected["appliedPromotions"] = breakdown.AppliedPromotions
+			result.Items = append(result.Items, projected)
+		} else {
+			result.Items = append(result.Items, promotedGroceryItem{
+				GroceryItem:       item,
+				OriginalPrice:     breakdown.OriginalPrice,
+				EffectivePrice:    breakdown.EffectivePrice,
+				AppliedPromotions: breakdown.AppliedPromotions,
+			})
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+	log.Print("Response Sent: FetchItems")
+}
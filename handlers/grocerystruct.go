@@ -19,5 +19,7 @@ type GroceryItem struct {
 		Month int `json:"month"`
 		Year  int `json:"year"`
 	} `json:"expDate" `
-	CountryOfOrigin string `json:"countryOfOrigin" `
+	CountryOfOrigin string   `json:"countryOfOrigin" `
+	Tags            []string `json:"tags" `
+	Stock           int      `json:"stock" `
 }
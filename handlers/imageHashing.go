@@ -39,7 +39,7 @@ func IsDuplicateImage(imageHash string) bool {
 	cleanedHash := strings.Trim(imageHash, "\"")
 
 	// Query Firestore for documents with the given imageHash
-	iter := client.Collection("groceryItems").Where("ImageHash", "==", cleanedHash).Documents(context.Background())
+	iter := client.Collection("groceryItems").Where("imageHash", "==", cleanedHash).Documents(context.Background())
 
 	// Flag to track duplicate status
 	duplicateFound := false
@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+)
+
+const (
+	// imageManifest names the optional manifest of an image archive
+	imageManifest = "manifest.csv"
+
+	maxImageArchiveSize = 512 << 20
+	maxImageSize        = 20 << 20
+
+	// imageUploadWorkers bounds the images uploaded at the same time
+	imageUploadWorkers = 4
+)
+
+// Outcomes of the files of an image archive
+const (
+	imageAttached = "attached"
+	imageSkipped  = "skipped"
+	imageFailed   = "failed"
+)
+
+// manifestFileColumns name the column of a manifest holding the file names
+var manifestFileColumns = map[string]bool{"file": true, "filename": true, "image": true, "imagefile": true}
+
+// ImageImportResult is what happened to one file of an image archive
+type ImageImportResult struct {
+	File   string `json:"file"`
+	Status string `json:"status"` // attached, skipped or failed
+	ItemID int    `json:"itemId,omitempty"`
+	Reason string `json:"reason,omitempty"` // why the file was skipped or failed
+}
+
+// ImageImportReport sums up an image archive import
+type ImageImportReport struct {
+	Files    int                 `json:"files"`
+	Attached int                 `json:"attached"`
+	Skipped  int                 `json:"skipped"`
+	Failed   int                 `json:"failed"`
+	Results  []ImageImportResult `json:"results"`
+}
+
+// count sums up the results
+func (report *ImageImportReport) count() {
+	report.Files, report.Attached, report.Skipped, report.Failed = len(report.Results), 0, 0, 0
+	for _, result := range report.Results {
+		switch result.Status {
+		case imageAttached:
+			report.Attached++
+		case imageSkipped:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+	}
+}
+
+// ImportImages attaches the images of a ZIP archive to grocery items.
+// @Summary Import images from a ZIP archive
+// @Description Attaches every image (JPEG or PNG) of a ZIP archive to a grocery item, replacing its image and thumbnail.
+// @Description A manifest.csv in the archive says which item each file is for: a file column, and either an id column or columns of other fields matching exactly one item (e.g. barcode, or productName and brand).
+// @Description Without a manifest, file names say it: 12.jpg or 12_front.jpg for item 12, and names of 8 digits or more, e.g. 8901058851472.jpg, for the item with that barcode.
+// @Description Each image is hashed, and skipped when it is a duplicate: the same image as an earlier file of the archive, already the image of the item, or the image of another item.
+// @Description The report gives the outcome of every file with the reason of the skipped and failed ones. Do provide 'Bearer' before adding authorization token
+// @ID import-images
+// @Accept multipart/form-data
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param file formData file true "ZIP archive of images, with an optional manifest.csv"
+// @Success 200 {object} ImageImportReport "Outcome of every file"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /imageImports [post]
+// @Security BearerToken
+func ImportImages(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageArchiveSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		log.Println("Failed to parse multipart form:", err)
+		respondWithError(w, http.StatusBadRequest, "Failed to parse multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to get file")
+		return
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "The file is not a ZIP archive")
+		return
+	}
+
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	defer repo.Close()
+
+	imports := imageArchiveImport{
+		repo:        repo,
+		upload:      uploadImageAndThumbailToCloudStorage,
+		isDuplicate: IsDuplicateImage,
+	}
+	report, err := imports.run(context.Background(), archive)
+	var manifestErr *manifestError
+	if errors.As(err, &manifestErr) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Print("Failed to import images:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to import images")
+		return
+	}
+	log.Printf("Image archive %s by %s: %d attached, %d skipped, %d failed", header.Filename, email, report.Attached, report.Skipped, report.Failed)
+
+	auditRecord := GenerateAuditRecord("bulk-image-import", header.Filename)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// manifestError is a manifest that can't be read, which stops the import
+type manifestError struct {
+	err error
+}
+
+func (e *manifestError) Error() string {
+	return "manifest.csv: " + e.err.Error()
+}
+
+// imageArchiveImport attaches the images of an archive through the image
+// pipeline of the item endpoints
+type imageArchiveImport struct {
+	repo        repository.GroceryItemRepository
+	upload      func(file multipart.File, item models.GroceryItem) (string, string, error)
+	isDuplicate func(imageHash string) bool
+}
+
+// imageTarget is the item a file of the archive is for, found by ID or by
+// filters on other fields
+type imageTarget struct {
+	id      int
+	filters []repository.Filter
+	problem string // why no item can be looked up for the file
+}
+
+// imageFile is an image of the archive on its way to an item
+type imageFile struct {
+	result int // index in the results of the report
+	data   []byte
+	hash   string
+	prev   models.GroceryItem
+	item   models.GroceryItem // prev with the image once uploaded
+}
+
+func (im imageArchiveImport) run(ctx context.Context, archive *zip.Reader) (*ImageImportReport, error) {
+	var images []*zip.File
+	var manifest *zip.File
+	for _, file := range archive.File {
+		name := path.Base(file.Name)
+		switch {
+		case file.FileInfo().IsDir(), strings.HasPrefix(file.Name, "__MACOSX/"), strings.HasPrefix(name, "."):
+		case strings.EqualFold(name, imageManifest) && manifest == nil:
+			manifest = file
+		default:
+			images = append(images, file)
+		}
+	}
+
+	targets := map[string]imageTarget{}
+	if manifest != nil {
+		var err error
+		if targets, err = readImageManifest(manifest); err != nil {
+			return nil, &manifestError{err: err}
+		}
+	}
+
+	report := &ImageImportReport{Results: []ImageImportResult{}}
+	var pending []*imageFile
+	seenHashes := map[string]string{}
+	claimed := map[int]string{}
+	for _, file := range images {
+		target, ok := targets[path.Base(file.Name)]
+		if manifest == nil {
+			target, ok = targetFromFilename(file.Name), true
+		}
+		result := ImageImportResult{File: file.Name}
+		if !ok {
+			result.Status, result.Reason = imageSkipped, "not listed in the manifest"
+			report.Results = append(report.Results, result)
+			continue
+		}
+		delete(targets, path.Base(file.Name))
+
+		data, err := readImageFile(file)
+		if err != nil {
+			result.Status, result.Reason = imageFailed, err.Error()
+			report.Results = append(report.Results, result)
+			continue
+		}
+		hash, _ := CalculateImageHash(imageReader{bytes.NewReader(data)})
+		if seenHashes[hash] != "" {
+			result.Status, result.Reason = imageSkipped, "duplicate of "+seenHashes[hash]+" in the archive"
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		item, problem, err := im.findItem(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		result.ItemID = item.ID
+		switch {
+		case problem != "":
+			result.Status, result.Reason = imageFailed, problem
+		case item.ImageHash == hash:
+			result.Status, result.Reason = imageSkipped, "duplicate, already the image of the item"
+		case claimed[item.ID] != "":
+			result.Status, result.Reason = imageFailed, "the item already gets the image "+claimed[item.ID]+" of the archive"
+		}
+		if result.Status != "" {
+			report.Results = append(report.Results, result)
+			continue
+		}
+		seenHashes[hash] = file.Name
+		claimed[item.ID] = file.Name
+
+		report.Results = append(report.Results, result)
+		pending = append(pending, &imageFile{result: len(report.Results) - 1, data: data, hash: hash, prev: item, item: item})
+	}
+
+	// manifest rows naming files the archive doesn't have
+	missing := make([]string, 0, len(targets))
+	for name := range targets {
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		report.Results = append(report.Results, ImageImportResult{File: name, Status: imageFailed, Reason: "listed in the manifest but not in the archive"})
+	}
+
+	im.attach(ctx, report, pending)
+	report.count()
+	return report, nil
+}
+
+// attach uploads the pending images with their thumbnails, a few at a time,
+// then stores the items
+func (im imageArchiveImport) attach(ctx context.Context, report *ImageImportReport, pending []*imageFile) {
+	fail := func(p *imageFile, status, reason string) {
+		report.Results[p.result].Status, report.Results[p.result].Reason = status, reason
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	slots := make(chan struct{}, imageUploadWorkers)
+	uploaded := make([]bool, len(pending))
+	for i, p := range pending {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, p *imageFile) {
+			defer func() { <-slots; wg.Done() }()
+
+			if im.isDuplicate(p.hash) {
+				mu.Lock()
+				fail(p, imageSkipped, "duplicate, another item already has this image")
+				mu.Unlock()
+				return
+			}
+			imageURL, thumbnailURL, err := im.upload(imageReader{bytes.NewReader(p.data)}, p.item)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("Failed to upload image for item %d: %v", p.item.ID, err)
+				fail(p, imageFailed, "the image or its thumbnail could not be stored")
+				return
+			}
+			p.item.Image, p.item.Thumbnail, p.item.ImageHash = imageURL, thumbnailURL, p.hash
+			uploaded[i] = true
+		}(i, p)
+	}
+	wg.Wait()
+
+	var stored []*imageFile
+	var items []models.GroceryItem
+	for i, p := range pending {
+		if uploaded[i] {
+			stored = append(stored, p)
+			items = append(items, p.item)
+		}
+	}
+	errs := im.repo.UpdateItems(ctx, items)
+	for i, p := range stored {
+		if errs[i] != nil {
+			log.Printf("Failed to update item %d: %v", p.item.ID, errs[i])
+			fail(p, imageFailed, "the item could not be updated")
+			continue
+		}
+		report.Results[p.result].Status = imageAttached
+	}
+
+	for i, p := range stored {
+		if errs[i] == nil {
+			catalogChanged(&p.prev, &p.item)
+		}
+	}
+}
+
+// findItem looks up the item of a file, or says why there is none
+func (im imageArchiveImport) findItem(ctx context.Context, target imageTarget) (models.GroceryItem, string, error) {
+	if target.problem != "" {
+		return models.GroceryItem{}, target.problem, nil
+	}
+	if target.filters == nil {
+		item, err := im.repo.Get(ctx, target.id)
+		if err == repository.ErrNotFound {
+			return models.GroceryItem{}, fmt.Sprintf("no item with ID %d", target.id), nil
+		}
+		return item, "", err
+	}
+
+	items, err := im.repo.List(ctx, repository.Query{Filters: target.filters, Limit: 2})
+	if err != nil {
+		return models.GroceryItem{}, "", err
+	}
+	switch len(items) {
+	case 0:
+		return models.GroceryItem{}, "no item matches", nil
+	case 1:
+		return items[0], "", nil
+	}
+	return models.GroceryItem{}, fmt.Sprintf("more than one item matches, e.g. %d and %d", items[0].ID, items[1].ID), nil
+}
+
+// targetFromFilename reads the item of a file from its name: the item ID, or
+// a barcode when it has 8 digits or more, optionally followed by _ or - and
+// anything else
+func targetFromFilename(name string) imageTarget {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	if cut := strings.IndexAny(base, "_- "); cut >= 0 {
+		base = base[:cut]
+	}
+	if _, err := strconv.ParseUint(base, 10, 64); err != nil {
+		return imageTarget{problem: "the file name names no item, use the item ID or barcode, or a manifest"}
+	}
+	if len(base) >= 8 {
+		filters, _ := repository.ParseFilters(url.Values{"barcode": {base}})
+		return imageTarget{filters: filters}
+	}
+	id, _ := strconv.Atoi(base)
+	return imageTarget{id: id}
+}
+
+// readImageManifest maps the files listed in the manifest to their items.
+// Files are matched by base name, so the manifest can name them with or
+// without their folder.
+func readImageManifest(file *zip.File) (map[string]imageTarget, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	reader := csv.NewReader(rc)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("can't read the header: %v", err)
+	}
+
+	fileColumn := -1
+	for i, column := range header {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		header[i] = column
+		if manifestFileColumns[strings.ToLower(column)] && fileColumn < 0 {
+			fileColumn = i
+			continue
+		}
+		if _, ok := repository.LookupField(column); !ok {
+			return nil, fmt.Errorf("unknown column %q, use file and id or fields of the items", column)
+		}
+	}
+	if fileColumn < 0 {
+		return nil, errors.New("no file column")
+	}
+
+	targets := map[string]imageTarget{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return targets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if fileColumn >= len(record) || strings.TrimSpace(record[fileColumn]) == "" {
+			continue
+		}
+
+		var target imageTarget
+		values := url.Values{}
+		for i, value := range record {
+			if value = strings.TrimSpace(value); i == fileColumn || value == "" {
+				continue
+			}
+			if strings.EqualFold(header[i], "id") {
+				if target.id, err = strconv.Atoi(value); err != nil {
+					target.problem = fmt.Sprintf("id %q on line %d is not a number", value, line)
+				}
+				continue
+			}
+			values.Set(header[i], value)
+		}
+		if target.id != 0 && len(values) > 0 {
+			values.Set("id", strconv.Itoa(target.id))
+		}
+		if len(values) > 0 {
+			if target.filters, err = repository.ParseFilters(values); err != nil {
+				target.problem = fmt.Sprintf("line %d: %v", line, err)
+			}
+		} else if target.id == 0 && target.problem == "" {
+			target.problem = fmt.Sprintf("line %d names no item", line)
+		}
+		targets[path.Base(strings.TrimSpace(record[fileColumn]))] = target
+	}
+}
+
+// readImageFile reads a file of the archive that has to be an image
+func readImageFile(file *zip.File) ([]byte, error) {
+	if file.UncompressedSize64 > maxImageSize {
+		return nil, fmt.Errorf("larger than %d MB", maxImageSize>>20)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("can't be read from the archive: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("can't be read from the archive: %v", err)
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("larger than %d MB", maxImageSize>>20)
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return nil, errors.New("not a JPEG or PNG image")
+	}
+	return data, nil
+}
+
+// imageReader lets an image read from an archive go through the functions
+// taking uploaded files
+type imageReader struct {
+	*bytes.Reader
+}
+
+func (imageReader) Close() error {
+	return nil
+}
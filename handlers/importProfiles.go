@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/capstone/importer"
+	"example.com/capstone/utils"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const importProfilesCollection = "importProfiles"
+
+var errUnknownImportProfile = errors.New("unknown import profile")
+
+// CreateImportProfile saves a column mapping profile for imports.
+// @Summary Save an import profile
+// @Description Saves how the files of a supplier are read, to import them with profile={profileId}. columns maps headers to fields (e.g. "MRP": "price", "Net Wt": "weight"),
+// @Description transforms reads a field with unit (weight with its unit, e.g. "500 g"), currency (e.g. "₹1,299.00"), lowercase or uppercase,
+// @Description dateFormats are tried for mfgDate and expDate before the usual formats (e.g. DD/MM/YYYY, MMM-YY), trueWords and falseWords are read as vegetarian values (e.g. Veg, Non-Veg),
+// @Description and defaults fill fields the rows have no value for (e.g. "countryOfOrigin": "India"). Do provide 'Bearer' before adding authorization token
+// @ID create-import-profile
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param profile body importer.Profile true "Import profile, id, createdBy and createdAt are set by the server"
+// @Success 201 {object} importer.Profile "Saved profile"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /importProfiles [post]
+// @Security BearerToken
+func CreateImportProfile(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	var profile importer.Profile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	profile.Name = strings.TrimSpace(profile.Name)
+	profile.CreatedBy = email
+	profile.CreatedAt = time.Now().UTC()
+	if err := profile.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ref, _, err := client.Collection(importProfilesCollection).Add(context.Background(), profile)
+	if err != nil {
+		log.Print("Failed to save import profile:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save import profile")
+		return
+	}
+	profile.ID = ref.ID
+
+	respondWithJSON(w, http.StatusCreated, profile)
+}
+
+// ListImportProfiles lists the saved import profiles.
+// @Summary List import profiles
+// @Description Lists the saved import profiles by name. Do provide 'Bearer' before adding authorization token
+// @ID list-import-profiles
+// @Produce json
+// @Param Authorization header string true "token"
+// @Success 200 {array} importer.Profile "Import profiles"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /importProfiles [get]
+// @Security BearerToken
+func ListImportProfiles(w http.ResponseWriter, r *http.Request) {
+	if !isAuthorized(w, r) {
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	profiles := []importer.Profile{}
+	iter := client.Collection(importProfilesCollection).Documents(context.Background())
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Print("Failed to read import profiles:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to read import profiles")
+			return
+		}
+		var profile importer.Profile
+		if err := doc.DataTo(&profile); err != nil {
+			log.Printf("Skipping import profile %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		profile.ID = doc.Ref.ID
+		profiles = append(profiles, profile)
+	}
+
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return strings.ToLower(profiles[i].Name) < strings.ToLower(profiles[j].Name)
+	})
+	respondWithJSON(w, http.StatusOK, profiles)
+}
+
+// DeleteImportProfile deletes an import profile.
+// @Summary Delete an import profile
+// @Description Deletes an import profile. Jobs created with it keep the profile as it was. Do provide 'Bearer' before adding authorization token
+// @ID delete-import-profile
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param profileId path string true "ID of the import profile"
+// @Success 200 {object} map[string]string "Deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /importProfiles/{profileId} [delete]
+// @Security BearerToken
+func DeleteImportProfile(w http.ResponseWriter, r *http.Request) {
+	if !isAuthorized(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	profileID := parts[len(parts)-1]
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	ref := client.Collection(importProfilesCollection).Doc(profileID)
+	if _, err := ref.Get(ctx); status.Code(err) == codes.NotFound {
+		respondWithError(w, http.StatusNotFound, "Import profile not found")
+		return
+	} else if err != nil {
+		log.Print("Failed to read import profile:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read import profile")
+		return
+	}
+
+	if _, err := ref.Delete(ctx); err != nil {
+		log.Print("Failed to delete import profile:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete import profile")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Import profile deleted"})
+}
+
+// loadImportProfile reads a saved profile, errUnknownImportProfile when there
+// is none with that ID
+func loadImportProfile(ctx context.Context, profileID string) (*importer.Profile, error) {
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	doc, err := client.Collection(importProfilesCollection).Doc(profileID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errUnknownImportProfile
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var profile importer.Profile
+	if err := doc.DataTo(&profile); err != nil {
+		return nil, err
+	}
+	profile.ID = doc.Ref.ID
+	return &profile, nil
+}
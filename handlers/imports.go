@@ -0,0 +1,688 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"example.com/capstone/importer"
+	"example.com/capstone/models"
+	"example.com/capstone/utils"
+)
+
+// importJobsTopic carries the IDs of queued import jobs to the import worker
+// Cloud Function, when IMPORT_WORKER is function
+const importJobsTopic = "importJobs"
+
+var (
+	errUnknownImportFormat = errors.New("format must be csv, json, jsonl or xlsx")
+	errMissingImportFile   = errors.New("the form has no file field")
+)
+
+// importContentTypes tells the format of a request body sent without multipart
+var importContentTypes = map[string]string{
+	"text/csv":             importer.FormatCSV,
+	"application/json":     importer.FormatJSON,
+	"application/x-ndjson": importer.FormatJSONL,
+	"application/jsonl":    importer.FormatJSONL,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": importer.FormatXLSX,
+}
+
+var (
+	importRunner     *importer.Runner
+	importRunnerErr  error
+	importRunnerOnce sync.Once
+)
+
+// ImportGroceryItems imports a file of grocery items into the catalog.
+// @Summary Import grocery items
+// @Description Imports a CSV file with a header row, a JSON array, JSON lines or an XLSX workbook, sent as the file field of a multipart form or as the request body.
+// @Description A workbook is read from its first sheet unless sheet is set; the header is the first row naming at least two fields, rows above it such as a title are skipped.
+// @Description Date cells and dates typed as text both work, numbers are read as stored rather than as displayed.
+// @Description The format is detected from the file name or content unless format is set. Columns are matched to fields by name ignoring case and punctuation,
+// @Description e.g. Product Name or product_name for productName. Dates are MM/YYYY or YYYY-MM, tags in CSV are separated by ; or |.
+// @Description Rows are matched with the catalog by key, productName, brand, weight and weightUnit unless set (e.g. key=barcode); the first key field has to match exactly, the others ignoring case.
+// @Description onConflict says what happens to a matching row: skip (default), overwrite the fields the file has columns for, merge the non-empty values of the row, or fail the row.
+// @Description Without a column for every required field (after the mapping of the profile and its defaults), the import stops before the first row unless onConflict is merge or overwrite.
+// @Description Rows matching nothing are validated and created with a new ID, rows repeating the key of an earlier row are skipped. Every update is audited.
+// @Description With dryRun=true nothing is written: the job ends previewed, its report (GET /imports/{jobId}/report) says what each row would do with the changes of every updated item,
+// @Description and POST /imports/{jobId}/approve runs the import of the same file with the same options.
+// @Description The file is imported in the background: the response is the queued job, follow it with GET /imports/{jobId}.
+// @Description With sync=true small files are imported right away and the report is returned instead. Do provide 'Bearer' before adding authorization token
+// @ID import-grocery-items
+// @Accept multipart/form-data
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param file formData file false "CSV, JSON, JSON lines or XLSX file, the request body is read when missing"
+// @Param format query string false "csv, json, jsonl or xlsx, detected when not set"
+// @Param sheet query string false "Name or 1-based position of the sheet of a workbook to import"
+// @Param key query string false "Comma separated fields identifying a product, e.g. barcode"
+// @Param onConflict query string false "skip, overwrite, merge or fail"
+// @Param profile query string false "ID of the import profile reading the columns, see POST /importProfiles"
+// @Param dryRun query boolean false "Parse, validate and match the rows without writing anything"
+// @Param sync query boolean false "Import before responding and return the report"
+// @Success 202 {object} importer.Job "Queued import job"
+// @Success 200 {object} importer.Report "Import report, with sync=true"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 422 {object} importer.Report "With sync=true, the file could not be read to the end, the report covers the rows before"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /imports [post]
+// @Security BearerToken
+func ImportGroceryItems(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	file, opts, err := importFile(r)
+	if err != nil {
+		log.Println("Failed to read import file:", err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	if r.URL.Query().Get("sync") == "true" {
+		importNow(w, file, opts)
+		return
+	}
+
+	ctx := context.Background()
+	blobs, err := utils.CreateBlobStore()
+	if err != nil {
+		log.Print("Failed to create blob store:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create blob store")
+		return
+	}
+	defer blobs.Close()
+
+	filename := path.Base("/" + opts.Filename)
+	if filename == "/" {
+		filename = "upload"
+	}
+	source := "imports/" + newBlobID() + "/" + filename
+	if err := storeBlob(ctx, blobs, source, file); err != nil {
+		log.Print("Failed to store import file:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to store import file")
+		return
+	}
+
+	jobs, err := utils.CreateImportJobStore()
+	if err != nil {
+		log.Print("Failed to create import job store:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create import job store")
+		return
+	}
+	defer jobs.Close()
+
+	job := importer.Job{
+		Status:    importer.JobQueued,
+		Filename:  opts.Filename,
+		Format:    opts.Format,
+		Sheet:     opts.Sheet,
+		Source:    source,
+		Key:       opts.Key,
+		Conflict:  opts.Conflict,
+		DryRun:    opts.DryRun,
+		Profile:   opts.Profile,
+		CreatedBy: email,
+		CreatedAt: time.Now().UTC(),
+	}
+	job.ID, err = jobs.CreateJob(ctx, job)
+	if err != nil {
+		log.Print("Failed to create import job:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create import job")
+		return
+	}
+
+	if err := dispatchImportJob(ctx, job.ID); err != nil {
+		log.Print("Failed to dispatch import job:", err)
+		jobs.UpdateJob(ctx, job.ID, func(job *importer.Job) error {
+			job.Status = importer.JobFailed
+			job.Error = "the job could not be handed to a worker"
+			job.FinishedAt = time.Now().UTC()
+			return nil
+		})
+		respondWithError(w, http.StatusInternalServerError, "Failed to dispatch import job")
+		return
+	}
+
+	action := "bulk-import"
+	if job.DryRun {
+		action = "bulk-import-preview"
+	}
+	auditRecord := GenerateAuditRecord(action, job.ID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	w.Header().Set("Location", "/imports/"+job.ID)
+	respondWithJSON(w, http.StatusAccepted, job)
+	log.Print("Response Sent: ImportGroceryItems")
+}
+
+// FetchImportJob returns the state of an import job.
+// @Summary Fetch an import job
+// @Description Returns the status of an import job (queued, running, succeeded, failed or cancelled) and the rows handled so far. Do provide 'Bearer' before adding authorization token
+// @ID fetch-import-job
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param jobId path string true "ID of the import job"
+// @Success 200 {object} importer.Job "Import job"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /imports/{jobId} [get]
+// @Security BearerToken
+func FetchImportJob(w http.ResponseWriter, r *http.Request) {
+	if !isAuthorized(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	jobID := parts[len(parts)-1]
+
+	jobs, err := utils.CreateImportJobStore()
+	if err != nil {
+		log.Print("Failed to create import job store:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create import job store")
+		return
+	}
+	defer jobs.Close()
+
+	job, err := jobs.Job(context.Background(), jobID)
+	if err == importer.ErrJobNotFound {
+		respondWithError(w, http.StatusNotFound, "Import job not found")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to read import job:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read import job")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// ImportJobErrors returns the rows of an import job that were not created.
+// @Summary Import error report
+// @Description Lists the failed and skipped rows of a finished import job with the reasons, as JSON or as CSV with format=csv. Do provide 'Bearer' before adding authorization token
+// @ID import-job-errors
+// @Produce json
+// @Produce text/csv
+// @Param Authorization header string true "token"
+// @Param jobId path string true "ID of the import job"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {array} importer.RowResult "Failed and skipped rows"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 409 {object} ErrorResponse "The job has not finished"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /imports/{jobId}/errors [get]
+// @Security BearerToken
+func ImportJobErrors(w http.ResponseWriter, r *http.Request) {
+	if !isAuthorized(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	jobID := parts[len(parts)-2]
+
+	jobs, err := utils.CreateImportJobStore()
+	if err != nil {
+		log.Print("Failed to create import job store:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create import job store")
+		return
+	}
+	defer jobs.Close()
+
+	ctx := context.Background()
+	job, err := jobs.Job(ctx, jobID)
+	if err == importer.ErrJobNotFound {
+		respondWithError(w, http.StatusNotFound, "Import job not found")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to read import job:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read import job")
+		return
+	}
+	if !job.Done() && job.Status != importer.JobPreviewed {
+		respondWithError(w, http.StatusConflict, "Import job has not finished yet")
+		return
+	}
+
+	report, err := jobs.Report(ctx, jobID)
+	if err == importer.ErrJobNotFound {
+		// the job failed before reading the file
+		report = &importer.Report{}
+	} else if err != nil {
+		log.Print("Failed to read import report:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read import report")
+		return
+	}
+
+	rows := []importer.RowResult{}
+	for _, result := range report.Results {
+		if result.Status != importer.RowCreated {
+			rows = append(rows, result)
+		}
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="import-`+jobID+`-errors.csv"`)
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"row", "status", "reasons"})
+		for _, row := range rows {
+			writer.Write([]string{strconv.Itoa(row.Row), row.Status, strings.Join(row.Reasons, "; ")})
+		}
+		writer.Flush()
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rows)
+}
+
+// FetchImportReport returns the report of an import job.
+// @Summary Import report
+// @Description Returns the report of a finished or previewed import job: the counts and the outcome of every row, with the field changes of updated items.
+// @Description status keeps the rows with that outcome only, e.g. status=updated to review the changes of a dry run. Do provide 'Bearer' before adding authorization token
+// @ID fetch-import-report
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param jobId path string true "ID of the import job"
+// @Param status query string false "created, updated, unchanged, skipped or failed"
+// @Success 200 {object} importer.Report "Import report"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 409 {object} ErrorResponse "The job has not finished"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /imports/{jobId}/report [get]
+// @Security BearerToken
+func FetchImportReport(w http.ResponseWriter, r *http.Request) {
+	if !isAuthorized(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	jobID := parts[len(parts)-2]
+
+	jobs, err := utils.CreateImportJobStore()
+	if err != nil {
+		log.Print("Failed to create import job store:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create import job store")
+		return
+	}
+	defer jobs.Close()
+
+	ctx := context.Background()
+	job, err := jobs.Job(ctx, jobID)
+	if err == importer.ErrJobNotFound {
+		respondWithError(w, http.StatusNotFound, "Import job not found")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to read import job:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read import job")
+		return
+	}
+	if !job.Done() && job.Status != importer.JobPreviewed {
+		respondWithError(w, http.StatusConflict, "Import job has not finished yet")
+		return
+	}
+
+	report, err := jobs.Report(ctx, jobID)
+	if err == importer.ErrJobNotFound {
+		respondWithError(w, http.StatusNotFound, "Import job has no report, it failed before reading the file")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to read import report:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read import report")
+		return
+	}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		results := []importer.RowResult{}
+		for _, result := range report.Results {
+			if result.Status == status {
+				results = append(results, result)
+			}
+		}
+		report.Results = results
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// ApproveImportJob runs the import previewed by a dry run.
+// @Summary Approve an import preview
+// @Description Queues the import of the file of a previewed dry run, with the same options. The job goes back to queued and ends like any import. Do provide 'Bearer' before adding authorization token
+// @ID approve-import-job
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param jobId path string true "ID of the import job"
+// @Success 202 {object} importer.Job "Queued import job"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 409 {object} ErrorResponse "The job is not a previewed dry run"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /imports/{jobId}/approve [post]
+// @Security BearerToken
+func ApproveImportJob(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	jobID := parts[len(parts)-2]
+
+	runner, err := loadImportRunner()
+	if err != nil {
+		log.Print("Failed to start import runner:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start import runner")
+		return
+	}
+
+	ctx := context.Background()
+	job, err := runner.Approve(ctx, jobID, email)
+	switch {
+	case err == importer.ErrJobNotFound:
+		respondWithError(w, http.StatusNotFound, "Import job not found")
+		return
+	case err == importer.ErrJobNotPreviewed:
+		respondWithError(w, http.StatusConflict, "Import job is not a previewed dry run")
+		return
+	case err != nil:
+		log.Print("Failed to approve import job:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to approve import job")
+		return
+	}
+
+	if err := dispatchImportJob(ctx, job.ID); err != nil {
+		log.Print("Failed to dispatch import job:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to dispatch import job")
+		return
+	}
+
+	auditRecord := GenerateAuditRecord("bulk-import", job.ID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// CancelImportJob cancels an import job.
+// @Summary Cancel an import job
+// @Description Cancels a queued import job or a previewed dry run, or stops a running one after its current row. Items already written stay in the catalog. Do provide 'Bearer' before adding authorization token
+// @ID cancel-import-job
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param jobId path string true "ID of the import job"
+// @Success 200 {object} importer.Job "Import job"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 409 {object} ErrorResponse "The job already finished"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /imports/{jobId}/cancel [post]
+// @Security BearerToken
+func CancelImportJob(w http.ResponseWriter, r *http.Request) {
+	if !isAuthorized(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	jobID := parts[len(parts)-2]
+
+	runner, err := loadImportRunner()
+	if err != nil {
+		log.Print("Failed to start import runner:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start import runner")
+		return
+	}
+
+	job, err := runner.Cancel(context.Background(), jobID)
+	switch {
+	case err == importer.ErrJobNotFound:
+		respondWithError(w, http.StatusNotFound, "Import job not found")
+		return
+	case err == importer.ErrJobFinished:
+		respondWithError(w, http.StatusConflict, "Import job already finished")
+		return
+	case err != nil:
+		log.Print("Failed to cancel import job:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to cancel import job")
+		return
+	}
+
+	auditRecord := GenerateAuditRecord("bulk-import-cancel", jobID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// importNow imports a file within the request and responds with the report
+func importNow(w http.ResponseWriter, file io.Reader, opts importer.Options) {
+	im, release, err := newCatalogImporter()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	defer release()
+
+	report, err := im.Run(context.Background(), file, opts)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Imported %s file (dry run %t): %d rows, %d created, %d updated, %d unchanged, %d skipped, %d failed",
+		report.Format, report.DryRun, report.Rows, report.Created, report.Updated, report.Unchanged, report.Skipped, report.Failed)
+	if !report.DryRun {
+		auditRecord := GenerateAuditRecord("bulk-import", "")
+		log.Printf("Audit Record: %+v", auditRecord)
+		if err := PublishAuditRecord(auditRecord); err != nil {
+			log.Println("Failed to publish audit record:", err)
+		}
+	}
+
+	if report.Error != "" {
+		respondWithJSON(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+	log.Print("Response Sent: ImportGroceryItems")
+}
+
+// loadImportRunner returns the runner of the import jobs of this process,
+// starting its workers on first use. IMPORT_WORKERS sets their number.
+func loadImportRunner() (*importer.Runner, error) {
+	importRunnerOnce.Do(func() {
+		jobs, err := utils.CreateImportJobStore()
+		if err != nil {
+			importRunnerErr = err
+			return
+		}
+		blobs, err := utils.CreateBlobStore()
+		if err != nil {
+			jobs.Close()
+			importRunnerErr = err
+			return
+		}
+
+		importRunner = importer.NewRunner(jobs, blobs, newCatalogImporter)
+		workers, err := strconv.Atoi(os.Getenv("IMPORT_WORKERS"))
+		if err != nil || workers < 1 {
+			workers = 2
+		}
+		importRunner.Start(workers)
+	})
+	return importRunner, importRunnerErr
+}
+
+// NewImporter sets up an importer writing to the catalog backend that
+// publishes an audit record for every item it updates
+func NewImporter() (*importer.Importer, func(), error) {
+	im, release, err := utils.NewImporter()
+	if err != nil {
+		return nil, nil, err
+	}
+	im.OnUpdated = func(prev, item models.GroceryItem) {
+		auditRecord := GenerateAuditRecord("bulk-import-update", strconv.Itoa(item.ID))
+		log.Printf("Audit Record: %+v", auditRecord)
+		if err := PublishAuditRecord(auditRecord); err != nil {
+			log.Println("Failed to publish audit record:", err)
+		}
+	}
+	return im, release, nil
+}
+
+// newCatalogImporter sets up an importer that also keeps the derived views of
+// the catalog in sync with what it writes
+func newCatalogImporter() (*importer.Importer, func(), error) {
+	im, release, err := NewImporter()
+	if err != nil {
+		return nil, nil, err
+	}
+	audit := im.OnUpdated
+	im.OnCreated = func(item models.GroceryItem) {
+		catalogChanged(nil, &item)
+	}
+	im.OnUpdated = func(prev, item models.GroceryItem) {
+		audit(prev, item)
+		catalogChanged(&prev, &item)
+	}
+	return im, release, nil
+}
+
+// dispatchImportJob hands a queued job to the workers of this process, or
+// to the import worker Cloud Function through Pub/Sub when IMPORT_WORKER is
+// function
+func dispatchImportJob(ctx context.Context, jobID string) error {
+	if os.Getenv("IMPORT_WORKER") != "function" {
+		runner, err := loadImportRunner()
+		if err != nil {
+			return err
+		}
+		runner.Enqueue(jobID)
+		return nil
+	}
+
+	pubsubClient, err := pubsub.NewClient(ctx, "capstone-408907")
+	if err != nil {
+		return err
+	}
+	defer pubsubClient.Close()
+
+	data, err := json.Marshal(map[string]string{"jobId": jobID})
+	if err != nil {
+		return err
+	}
+	_, err = pubsubClient.Topic(importJobsTopic).Publish(ctx, &pubsub.Message{Data: data}).Get(ctx)
+	return err
+}
+
+// importFile returns the file sent to an import endpoint without buffering
+// it: the file part of a multipart form, or else the request body
+func importFile(r *http.Request) (io.ReadCloser, importer.Options, error) {
+	query := r.URL.Query()
+	opts := importer.Options{
+		Format:   query.Get("format"),
+		Sheet:    query.Get("sheet"),
+		Key:      importer.ParseKey(query.Get("key")),
+		Conflict: query.Get("onConflict"),
+		DryRun:   query.Get("dryRun") == "true",
+	}
+	switch opts.Format {
+	case "", importer.FormatCSV, importer.FormatJSON, importer.FormatJSONL, importer.FormatXLSX:
+	default:
+		return nil, opts, errUnknownImportFormat
+	}
+	if profileID := query.Get("profile"); profileID != "" {
+		profile, err := loadImportProfile(context.Background(), profileID)
+		if err != nil && err != errUnknownImportProfile {
+			log.Print("Failed to read import profile:", err)
+			err = errors.New("the import profile can't be read")
+		}
+		if err != nil {
+			return nil, opts, err
+		}
+		opts.Profile = profile
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, opts, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		if opts.Format == "" {
+			opts.Format = importContentTypes[mediaType]
+		}
+		return r.Body, opts, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, opts, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, opts, errMissingImportFile
+		}
+		if err != nil {
+			return nil, opts, err
+		}
+		if part.FormName() == "file" {
+			opts.Filename = part.FileName()
+			return part, opts, nil
+		}
+		part.Close()
+	}
+}
+
+// storeBlob copies r to a new blob
+func storeBlob(ctx context.Context, blobs importer.BlobStore, name string, r io.Reader) error {
+	writer, err := blobs.Create(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, r); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// newBlobID returns a unique, time ordered name for a blob folder
+func newBlobID() string {
+	random := make([]byte, 4)
+	rand.Read(random)
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(random)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"example.com/capstone/importer"
+	"example.com/capstone/utils"
+)
+
+// StartIngestWatcher imports the files dropped in dataFiles/ of the blob store
+// in the background, like the storage triggered Cloud Function does for the
+// data bucket. It is meant for local development with BLOB_DIR, the directory
+// is scanned every INGEST_INTERVAL (a duration, 5s by default).
+func StartIngestWatcher() {
+	interval, err := time.ParseDuration(os.Getenv("INGEST_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	blobs, err := utils.CreateBlobStore()
+	if err != nil {
+		log.Print("Failed to create blob store, data files won't be ingested:", err)
+		return
+	}
+	ledger, err := utils.CreateIngestLedger()
+	if err != nil {
+		blobs.Close()
+		log.Print("Failed to create ingest ledger, data files won't be ingested:", err)
+		return
+	}
+
+	ingester := &importer.Ingester{Blobs: blobs, Ledger: ledger, NewImporter: newCatalogImporter}
+	log.Printf("Watching %s for data files every %s", importer.IngestPrefix, interval)
+	go ingester.Watch(context.Background(), interval)
+}
@@ -2,34 +2,154 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
-	"cloud.google.com/go/firestore"
 	"example.com/capstone/models"
+	"example.com/capstone/repository"
 	"example.com/capstone/utils"
-	"google.golang.org/api/iterator"
 )
 
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// listOptions are the query parameters of a listing that are not filters
+var listOptions = []string{"fields", "sort", "facets", "pageSize", "pageNumber", "pageToken", "includeTotal", "withPromotions"}
+
+// defaultListFields are returned by listGroceryItems when fields isn't set
+var defaultListFields = []string{"id", "productName", "price", "category", "thumbnailURL"}
+
+// promotionFields are read on top of the requested fields to evaluate promotions
+var promotionFields = []string{"id", "price", "category", "brand", "tags"}
+
+// facetCache holds facet counts of recent listings. Changes made through this
+// instance invalidate it, changes made elsewhere show up within a minute.
+var facetCache = repository.NewFacetCache(time.Minute, 256)
+
+// GroceryItemPage is one page of a grocery item listing
+type GroceryItemPage struct {
+	Items         []map[string]interface{} `json:"items"`
+	NextPageToken string                   `json:"nextPageToken,omitempty"` // empty on the last page
+	TotalCount    *int64                   `json:"totalCount,omitempty"`    // only when includeTotal=true
+	Facets        []repository.FacetResult `json:"facets,omitempty"`        // only when facets is set
+}
+
+// pageRequest is the pagination part of a listing request
+type pageRequest struct {
+	size         int
+	number       int
+	token        string
+	includeTotal bool
+}
+
+// parsePageRequest reads pageSize, pageNumber, pageToken and includeTotal.
+// pageSize is capped at maxPageSize. pageNumber is kept for older clients and
+// can't be combined with pageToken.
+func parsePageRequest(values url.Values) (pageRequest, error) {
+	page := pageRequest{
+		size:         defaultPageSize,
+		number:       1,
+		token:        values.Get("pageToken"),
+		includeTotal: values.Get("includeTotal") == "true",
+	}
+
+	if v := values.Get("pageSize"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 {
+			return page, errors.New("pageSize must be a positive integer")
+		}
+		page.size = size
+	}
+	if page.size > maxPageSize {
+		page.size = maxPageSize
+	}
+
+	if v := values.Get("pageNumber"); v != "" {
+		if page.token != "" {
+			return page, errors.New("use either pageToken or pageNumber, not both")
+		}
+		number, err := strconv.Atoi(v)
+		if err != nil || number < 1 {
+			return page, errors.New("pageNumber must be a positive integer")
+		}
+		page.number = number
+	}
+
+	return page, nil
+}
+
+// pageTokenSecrets sign page tokens, set by ConfigurePageTokens. The first
+// one signs new tokens, the others are previous secrets that are still accepted.
+var pageTokenSecrets [][]byte
+
+// ConfigurePageTokens reads the page token secrets from the environment.
+// PAGE_TOKEN_SECRET signs new tokens, without it listings answer 503 until the
+// server is restarted with one. PAGE_TOKEN_PREVIOUS_SECRETS, comma separated,
+// are only checked, so rotating the secret doesn't break the pages clients are on.
+func ConfigurePageTokens() error {
+	secret := os.Getenv("PAGE_TOKEN_SECRET")
+	if secret == "" {
+		if os.Getenv("PAGE_TOKEN_PREVIOUS_SECRETS") != "" {
+			return errors.New("PAGE_TOKEN_PREVIOUS_SECRETS is set without PAGE_TOKEN_SECRET")
+		}
+		log.Print("PAGE_TOKEN_SECRET is not set, listing is disabled")
+		return nil
+	}
+
+	secrets := [][]byte{[]byte(secret)}
+	for _, previous := range strings.Split(os.Getenv("PAGE_TOKEN_PREVIOUS_SECRETS"), ",") {
+		if previous = strings.TrimSpace(previous); previous != "" {
+			secrets = append(secrets, []byte(previous))
+		}
+	}
+	pageTokenSecrets = secrets
+	return nil
+}
+
 // ListItemsBY lists grocery items based on query parameters.
 // @Summary List grocery items based on query parameters
-// @Description Retrieves a list of grocery items from the Firestore database based on the provided query parameters.
+// @Description Retrieves a list of grocery items based on the provided query parameters.
+// @Description Filters take the form field=value or field[op]=value with op one of eq, ne, gt, gte, lt, lte or in (comma separated values),
+// @Description e.g. price[gte]=10&price[lt]=50&vegetarian=true&category[in]=Snacks,Dairy. Every filter has to match.
+// @Description Filterable fields: id, productName, category, price, weight, weightUnit, vegetarian, manufacturer, brand, itemPackageQuantity,
+// @Description packageInformation, countryOfOrigin, stock and tags (eq means contains, in means contains any). Unknown fields are rejected.
+// @Description sort takes a comma separated list of fields, prefix a field with - for descending order, e.g. sort=category,-price,productName.
+// @Description Any filterable field except tags can be sorted on, as well as unitPrice, the price per kg or litre.
+// @Description facets counts the items matching the filters per value of category, brand, vegetarian, countryOfOrigin or any other text field,
+// @Description and per range of a number field given as field:bound-bound-..., e.g. price:0-50-100-500 counts 0-50, 50-100, 100-500 and 500+.
+// @Description Range and yes/no facets are exact. Text facets are counted over the first 5000 matching items, and marked partial when more items match.
+// @Description Without sort, items are ordered by the fields with range filters. id always breaks ties. Pass nextPageToken back as pageToken to get the next page.
+// @Description Range filters (ne, gt, gte, lt, lte) can only be on one field, and a sort has to start with that field. Other combinations are rejected with 400.
 // @ID list-items-by
 // @Produce json
 // @Param productName query string false "Filter by product name"
 // @Param price query number false "Filter by price"
-// @Param price_min query number false "Filter by minimum price"
-// @Param price_max query number false "Filter by maximum price"
-// @Param Category query string false "Filter by category"
-// @Param pageSize query integer false "Number of items per page" format(int32)
-// @Param pageNumber query integer false "Page number" format(int32)
-// @Success 201 {Object} string "List Of Grocery Items"
+// @Param price_min query number false "Filter by minimum price, same as price[gte]"
+// @Param price_max query number false "Filter by maximum price, same as price[lte]"
+// @Param category query string false "Filter by category"
+// @Param vegetarian query boolean false "Filter by vegetarian"
+// @Param fields query string false "Fields to return, keyed by their names, e.g. id,productName,price,brand,expDate. Without it items have the keys ID, ProductName, Price, Category and Thumbnail, as before fields was added"
+// @Param sort query string false "Sort order, e.g. category,-price,productName"
+// @Param facets query string false "Facets to count over all matching items, e.g. category,brand,vegetarian,countryOfOrigin,price:0-50-100-500"
+// @Param pageSize query integer false "Number of items per page, 10 by default and at most 100" format(int32)
+// @Param pageToken query string false "nextPageToken of the previous page"
+// @Param pageNumber query integer false "Page number, for older clients, can't be combined with pageToken" format(int32)
+// @Param includeTotal query boolean false "Also return totalCount, the number of items matching the filters"
+// @Param withPromotions query boolean false "Also return originalPrice, effectivePrice and appliedPromotions for each item, OriginalPrice, EffectivePrice and AppliedPromotions without fields"
+// @Success 200 {object} GroceryItemPage "Page of grocery items"
 // @Failure 400 {object} ErrorResponse "Bad Request"
 // @Failure 401 {object} ErrorResponse "Unauthorized"
 // @Failure 404 {object} ErrorResponse "Not Found"
 // @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Failure 503 {object} ErrorResponse "Page tokens are not configured"
 // @Router /listGroceryItems [get]
 func ListItemsBY(w http.ResponseWriter, r *http.Request) {
 	// handle preflight CORS
@@ -43,135 +163,219 @@ func ListItemsBY(w http.ResponseWriter, r *http.Request) {
 
 	utils.InitLogger()
 
-	// Create a Firestore client
-	client, err := utils.CreateFirestoreClient()
+	if len(pageTokenSecrets) == 0 {
+		respondWithError(w, http.StatusServiceUnavailable, "Page tokens are not configured")
+		return
+	}
+
+	filters, err := repository.ParseFilters(r.URL.Query(), listOptions...)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sortKeys, err := repository.ParseSort(r.URL.Query().Get("sort"))
 	if err != nil {
-		log.Print("Failed to create Firestore client:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer client.Close()
 
-	log.Print("Firestore client created")
+	facetRequests, err := repository.ParseFacets(r.URL.Query().Get("facets"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	collectionRef := client.Collection("groceryItems")
-	var query firestore.Query
+	fields, err := repository.ParseFields(r.URL.Query().Get("fields"), defaultListFields...)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	withPromotions := r.URL.Query().Get("withPromotions") == "true"
 
-	i := 0
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	defer repo.Close()
 
-	for k := range r.URL.Query() {
+	page, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-		if k == "pageSize" || k == "pageNumber" {
-			continue
+	// one extra item tells whether there is a next page
+	query := repository.Query{Filters: filters, Sort: sortKeys, Limit: page.size + 1, Fields: fields}
+	if withPromotions {
+		query.Fields = repository.WithFields(fields, promotionFields...)
+	}
+	if page.token != "" {
+		query.After, err = repository.DecodePageToken(pageTokenSecrets, page.token, query)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
 		}
-		v := r.URL.Query().Get(k)
-		// Handle value inequality and range comparisons
-		if strings.HasPrefix(k, "price") {
-			// Convert the value to a float64 for numerical comparison
-			price, err := strconv.ParseFloat(v, 64)
-			if err != nil {
-				log.Printf("Invalid price value: %s", v)
-				continue
-			}
-
-			if strings.HasSuffix(k, "_min") {
-				query = collectionRef.Where("Price", "<=", price)
-			} else if strings.HasSuffix(k, "_max") {
-				query = collectionRef.Where("Price", ">=", price)
-			} else {
-				query = collectionRef.Where("Price", "==", price)
-			}
+	} else {
+		query.Offset = (page.number - 1) * page.size
+	}
 
-		} else {
-			if i == 0 {
-				log.Printf("Query parameter: %s=%s\n", k, v)
-				query = collectionRef.Where(k, "==", v)
+	if err := repository.CheckQuery(repo, query); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-			} else {
-				query = query.Where(k, "==", v)
-			}
+	log.Printf("pageSize: %d, pageNumber: %d, pageToken: %q", page.size, page.number, page.token)
 
-			i++
+	// keyword search, e.g. productName containing baby care oil, is served by /search
 
-		}
+	log.Printf("Request Parameters: %v", r.URL.Query())
 
+	ctx := context.Background()
+	groceryItems, err := repo.List(ctx, query)
+	if err != nil {
+		log.Print("Failed to read grocery item data:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read grocery item data")
+		return
 	}
 
-	// Calculate pagination parameters
-	pageSizeStr := r.URL.Query().Get("pageSize")
-	pageNumberStr := r.URL.Query().Get("pageNumber")
-
-	var pageSize, pageNumber int
-
-	if pageSizeStr != "" {
-		pageSize, err = strconv.Atoi(pageSizeStr)
+	var result GroceryItemPage
+	if len(groceryItems) > page.size {
+		groceryItems = groceryItems[:page.size]
+		result.NextPageToken, err = repository.EncodePageToken(pageTokenSecrets[0], query, groceryItems[len(groceryItems)-1])
 		if err != nil {
-			log.Printf("Error parsing pageSize: %v", err)
+			log.Print("Failed to create page token:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to create page token")
+			return
 		}
-	} else {
-		// default page size
-		pageSize = 10
 	}
 
-	if pageNumberStr != "" {
-		pageNumber, err = strconv.Atoi(pageNumberStr)
+	if page.includeTotal {
+		total, err := repo.Count(ctx, filters)
 		if err != nil {
-			log.Printf("Error parsing pageNumber: %v", err)
+			log.Print("Failed to count grocery items:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to count grocery items")
+			return
 		}
-	} else {
-		// default page no
-		pageNumber = 1
+		result.TotalCount = &total
 	}
 
-	// Calculate the start index for pagination
-	startIndex := (pageNumber - 1) * pageSize
-
-	log.Printf("pageSize: %d, pageNumber: %d, startIndex: %d", pageSize, pageNumber, startIndex)
-
-	// Add pagination to the query
-	query = query.Offset(0).Limit(pageSize)
-
-	// try to add "productname" containing baby care oil not exact name - but keyword
-
-	log.Printf("Request Parameters: %v", r.URL.Query())
-
-	// Execute the query
-	iter := query.Documents(context.Background())
-
-	var groceryItems []models.GroceryItem
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
+	if len(facetRequests) > 0 {
+		result.Facets, err = facetCache.Facets(ctx, repo, filters, facetRequests)
+		if err != nil {
+			log.Print("Failed to compute facets:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to compute facets")
+			return
 		}
+	}
+
+	var promotions []models.Promotion
+	if withPromotions {
+		client, err := utils.CreateFirestoreClient()
 		if err != nil {
-			log.Print("Failed to read grocery item data from Firestore:", err)
-			respondWithError(w, http.StatusInternalServerError, "Failed to read grocery item data from Firestore")
+			log.Print("Failed to create Firestore client:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
 			return
 		}
-		var item models.GroceryItem
-		if err := doc.DataTo(&item); err != nil {
-			log.Print("Failed to parse grocery item data from Firestore:", err)
-			respondWithError(w, http.StatusInternalServerError, "Failed to parse grocery item data from Firestore")
+		defer client.Close()
+
+		promotions, err = fetchActivePromotions(ctx, client)
+		if err != nil {
+			log.Print("Failed to read promotions from Firestore:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to read promotions from Firestore")
 			return
 		}
-		groceryItems = append(groceryItems, item)
 	}
 
 	// Create a response object
-	var response []interface{}
+	legacy := r.URL.Query().Get("fields") == ""
+	result.Items = []map[string]interface{}{}
+	now := time.Now().UTC()
 	for _, item := range groceryItems {
-		itemMap := map[string]interface{}{
-			"ID":          item.ID,
-			"ProductName": item.ProductName,
-			"Price":       item.Price,
-			"Category":    item.Category,
-			"Thumbnail":   item.Thumbnail,
+		itemMap := repository.Project(item, fields)
+		if legacy {
+			itemMap = legacyListItem(item)
+		}
+		if withPromotions {
+			breakdown := evaluatePromotions(item, item.Price, 1, promotions, now)
+			if legacy {
+				itemMap["OriginalPrice"] = breakdown.OriginalPrice
+				itemMap["EffectivePrice"] = breakdown.EffectivePrice
+				itemMap["AppliedPromotions"] = breakdown.AppliedPromotions
+			} else {
+				itemMap["originalPrice"] = breakdown.OriginalPrice
+				itemMap["effectivePrice"] = breakdown.EffectivePrice
+				itemMap["appliedPromotions"] = breakdown.AppliedPromotions
+			}
 		}
-		response = append(response, itemMap)
+		result.Items = append(result.Items, itemMap)
 	}
 
 	// Return the response as JSON
-	respondWithJSON(w, http.StatusOK, response)
+	respondWithJSON(w, http.StatusOK, result)
 	log.Print("Response Sent: ListGroceryItems")
 }
+
+// legacyListItem is an item as listings returned it before fields was added,
+// keyed by Go field names. It is still returned without fields, so existing
+// clients keep reading ID, ProductName, Price, Category and Thumbnail.
+func legacyListItem(item models.GroceryItem) map[string]interface{} {
+	return map[string]interface{}{
+		"ID":          item.ID,
+		"ProductName": item.ProductName,
+		"Price":       item.Price,
+		"Category":    item.Category,
+		"Thumbnail":   item.Thumbnail,
+	}
+}
+
+// BackfillUnitPrices stores the unit price of existing grocery items.
+// @Summary Backfill stored unit prices
+// @Description Listings sorted by unitPrice are ordered by Firestore on a unit price stored with every item, which items written before it was stored lack and are left out of such listings. This stores it on every item that doesn't have it, or has a stale one. It reads the whole catalog, run it once after deploying. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID backfill-unit-prices
+// @Produce json
+// @Param Authorization header string true "token"
+// @Success 200 {object} map[string]int "Number of updated items"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /backfillUnitPrices [post]
+// @Security BearerToken
+func BackfillUnitPrices(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isStaff(w, r) {
+		return
+	}
+
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	defer repo.Close()
+
+	// only Firestore stores the unit price, the memory catalog computes it
+	updated := 0
+	if backfiller, ok := repo.(interface {
+		BackfillUnitPrices(ctx context.Context) (int, error)
+	}); ok {
+		updated, err = backfiller.BackfillUnitPrices(context.Background())
+		if err != nil {
+			log.Printf("Failed to backfill unit prices after %d items: %v", updated, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to backfill unit prices")
+			return
+		}
+	}
+
+	auditRecord := GenerateAuditRecord("unit-price-backfill", strconv.Itoa(updated))
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]int{"updated": updated})
+}
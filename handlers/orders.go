@@ -0,0 +1,818 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	orderStatusPendingPayment = "pending-payment"
+	orderStatusPaymentFailed  = "payment-failed"
+	orderStatusPlaced         = "placed"
+	orderStatusPacked         = "packed"
+	orderStatusOutForDelivery = "out-for-delivery"
+	orderStatusDelivered      = "delivered"
+	orderStatusCancelled      = "cancelled"
+)
+
+// orderTransitions lists the statuses an order can move to from each status
+var orderTransitions = map[string][]string{
+	orderStatusPendingPayment: {orderStatusPlaced, orderStatusPaymentFailed, orderStatusCancelled},
+	orderStatusPlaced:         {orderStatusPacked, orderStatusCancelled},
+	orderStatusPacked:         {orderStatusOutForDelivery, orderStatusCancelled},
+	orderStatusOutForDelivery: {orderStatusDelivered},
+}
+
+// stalePendingPayment is how long an order can wait for its payment before
+// ReleaseStaleOrders gives up on it, far longer than a checkout takes
+const stalePendingPayment = 30 * time.Minute
+
+var (
+	errEmptyCart         = errors.New("cart is empty")
+	errOrderNotFound     = errors.New("order not found")
+	errInvalidTransition = errors.New("invalid order status transition")
+)
+
+// stockError lists the cart lines that can't be reserved
+type stockError struct {
+	items []string
+}
+
+func (e *stockError) Error() string {
+	return "not enough stock for: " + strings.Join(e.items, ", ")
+}
+
+type checkoutRequest struct {
+	PaymentToken string `json:"paymentToken"`
+	CouponCode   string `json:"couponCode"`
+}
+
+type orderStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// Checkout places an order for the cart of the logged in user.
+// @Summary Place an order
+// @Description Places an order for everything in the cart of the logged in user. Stock is reserved, except for items without a stock which are not tracked, line prices are snapshotted on the order and the cart is emptied. An optional coupon is redeemed in the same transaction, so a single-use coupon can't be used by two checkouts at once. The order total is then authorized and captured with the payment gateway; the order only moves to placed when the capture succeeds, otherwise it is marked payment-failed and the stock is released. An order a coupon makes free is placed without calling the gateway. Do provide 'Bearer' before adding authorization token
+// @ID checkout
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param payment body checkoutRequest true "Payment token from the gateway and an optional coupon code"
+// @Success 201 {object} models.Order "Order placed"
+// @Failure 400 {object} ErrorResponse "Cart is empty"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 402 {object} models.Order "Payment failed"
+// @Failure 409 {object} ErrorResponse "Not enough stock, or the order was released while it was being paid"
+// @Failure 422 {object} ErrorResponse "Coupon can't be used on this cart"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Failure 503 {object} ErrorResponse "Payments are not configured"
+// @Router /checkout [post]
+// @Security BearerToken
+func Checkout(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	if paymentProvider == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Payments are not configured")
+		return
+	}
+
+	var req checkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	order, err := placeOrder(ctx, client, email, strings.ToUpper(strings.TrimSpace(req.CouponCode)))
+	var shortage *stockError
+	var invalidCoupon *couponError
+	switch {
+	case errors.As(err, &invalidCoupon):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case errors.Is(err, errEmptyCart):
+		respondWithError(w, http.StatusBadRequest, "Cart is empty")
+		return
+	case errors.As(err, &shortage), errors.Is(err, errItemNotFound):
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		log.Print("Failed to place order:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to place order")
+		return
+	}
+
+	auditRecord := GenerateAuditRecord("order-"+order.Status, order.ID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	order, paid, err := payForOrder(ctx, client, order, req.PaymentToken)
+	if errors.Is(err, errOrderReleased) {
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		log.Print("Failed to process payment:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to process payment")
+		return
+	}
+
+	auditRecord = GenerateAuditRecord("order-"+order.Status, order.ID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	if !paid {
+		respondWithJSON(w, http.StatusPaymentRequired, order)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, order)
+	log.Print("Response Sent: Checkout")
+}
+
+// placeOrder reserves stock for the cart lines, redeems the coupon if one is
+// given and creates the order, waiting for payment, in one transaction
+func placeOrder(ctx context.Context, client *firestore.Client, email, couponCode string) (models.Order, error) {
+	cart, err := fetchCart(ctx, client, email)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if len(cart.Lines) == 0 {
+		return models.Order{}, errEmptyCart
+	}
+
+	// promotions and price schedules don't need to be read inside the transaction
+	promotions, err := fetchActivePromotions(ctx, client)
+	if err != nil {
+		return models.Order{}, err
+	}
+	changes := make(map[int][]models.PriceChange, len(cart.Lines))
+	for _, line := range cart.Lines {
+		if changes[line.ItemID], err = fetchPriceChanges(ctx, client, line.ItemID); err != nil {
+			return models.Order{}, err
+		}
+	}
+
+	cartRef := client.Collection("carts").Doc(email)
+	orderRef := client.Collection("orders").NewDoc()
+
+	var order models.Order
+	err = client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		cartDoc, err := tx.Get(cartRef)
+		if status.Code(err) == codes.NotFound {
+			return errEmptyCart
+		}
+		if err != nil {
+			return err
+		}
+		var cart models.Cart
+		if err := cartDoc.DataTo(&cart); err != nil {
+			return err
+		}
+		if len(cart.Lines) == 0 {
+			return errEmptyCart
+		}
+
+		items := make(map[int]models.GroceryItem, len(cart.Lines))
+		refs := make(map[int]*firestore.DocumentRef, len(cart.Lines))
+		for _, line := range cart.Lines {
+			query := client.Collection("groceryItems").Where("ID", "==", line.ItemID).Limit(1)
+			doc, err := tx.Documents(query).Next()
+			if err == iterator.Done {
+				return fmt.Errorf("%w: %d", errItemNotFound, line.ItemID)
+			}
+			if err != nil {
+				return err
+			}
+
+			var item models.GroceryItem
+			if err := doc.DataTo(&item); err != nil {
+				return err
+			}
+			items[line.ItemID] = item
+			refs[line.ItemID] = doc.Ref
+		}
+		if short := stockShortages(cart.Lines, items); len(short) > 0 {
+			return &stockError{items: short}
+		}
+
+		now := time.Now().UTC()
+		view := priceLines(cart.Lines, items, changes, promotions, now)
+
+		// the coupon document is read and written in this transaction, so
+		// concurrent checkouts using the same coupon are serialized by Firestore
+		var couponRef *firestore.DocumentRef
+		couponDiscount := 0.0
+		if couponCode != "" {
+			couponRef = client.Collection("coupons").Doc(couponCode)
+			couponDoc, err := tx.Get(couponRef)
+			if status.Code(err) == codes.NotFound {
+				return &couponError{"coupon does not exist"}
+			}
+			if err != nil {
+				return err
+			}
+			var coupon models.Coupon
+			if err := couponDoc.DataTo(&coupon); err != nil {
+				return err
+			}
+
+			query := client.Collection("couponRedemptions").Where("Code", "==", couponCode).Where("UserEmail", "==", email)
+			used, err := countCouponUses(tx.Documents(query))
+			if err != nil {
+				return err
+			}
+
+			if couponDiscount, err = evaluateCoupon(coupon, view, items, used, now); err != nil {
+				return err
+			}
+		}
+
+		order = models.Order{
+			UserEmail: email,
+			Lines:     view.Lines,
+			Subtotal:  view.Subtotal,
+			Discount:  repository.RoundPrice(view.Discount + couponDiscount),
+			Total:     repository.RoundPrice(view.Total - couponDiscount),
+			Status:    orderStatusPendingPayment,
+			StatusHistory: []models.OrderStatusChange{
+				{Status: orderStatusPendingPayment, ChangedBy: email, ChangedAt: now},
+			},
+			CouponCode:     couponCode,
+			CouponDiscount: couponDiscount,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+
+		markReserved(order.Lines, items)
+		for _, line := range order.Lines {
+			if !line.Reserved {
+				continue
+			}
+			if err := tx.Update(refs[line.ItemID], []firestore.Update{
+				{Path: "Stock", Value: firestore.Increment(-line.Quantity)},
+			}); err != nil {
+				return err
+			}
+		}
+		if couponRef != nil {
+			if err := tx.Update(couponRef, []firestore.Update{
+				{Path: "Redemptions", Value: firestore.Increment(1)},
+			}); err != nil {
+				return err
+			}
+			if err := tx.Create(client.Collection("couponRedemptions").Doc(orderRef.ID), models.CouponRedemption{
+				Code:       couponCode,
+				UserEmail:  email,
+				OrderID:    orderRef.ID,
+				Discount:   couponDiscount,
+				RedeemedAt: now,
+			}); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(orderRef, order); err != nil {
+			return err
+		}
+		return tx.Delete(cartRef)
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	order.ID = orderRef.ID
+	return order, nil
+}
+
+// ListOrders lists the orders of the logged in user.
+// @Summary List my orders
+// @Description Lists the orders of the logged in user, newest first. Do provide 'Bearer' before adding authorization token
+// @ID list-orders
+// @Produce json
+// @Param Authorization header string true "token"
+// @Success 200 {array} models.Order "Orders"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /orders [get]
+// @Security BearerToken
+func ListOrders(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	iter := client.Collection("orders").Where("UserEmail", "==", email).Documents(context.Background())
+
+	orders := []models.Order{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Print("Failed to read orders from Firestore:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to read orders from Firestore")
+			return
+		}
+		var order models.Order
+		if err := doc.DataTo(&order); err != nil {
+			log.Print("Failed to parse order:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to parse order")
+			return
+		}
+		order.ID = doc.Ref.ID
+		orders = append(orders, order)
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+
+	respondWithJSON(w, http.StatusOK, orders)
+}
+
+// FetchOrder fetches one order of the logged in user.
+// @Summary Fetch an order
+// @Description Fetches an order of the logged in user by its ID. Do provide 'Bearer' before adding authorization token
+// @ID fetch-order
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param orderId path string true "ID of the order"
+// @Success 200 {object} models.Order "Order"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /orders/{orderId} [get]
+// @Security BearerToken
+func FetchOrder(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	orderID := parts[len(parts)-1]
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	order, err := fetchOrder(context.Background(), client, orderID)
+	if err == errOrderNotFound || (err == nil && order.UserEmail != email) {
+		respondWithError(w, http.StatusNotFound, "Order not found")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to read order:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read order")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, order)
+}
+
+// UpdateOrderStatus moves an order to its next status.
+// @Summary Update the status of an order
+// @Description Moves a paid order through packed, out-for-delivery and delivered. Customers can only cancel their own orders before they are out for delivery; other changes need an admin or manager. Orders waiting for their payment can't be cancelled. Cancelling releases the reserved stock and refunds captured payments. placed and payment-failed are only set by the payment flow. Do provide 'Bearer' before adding authorization token
+// @ID update-order-status
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param orderId path string true "ID of the order"
+// @Param status body orderStatusRequest true "New status"
+// @Success 200 {object} models.Order "Order"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 409 {object} ErrorResponse "Invalid status transition"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /orders/{orderId}/status [put]
+// @Security BearerToken
+func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	// path is /orders/{orderId}/status
+	parts := strings.Split(strings.TrimSuffix(r.URL.Path, "/status"), "/")
+	orderID := parts[len(parts)-1]
+
+	var req orderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if req.Status == orderStatusPlaced || req.Status == orderStatusPaymentFailed {
+		respondWithError(w, http.StatusBadRequest, "Status "+req.Status+" is set by the payment flow")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	order, err := fetchOrder(ctx, client, orderID)
+	if err == errOrderNotFound {
+		respondWithError(w, http.StatusNotFound, "Order not found")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to read order:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read order")
+		return
+	}
+
+	role, err := fetchUserRole(ctx, client, email)
+	if err != nil {
+		log.Print("Failed to read user role:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read user role")
+		return
+	}
+	staff := isStaffRole(role)
+	if !staff && (order.UserEmail != email || req.Status != orderStatusCancelled) {
+		respondWithError(w, http.StatusForbidden, "Only staff can change the status of this order")
+		return
+	}
+	// the payment may be captured at any moment, stale ones are released by
+	// ReleaseStaleOrders
+	if order.Status == orderStatusPendingPayment {
+		respondWithError(w, http.StatusConflict, "The order is waiting for its payment, it can be cancelled once the payment completes")
+		return
+	}
+
+	order, err = transitionOrder(ctx, client, orderID, req.Status, email, nil)
+	if err != nil {
+		if errors.Is(err, errInvalidTransition) {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		log.Print("Failed to update order status:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update order status")
+		return
+	}
+
+	auditRecord := GenerateAuditRecord("order-"+order.Status, orderID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	if order.Status == orderStatusCancelled && order.PaymentStatus == "captured" {
+		if err := refundOrder(ctx, client, order); err != nil {
+			log.Print("Failed to refund order:", err)
+			respondWithError(w, http.StatusInternalServerError, "Order cancelled but the refund failed")
+			return
+		}
+		if order, err = fetchOrder(ctx, client, orderID); err != nil {
+			log.Print("Failed to read order:", err)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, order)
+}
+
+// transitionOrder moves an order to newStatus, releasing its stock and coupon
+// use when it is cancelled or its payment failed. update, when not nil, can change other
+// fields of the order in the same transaction.
+func transitionOrder(ctx context.Context, client *firestore.Client, orderID, newStatus, changedBy string, update func(order *models.Order)) (models.Order, error) {
+	orderRef := client.Collection("orders").Doc(orderID)
+
+	var order models.Order
+	err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(orderRef)
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&order); err != nil {
+			return err
+		}
+
+		if !canTransition(order.Status, newStatus) {
+			return fmt.Errorf("%w: can not move order from %s to %s", errInvalidTransition, order.Status, newStatus)
+		}
+
+		release := newStatus == orderStatusCancelled || newStatus == orderStatusPaymentFailed
+
+		// all reads have to happen before the writes
+		var redemptionRef *firestore.DocumentRef
+		if release && order.CouponCode != "" {
+			redemptionRef = client.Collection("couponRedemptions").Doc(orderID)
+			redemptionDoc, err := tx.Get(redemptionRef)
+			if err != nil {
+				return err
+			}
+			var redemption models.CouponRedemption
+			if err := redemptionDoc.DataTo(&redemption); err != nil {
+				return err
+			}
+			if redemption.Released {
+				redemptionRef = nil
+			}
+		}
+
+		var itemRefs []*firestore.DocumentRef
+		if release {
+			for _, line := range order.Lines {
+				// only the stock taken at checkout is given back
+				if !line.Reserved {
+					itemRefs = append(itemRefs, nil)
+					continue
+				}
+				query := client.Collection("groceryItems").Where("ID", "==", line.ItemID).Limit(1)
+				itemDoc, err := tx.Documents(query).Next()
+				if err == iterator.Done {
+					itemRefs = append(itemRefs, nil)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				itemRefs = append(itemRefs, itemDoc.Ref)
+			}
+		}
+
+		for i, ref := range itemRefs {
+			if ref == nil {
+				continue
+			}
+			if err := tx.Update(ref, []firestore.Update{
+				{Path: "Stock", Value: firestore.Increment(order.Lines[i].Quantity)},
+			}); err != nil {
+				return err
+			}
+		}
+
+		if redemptionRef != nil {
+			if err := tx.Update(client.Collection("coupons").Doc(order.CouponCode), []firestore.Update{
+				{Path: "Redemptions", Value: firestore.Increment(-1)},
+			}); err != nil {
+				return err
+			}
+			if err := tx.Update(redemptionRef, []firestore.Update{{Path: "Released", Value: true}}); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now().UTC()
+		if update != nil {
+			update(&order)
+		}
+		order.Status = newStatus
+		order.UpdatedAt = now
+		order.StatusHistory = append(order.StatusHistory, models.OrderStatusChange{
+			Status: newStatus, ChangedBy: changedBy, ChangedAt: now,
+		})
+
+		return tx.Set(orderRef, order)
+	})
+
+	order.ID = orderID
+	return order, err
+}
+
+// ReleaseStaleOrders gives up on orders still waiting for their payment.
+// @Summary Release orders stuck waiting for payment
+// @Description Job endpoint, meant to be called periodically (for example by Cloud Scheduler). Orders waiting for their payment for more than 30 minutes, e.g. because the server stopped during checkout, are marked payment-failed, which releases their stock and coupon. A payment captured for such an order is refunded. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID release-stale-orders
+// @Produce json
+// @Param Authorization header string true "token"
+// @Success 200 {object} map[string]int "Number of released and refunded orders"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Failure 503 {object} ErrorResponse "Payments are not configured"
+// @Router /releaseStaleOrders [post]
+// @Security BearerToken
+func ReleaseStaleOrders(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isStaff(w, r) {
+		return
+	}
+
+	if paymentProvider == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Payments are not configured")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	summary, err := releaseStaleOrders(context.Background(), client, time.Now().UTC().Add(-stalePendingPayment))
+	if err != nil {
+		log.Print("Failed to release stale orders:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to release stale orders")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, summary)
+	log.Print("Response Sent: ReleaseStaleOrders")
+}
+
+// releaseStaleOrders marks the orders waiting for their payment since before
+// cutoff payment-failed, and refunds the ones whose capture went through
+func releaseStaleOrders(ctx context.Context, client *firestore.Client, cutoff time.Time) (map[string]int, error) {
+	summary := map[string]int{"released": 0, "refunded": 0}
+
+	iter := client.Collection("orders").
+		Where("Status", "==", orderStatusPendingPayment).
+		Where("CreatedAt", "<", cutoff).
+		Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return summary, nil
+		}
+		if err != nil {
+			return summary, err
+		}
+
+		capture, err := capturedPayment(ctx, client, doc.Ref.ID)
+		if err != nil {
+			return summary, err
+		}
+
+		order, err := transitionOrder(ctx, client, doc.Ref.ID, orderStatusPaymentFailed, "stale-order-sweep", func(o *models.Order) {
+			o.PaymentStatus = paymentStatusFailed
+			if capture != "" {
+				o.PaymentReference = capture
+				o.PaymentStatus = "captured"
+			}
+		})
+		if errors.Is(err, errInvalidTransition) {
+			// the payment completed in the meantime
+			continue
+		}
+		if err != nil {
+			return summary, err
+		}
+		summary["released"]++
+
+		auditRecord := GenerateAuditRecord("order-"+order.Status, order.ID)
+		log.Printf("Audit Record: %+v", auditRecord)
+		if err := PublishAuditRecord(auditRecord); err != nil {
+			log.Println("Failed to publish audit record:", err)
+		}
+
+		if capture != "" {
+			if err := refundOrder(ctx, client, order); err != nil {
+				return summary, err
+			}
+			summary["refunded"]++
+		}
+	}
+}
+
+// capturedPayment returns the reference of the successful capture of an
+// order, empty when there is none
+func capturedPayment(ctx context.Context, client *firestore.Client, orderID string) (string, error) {
+	doc, err := client.Collection("paymentAttempts").
+		Where("OrderID", "==", orderID).
+		Where("Operation", "==", "capture").
+		Where("Status", "==", paymentStatusSucceeded).
+		Limit(1).Documents(ctx).Next()
+	if err == iterator.Done {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var attempt models.PaymentAttempt
+	if err := doc.DataTo(&attempt); err != nil {
+		return "", err
+	}
+	return attempt.Reference, nil
+}
+
+// stockShortages returns the names of the items the lines want more of than is
+// in stock. Items without a stock, e.g. added before stock was tracked, aren't
+// reserved and never run short.
+func stockShortages(lines []models.CartLine, items map[int]models.GroceryItem) []string {
+	var short []string
+	for _, line := range lines {
+		item := items[line.ItemID]
+		if item.Stock != nil && *item.Stock < line.Quantity {
+			short = append(short, item.ProductName)
+		}
+	}
+	return short
+}
+
+// markReserved flags the lines whose stock checkout takes, the ones of items
+// with a stock. Releasing an order gives back the stock of those lines only,
+// whether their items track stock by then or not.
+func markReserved(lines []models.OrderLine, items map[int]models.GroceryItem) {
+	for i := range lines {
+		lines[i].Reserved = items[lines[i].ItemID].Stock != nil
+	}
+}
+
+func canTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+func fetchOrder(ctx context.Context, client *firestore.Client, orderID string) (models.Order, error) {
+	var order models.Order
+
+	doc, err := client.Collection("orders").Doc(orderID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return order, errOrderNotFound
+	}
+	if err != nil {
+		return order, err
+	}
+
+	err = doc.DataTo(&order)
+	order.ID = doc.Ref.ID
+	return order, err
+}
+
+func fetchUserRole(ctx context.Context, client *firestore.Client, email string) (string, error) {
+	doc, err := client.Collection("users").Where("Email", "==", email).Limit(1).Documents(ctx).Next()
+	if err == iterator.Done {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var user models.User
+	if err := doc.DataTo(&user); err != nil {
+		return "", err
+	}
+	return strings.ToLower(user.Role), nil
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"example.com/capstone/models"
+)
+
+func TestStockShortages(t *testing.T) {
+	// an item stored before stock was tracked has no stock field at all
+	var preExisting models.GroceryItem
+	if err := json.Unmarshal([]byte(`{"id": 1, "productName": "Basmati Rice", "price": 120}`), &preExisting); err != nil {
+		t.Fatal(err)
+	}
+	stock := func(n int) *int { return &n }
+
+	items := map[int]models.GroceryItem{
+		1: preExisting,
+		2: {ID: 2, ProductName: "Ghee", Stock: stock(5)},
+		3: {ID: 3, ProductName: "Paneer", Stock: stock(0)},
+	}
+
+	tests := []struct {
+		name  string
+		lines []models.CartLine
+		want  []string
+	}{
+		{"untracked item is never short", []models.CartLine{{ItemID: 1, Quantity: 100}}, nil},
+		{"enough stock", []models.CartLine{{ItemID: 2, Quantity: 5}}, nil},
+		{"not enough stock", []models.CartLine{{ItemID: 2, Quantity: 6}}, []string{"Ghee"}},
+		{"out of stock", []models.CartLine{{ItemID: 1, Quantity: 1}, {ItemID: 3, Quantity: 1}}, []string{"Paneer"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := stockShortages(test.lines, items); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMarkReserved(t *testing.T) {
+	stock := 5
+	items := map[int]models.GroceryItem{
+		1: {ID: 1, ProductName: "Basmati Rice"},
+		2: {ID: 2, ProductName: "Ghee", Stock: &stock},
+	}
+	lines := []models.OrderLine{{ItemID: 1, Quantity: 2}, {ItemID: 2, Quantity: 1}}
+
+	markReserved(lines, items)
+	if lines[0].Reserved || !lines[1].Reserved {
+		t.Errorf("got reserved %v and %v, want only the tracked item reserved", lines[0].Reserved, lines[1].Reserved)
+	}
+}
@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/capstone/models"
+	"example.com/capstone/utils"
+)
+
+const (
+	paymentStatusSucceeded = "succeeded"
+	paymentStatusDeclined  = "declined"
+	paymentStatusFailed    = "failed"
+)
+
+var errInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// PaymentProvider is a payment gateway. Declines are reported through
+// PaymentResult.Status, errors are reserved for failures to talk to the gateway.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, req PaymentRequest) (PaymentResult, error)
+	Capture(ctx context.Context, authorizationRef string, amount float64) (PaymentResult, error)
+	Refund(ctx context.Context, captureRef string, amount float64) (PaymentResult, error)
+	VerifyWebhook(payload []byte, signature string) (PaymentWebhookEvent, error)
+}
+
+type PaymentRequest struct {
+	OrderID      string
+	Amount       float64
+	Currency     string
+	PaymentToken string // card or wallet token obtained by the client from the gateway
+	Email        string
+}
+
+type PaymentResult struct {
+	Status    string `json:"status"` // succeeded, declined or failed
+	Reference string `json:"reference"`
+	Message   string `json:"message"`
+}
+
+// PaymentWebhookEvent is a verified notification sent by the gateway
+type PaymentWebhookEvent struct {
+	Type      string  `json:"type"` // capture.succeeded, capture.failed or refund.succeeded
+	OrderID   string  `json:"orderID"`
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+}
+
+// paymentProvider is the gateway used at checkout, set by ConfigurePayments
+var paymentProvider PaymentProvider
+
+// ConfigurePayments sets up the payment gateway named by PAYMENT_PROVIDER.
+// Without one payments stay unconfigured and checkout answers 503. The mock
+// gateway approves any payment token, so it is only used when set to mock,
+// and it needs PAYMENT_WEBHOOK_SECRET, which webhooks are verified with. A
+// gateway that can't be set up is an error, the server doesn't start.
+func ConfigurePayments() error {
+	provider := os.Getenv("PAYMENT_PROVIDER")
+	if provider == "" {
+		log.Print("PAYMENT_PROVIDER is not set, checkout is disabled")
+		return nil
+	}
+
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if secret == "" {
+		return errors.New("PAYMENT_WEBHOOK_SECRET is not set")
+	}
+
+	switch provider {
+	case "mock":
+		log.Print("Payments go through the mock gateway")
+		paymentProvider = NewMockPaymentProvider(secret)
+		return nil
+	default:
+		return fmt.Errorf("unknown PAYMENT_PROVIDER %q", provider)
+	}
+}
+
+// MockPaymentProvider is a local gateway for development and tests. It never
+// calls out and always gives the same answer for the same input:
+//   - payment token "tok_decline" is declined at authorization
+//   - payment token "tok_capture_fail" is authorized but declined at capture
+//   - any other token succeeds
+//
+// References are derived from the order ID, and webhooks are signed with
+// HMAC-SHA256 of the payload using the webhook secret.
+type MockPaymentProvider struct {
+	webhookSecret string
+}
+
+func NewMockPaymentProvider(webhookSecret string) *MockPaymentProvider {
+	return &MockPaymentProvider{webhookSecret: webhookSecret}
+}
+
+func (p *MockPaymentProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockPaymentProvider) Authorize(ctx context.Context, req PaymentRequest) (PaymentResult, error) {
+	if req.Amount <= 0 {
+		return PaymentResult{Status: paymentStatusFailed, Message: "amount must be greater than zero"}, nil
+	}
+
+	switch req.PaymentToken {
+	case "":
+		return PaymentResult{Status: paymentStatusFailed, Message: "payment token is required"}, nil
+	case "tok_decline":
+		return PaymentResult{Status: paymentStatusDeclined, Message: "card declined"}, nil
+	}
+
+	reference := "mock_auth_" + mockReference(req.OrderID)
+	if req.PaymentToken == "tok_capture_fail" {
+		// the reference carries the outcome of the capture so the mock stays stateless
+		reference += "_cf"
+	}
+	return PaymentResult{Status: paymentStatusSucceeded, Reference: reference}, nil
+}
+
+func (p *MockPaymentProvider) Capture(ctx context.Context, authorizationRef string, amount float64) (PaymentResult, error) {
+	if !strings.HasPrefix(authorizationRef, "mock_auth_") {
+		return PaymentResult{Status: paymentStatusFailed, Message: "unknown authorization"}, nil
+	}
+	if strings.HasSuffix(authorizationRef, "_cf") {
+		return PaymentResult{Status: paymentStatusDeclined, Message: "capture declined"}, nil
+	}
+	return PaymentResult{Status: paymentStatusSucceeded, Reference: "mock_cap_" + mockReference(authorizationRef)}, nil
+}
+
+func (p *MockPaymentProvider) Refund(ctx context.Context, captureRef string, amount float64) (PaymentResult, error) {
+	if !strings.HasPrefix(captureRef, "mock_cap_") {
+		return PaymentResult{Status: paymentStatusFailed, Message: "unknown capture"}, nil
+	}
+	return PaymentResult{Status: paymentStatusSucceeded, Reference: "mock_ref_" + mockReference(captureRef)}, nil
+}
+
+func (p *MockPaymentProvider) VerifyWebhook(payload []byte, signature string) (PaymentWebhookEvent, error) {
+	var event PaymentWebhookEvent
+
+	if !hmac.Equal([]byte(signature), []byte(p.Sign(payload))) {
+		return event, errInvalidWebhookSignature
+	}
+
+	err := json.Unmarshal(payload, &event)
+	return event, err
+}
+
+// Sign returns the signature the mock gateway puts on a webhook payload
+func (p *MockPaymentProvider) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.webhookSecret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func mockReference(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:8])
+}
+
+// PaymentWebhook receives payment notifications from the gateway.
+// @Summary Payment gateway webhook
+// @Description Receives signed notifications from the payment gateway. The signature is read from the X-Payment-Signature header. A capture.succeeded event moves an order waiting for payment to placed, capture.failed marks it payment-failed
+// @ID payment-webhook
+// @Accept json
+// @Produce json
+// @Param X-Payment-Signature header string true "Signature of the payload"
+// @Param event body PaymentWebhookEvent true "Webhook event"
+// @Success 200 {object} map[string]string "Webhook processed"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Invalid signature"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Failure 503 {object} ErrorResponse "Payments are not configured"
+// @Router /payments/webhook [post]
+func PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	if paymentProvider == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Payments are not configured")
+		return
+	}
+
+	event, err := paymentProvider.VerifyWebhook(payload, r.Header.Get("X-Payment-Signature"))
+	if err == errInvalidWebhookSignature {
+		log.Print("Rejected payment webhook with invalid signature")
+		respondWithError(w, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook payload")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	recordPaymentAttempt(ctx, client, event.OrderID, "webhook", event.Amount, PaymentResult{
+		Status:    paymentStatusSucceeded,
+		Reference: event.Reference,
+		Message:   event.Type,
+	})
+
+	switch event.Type {
+	case "capture.succeeded":
+		_, err = transitionOrder(ctx, client, event.OrderID, orderStatusPlaced, paymentProvider.Name(), func(order *models.Order) {
+			order.PaymentReference = event.Reference
+			order.PaymentStatus = "captured"
+		})
+	case "capture.failed":
+		_, err = transitionOrder(ctx, client, event.OrderID, orderStatusPaymentFailed, paymentProvider.Name(), func(order *models.Order) {
+			order.PaymentStatus = paymentStatusFailed
+		})
+	case "refund.succeeded":
+		_, err = client.Collection("orders").Doc(event.OrderID).Update(ctx, []firestore.Update{
+			{Path: "PaymentStatus", Value: "refunded"},
+		})
+	default:
+		log.Printf("Ignoring payment webhook of type %s", event.Type)
+	}
+
+	// the gateway retries webhooks, so an order that already moved on is not an error
+	if err != nil && !errors.Is(err, errInvalidTransition) {
+		log.Print("Failed to process payment webhook:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to process webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Webhook processed"})
+}
+
+// payForOrder authorizes and captures the order total. The order only moves to
+// placed when the capture succeeds, otherwise it is marked payment-failed and
+// its stock is released.
+func payForOrder(ctx context.Context, client *firestore.Client, order models.Order, paymentToken string) (models.Order, bool, error) {
+	result := settlePayment(ctx, paymentProvider, order, paymentToken, func(operation string, result PaymentResult) {
+		recordPaymentAttempt(ctx, client, order.ID, operation, order.Total, result)
+	})
+
+	if result.Status != paymentStatusSucceeded {
+		failed, err := transitionOrder(ctx, client, order.ID, orderStatusPaymentFailed, paymentProvider.Name(), func(o *models.Order) {
+			o.PaymentProvider = paymentProvider.Name()
+			o.PaymentStatus = paymentStatusFailed
+		})
+		return failed, false, err
+	}
+
+	placed, err := transitionOrder(ctx, client, order.ID, orderStatusPlaced, paymentProvider.Name(), func(o *models.Order) {
+		o.PaymentProvider = paymentProvider.Name()
+		o.PaymentReference = result.Reference
+		o.PaymentStatus = "captured"
+		if order.Total <= 0 {
+			o.PaymentStatus = paymentStatusNotRequired
+		}
+	})
+	if errors.Is(err, errInvalidTransition) {
+		// the order was released while the payment ran, e.g. by staff or the
+		// stale order sweep, so the captured money goes back. The sweep
+		// refunds the captures it finds itself.
+		released, err := fetchOrder(ctx, client, order.ID)
+		if err != nil {
+			return order, false, err
+		}
+		if order.Total > 0 && released.PaymentReference != result.Reference {
+			released.PaymentReference = result.Reference
+			if err := refundOrder(ctx, client, released); err != nil {
+				return released, false, err
+			}
+		}
+		return released, false, errOrderReleased
+	}
+	return placed, true, err
+}
+
+// errOrderReleased is returned by payForOrder for an order released while its
+// payment ran
+var errOrderReleased = errors.New("the order was released while it was being paid, the payment was refunded")
+
+// paymentStatusNotRequired is the payment status of orders a coupon made free,
+// which are never sent to the gateway and so never refunded
+const paymentStatusNotRequired = "not-required"
+
+// settlePayment authorizes and captures the order total with provider, passing
+// the outcome of each call to record. An order with nothing to pay succeeds
+// without calling the gateway, which rejects zero amounts.
+func settlePayment(ctx context.Context, provider PaymentProvider, order models.Order, paymentToken string, record func(operation string, result PaymentResult)) PaymentResult {
+	if order.Total <= 0 {
+		return PaymentResult{Status: paymentStatusSucceeded, Message: "nothing to pay"}
+	}
+
+	authorization, err := provider.Authorize(ctx, PaymentRequest{
+		OrderID:      order.ID,
+		Amount:       order.Total,
+		Currency:     "INR",
+		PaymentToken: paymentToken,
+		Email:        order.UserEmail,
+	})
+	if err != nil {
+		authorization = PaymentResult{Status: paymentStatusFailed, Message: err.Error()}
+	}
+	record("authorize", authorization)
+	if authorization.Status != paymentStatusSucceeded {
+		return authorization
+	}
+
+	result, err := provider.Capture(ctx, authorization.Reference, order.Total)
+	if err != nil {
+		result = PaymentResult{Status: paymentStatusFailed, Message: err.Error()}
+	}
+	record("capture", result)
+	return result
+}
+
+// refundOrder refunds the captured amount of an order
+func refundOrder(ctx context.Context, client *firestore.Client, order models.Order) error {
+	result, err := paymentProvider.Refund(ctx, order.PaymentReference, order.Total)
+	if err != nil {
+		result = PaymentResult{Status: paymentStatusFailed, Message: err.Error()}
+	}
+	recordPaymentAttempt(ctx, client, order.ID, "refund", order.Total, result)
+
+	paymentStatus := "refunded"
+	if result.Status != paymentStatusSucceeded {
+		paymentStatus = "refund-failed"
+	}
+
+	_, err = client.Collection("orders").Doc(order.ID).Update(ctx, []firestore.Update{
+		{Path: "PaymentStatus", Value: paymentStatus},
+	})
+	return err
+}
+
+// recordPaymentAttempt stores the outcome of a gateway call and publishes an audit record for it
+func recordPaymentAttempt(ctx context.Context, client *firestore.Client, orderID, operation string, amount float64, result PaymentResult) {
+	attempt := models.PaymentAttempt{
+		OrderID:   orderID,
+		Provider:  paymentProvider.Name(),
+		Operation: operation,
+		Amount:    amount,
+		Status:    result.Status,
+		Reference: result.Reference,
+		Message:   result.Message,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if _, _, err := client.Collection("paymentAttempts").Add(ctx, attempt); err != nil {
+		log.Print("Failed to store payment attempt:", err)
+	}
+
+	auditRecord := GenerateAuditRecord("payment-"+operation+"-"+result.Status, orderID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+}
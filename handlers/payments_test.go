@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+)
+
+func TestSettlePayment(t *testing.T) {
+	provider := NewMockPaymentProvider("test-secret")
+
+	tests := []struct {
+		name       string
+		total      float64
+		token      string
+		wantStatus string
+		wantCalls  []string
+	}{
+		{"free order skips the gateway", 0, "", paymentStatusSucceeded, nil},
+		{"paid order is authorized and captured", 120, "tok_visa", paymentStatusSucceeded, []string{"authorize", "capture"}},
+		{"declined authorization isn't captured", 120, "tok_decline", paymentStatusDeclined, []string{"authorize"}},
+		{"declined capture", 120, "tok_capture_fail", paymentStatusDeclined, []string{"authorize", "capture"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []string
+			order := models.Order{ID: "order-1", UserEmail: "user@example.com", Total: test.total}
+			result := settlePayment(context.Background(), provider, order, test.token, func(operation string, result PaymentResult) {
+				calls = append(calls, operation)
+			})
+			if result.Status != test.wantStatus {
+				t.Errorf("status %q, want %q (%s)", result.Status, test.wantStatus, result.Message)
+			}
+			if !reflect.DeepEqual(calls, test.wantCalls) {
+				t.Errorf("gateway calls %v, want %v", calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFullDiscountCouponMakesOrderFree(t *testing.T) {
+	items := map[int]models.GroceryItem{1: {ID: 1, ProductName: "Ghee", Price: 250}}
+	view := CartView{Lines: []models.OrderLine{{ItemID: 1, Quantity: 2, UnitPrice: 250, LineTotal: 500}}, Subtotal: 500, Total: 500}
+
+	coupons := []models.Coupon{
+		{Code: "FREE100", Type: promotionTypePercentage, Value: 100, Active: true},
+		{Code: "FLAT1000", Type: promotionTypeFlat, Value: 1000, Active: true},
+	}
+	for _, coupon := range coupons {
+		t.Run(coupon.Code, func(t *testing.T) {
+			discount, err := evaluateCoupon(coupon, view, items, 0, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			order := models.Order{ID: "order-1", Total: repository.RoundPrice(view.Total - discount)}
+			if order.Total != 0 {
+				t.Fatalf("total %v, want 0", order.Total)
+			}
+
+			result := settlePayment(context.Background(), NewMockPaymentProvider("test-secret"), order, "", func(string, PaymentResult) {
+				t.Error("the gateway was called for a free order")
+			})
+			if result.Status != paymentStatusSucceeded {
+				t.Errorf("status %q, want %q", result.Status, paymentStatusSucceeded)
+			}
+		})
+	}
+}
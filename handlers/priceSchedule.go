@@ -0,0 +1,617 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	priceChangeStatusPending   = "pending"
+	priceChangeStatusActive    = "active"
+	priceChangeStatusApplied   = "applied"
+	priceChangeStatusExpired   = "expired"
+	priceChangeStatusCancelled = "cancelled"
+)
+
+type priceChangeRequest struct {
+	Price         float64 `json:"price"`
+	EffectiveFrom string  `json:"effectiveFrom"`
+	EffectiveTo   string  `json:"effectiveTo"`
+}
+
+// SchedulePriceChange schedules a future price change for a grocery item.
+// @Summary Schedule a price change
+// @Description Schedules a price change for a grocery item. effectiveFrom and effectiveTo are RFC3339 timestamps, leave effectiveTo empty for a permanent change. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID schedule-price-change
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param id path integer true "ID of the grocery item"
+// @Param priceChange body priceChangeRequest true "Price and effective window"
+// @Success 201 {object} models.PriceChange "Price change scheduled"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /schedulePriceChange/{id} [post]
+// @Security BearerToken
+func SchedulePriceChange(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isStaff(w, r) {
+		return
+	}
+
+	id, err := itemIDFromPath(r)
+	if err != nil {
+		log.Print("Invalid Item ID:", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid Item ID")
+		return
+	}
+
+	var req priceChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if req.Price <= 0 {
+		respondWithError(w, http.StatusBadRequest, "price must be greater than zero")
+		return
+	}
+
+	effectiveFrom, err := time.Parse(time.RFC3339, req.EffectiveFrom)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "effectiveFrom must be an RFC3339 timestamp")
+		return
+	}
+
+	var effectiveTo time.Time
+	if req.EffectiveTo != "" {
+		effectiveTo, err = time.Parse(time.RFC3339, req.EffectiveTo)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "effectiveTo must be an RFC3339 timestamp")
+			return
+		}
+		if !effectiveTo.After(effectiveFrom) {
+			respondWithError(w, http.StatusBadRequest, "effectiveTo must be after effectiveFrom")
+			return
+		}
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	// make sure the item exists before scheduling anything for it
+	iter := client.Collection("groceryItems").Where("ID", "==", id).Limit(1).Documents(ctx)
+	if _, err := iter.Next(); err == iterator.Done {
+		respondWithError(w, http.StatusNotFound, "Grocery item not found")
+		return
+	} else if err != nil {
+		log.Print("Failed to read grocery item data from Firestore:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read grocery item data from Firestore")
+		return
+	}
+
+	change := models.PriceChange{
+		ItemID:        id,
+		Price:         req.Price,
+		EffectiveFrom: effectiveFrom.UTC(),
+		EffectiveTo:   effectiveTo.UTC(),
+		Status:        priceChangeStatusPending,
+		Source:        "schedule",
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	docRef, _, err := client.Collection("priceSchedules").Add(ctx, change)
+	if err != nil {
+		log.Print("Failed to store price change in Firestore:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to store price change")
+		return
+	}
+	change.ID = docRef.ID
+
+	respondWithJSON(w, http.StatusCreated, change)
+	log.Print("Response Sent: SchedulePriceChange")
+}
+
+// CancelPriceChange cancels a pending or active price change.
+// @Summary Cancel a scheduled price change
+// @Description Cancels a price change that has not been applied yet, or ends a temporary price window early. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID cancel-price-change
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param changeId path string true "ID of the price change"
+// @Success 200 {object} map[string]string "Price change cancelled"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 409 {object} ErrorResponse "Price change already applied"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /cancelPriceChange/{changeId} [delete]
+// @Security BearerToken
+func CancelPriceChange(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isStaff(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	changeID := parts[len(parts)-1]
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docRef := client.Collection("priceSchedules").Doc(changeID)
+
+	doc, err := docRef.Get(ctx)
+	if err != nil {
+		log.Print("Price change not found:", err)
+		respondWithError(w, http.StatusNotFound, "Price change not found")
+		return
+	}
+
+	var change models.PriceChange
+	if err := doc.DataTo(&change); err != nil {
+		log.Print("Failed to parse price change:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to parse price change")
+		return
+	}
+
+	if change.Status != priceChangeStatusPending && change.Status != priceChangeStatusActive {
+		respondWithError(w, http.StatusConflict, "Price change is already "+change.Status)
+		return
+	}
+
+	if _, err := docRef.Update(ctx, []firestore.Update{{Path: "Status", Value: priceChangeStatusCancelled}}); err != nil {
+		log.Print("Failed to cancel price change:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to cancel price change")
+		return
+	}
+
+	auditRecord := GenerateAuditRecord("price-change-cancelled", strconv.Itoa(change.ItemID))
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Price change cancelled"})
+}
+
+// PriceHistory lists the price changes of a grocery item.
+// @Summary Price history of a grocery item
+// @Description Returns every recorded and scheduled price change of a grocery item ordered by effectiveFrom, together with the price effective now or at asOf
+// @ID price-history
+// @Produce json
+// @Param id path integer true "ID of the grocery item"
+// @Param asOf query string false "RFC3339 timestamp or date (2006-01-02) to resolve the price at"
+// @Success 200 {object} map[string]interface{} "Price history"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /priceHistory/{id} [get]
+func PriceHistory(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	id, err := itemIDFromPath(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Item ID")
+		return
+	}
+
+	asOf, err := parseAsOf(r.URL.Query().Get("asOf"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "asOf must be an RFC3339 timestamp or a date (2006-01-02)")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	doc, err := client.Collection("groceryItems").Where("ID", "==", id).Limit(1).Documents(ctx).Next()
+	if err == iterator.Done {
+		respondWithError(w, http.StatusNotFound, "Grocery item not found")
+		return
+	} else if err != nil {
+		log.Print("Failed to read grocery item data from Firestore:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read grocery item data from Firestore")
+		return
+	}
+
+	var item models.GroceryItem
+	if err := doc.DataTo(&item); err != nil {
+		log.Print("Failed to parse grocery item data from Firestore:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to parse grocery item data from Firestore")
+		return
+	}
+
+	changes, err := fetchPriceChanges(ctx, client, id)
+	if err != nil {
+		log.Print("Failed to read price changes:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read price changes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"itemID":  id,
+		"asOf":    asOf,
+		"price":   resolvePrice(item.Price, changes, asOf),
+		"changes": changes,
+	})
+}
+
+// ApplyPriceSchedules applies pending price changes whose effective time has come.
+// @Summary Apply scheduled price changes
+// @Description Job endpoint, meant to be called periodically (for example by Cloud Scheduler). Applies permanent price changes to the catalog, starts and ends temporary price windows and publishes an audit record for every change. The item stores the price in effect, the price of an active window included, so listings, filters, sorts and search use the price customers pay. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID apply-price-schedules
+// @Produce json
+// @Param Authorization header string true "token"
+// @Success 200 {object} map[string]int "Summary of applied changes"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /applyPriceSchedules [post]
+// @Security BearerToken
+func ApplyPriceSchedules(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isStaff(w, r) {
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	summary, err := applyPendingPriceChanges(context.Background(), client, time.Now().UTC())
+	if err != nil {
+		log.Print("Failed to apply price schedules:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to apply price schedules")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, summary)
+	log.Print("Response Sent: ApplyPriceSchedules")
+}
+
+// applyPendingPriceChanges moves pending and active price changes forward to
+// the state they should be in at now, and writes the price in effect to the item
+func applyPendingPriceChanges(ctx context.Context, client *firestore.Client, now time.Time) (map[string]int, error) {
+	summary := map[string]int{"applied": 0, "activated": 0, "expired": 0}
+
+	iter := client.Collection("priceSchedules").
+		Where("Status", "in", []string{priceChangeStatusPending, priceChangeStatusActive}).
+		Documents(ctx)
+
+	changesByItem := map[int][]*firestore.DocumentSnapshot{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var change models.PriceChange
+		if err := doc.DataTo(&change); err != nil {
+			return nil, err
+		}
+		changesByItem[change.ItemID] = append(changesByItem[change.ItemID], doc)
+	}
+
+	for itemID, docs := range changesByItem {
+		itemDoc, err := client.Collection("groceryItems").Where("ID", "==", itemID).Limit(1).Documents(ctx).Next()
+		if err == iterator.Done {
+			log.Printf("Skipping price changes of missing grocery item %d", itemID)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var item models.GroceryItem
+		if err := itemDoc.DataTo(&item); err != nil {
+			return nil, err
+		}
+
+		changes := make([]models.PriceChange, len(docs))
+		for i, doc := range docs {
+			doc.DataTo(&changes[i])
+			changes[i].ID = doc.Ref.ID
+		}
+
+		// apply in order of effective time so previous prices chain correctly
+		sort.SliceStable(changes, func(i, j int) bool {
+			return changes[i].EffectiveFrom.Before(changes[j].EffectiveFrom)
+		})
+
+		price, changed, audits := advancePriceChanges(item.Price, changes, now)
+		if len(changed) == 0 {
+			continue
+		}
+
+		batch := client.Batch()
+		for _, i := range changed {
+			change := changes[i]
+			batch.Update(client.Collection("priceSchedules").Doc(change.ID), []firestore.Update{
+				{Path: "Status", Value: change.Status},
+				{Path: "PreviousPrice", Value: change.PreviousPrice},
+				{Path: "AppliedAt", Value: change.AppliedAt},
+			})
+		}
+		for _, action := range audits {
+			switch action {
+			case "price-change":
+				summary["applied"]++
+			case "price-window-start":
+				summary["activated"]++
+			case "price-window-end":
+				summary["expired"]++
+			}
+		}
+
+		updated := item
+		updated.Price = price
+		if price != item.Price {
+			batch.Update(itemDoc.Ref, []firestore.Update{
+				{Path: "Price", Value: price},
+				{Path: "UnitPrice", Value: repository.UnitPrice(updated)},
+			})
+		}
+
+		if _, err := batch.Commit(ctx); err != nil {
+			return nil, err
+		}
+
+		if price != item.Price {
+			catalogChanged(&item, &updated)
+		}
+
+		for _, action := range audits {
+			auditRecord := GenerateAuditRecord(action, strconv.Itoa(itemID))
+			log.Printf("Audit Record: %+v", auditRecord)
+			if err := PublishAuditRecord(auditRecord); err != nil {
+				log.Println("Failed to publish audit record:", err)
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// advancePriceChanges moves changes, sorted by effective time, to the state
+// they should be in at now and returns the price to store on the item, the
+// indexes of the changes it updated and an audit action for each step.
+//
+// The stored price is the price in effect: while a window is active its price
+// is stored and the regular price is kept as the window's PreviousPrice, so
+// listings, filters, sorts and search all see the price customers pay.
+func advancePriceChanges(storedPrice float64, changes []models.PriceChange, now time.Time) (float64, []int, []string) {
+	regular := regularPrice(storedPrice, changes)
+	var changed []int
+	var audits []string
+
+	for i := range changes {
+		change := &changes[i]
+		switch {
+		case change.EffectiveFrom.After(now):
+			continue
+
+		case change.EffectiveTo.IsZero():
+			change.Status = priceChangeStatusApplied
+			change.PreviousPrice = regular
+			change.AppliedAt = now
+			regular = change.Price
+			audits = append(audits, "price-change")
+
+		case !now.Before(change.EffectiveTo):
+			change.Status = priceChangeStatusExpired
+			audits = append(audits, "price-window-end")
+
+		case change.Status == priceChangeStatusPending:
+			change.Status = priceChangeStatusActive
+			change.PreviousPrice = regular
+			change.AppliedAt = now
+			audits = append(audits, "price-window-start")
+
+		default:
+			continue
+		}
+		changed = append(changed, i)
+	}
+
+	// the latest active window sets the price, and keeps the regular price it
+	// gives back when it ends
+	window := -1
+	for i := range changes {
+		if changes[i].Status == priceChangeStatusActive {
+			window = i
+		}
+	}
+	if window < 0 {
+		return regular, changed, audits
+	}
+	if changes[window].PreviousPrice != regular {
+		changes[window].PreviousPrice = regular
+		if !slices.Contains(changed, window) {
+			changed = append(changed, window)
+		}
+	}
+	return changes[window].Price, changed, audits
+}
+
+// regularPrice is the price of an item without its price windows. The price of
+// an active window is stored while it lasts, unless the item was written since.
+func regularPrice(storedPrice float64, changes []models.PriceChange) float64 {
+	for i := len(changes) - 1; i >= 0; i-- {
+		if change := changes[i]; change.Status == priceChangeStatusActive {
+			if storedPrice == change.Price {
+				return change.PreviousPrice
+			}
+			break
+		}
+	}
+	return storedPrice
+}
+
+// recordPriceChange stores a direct price edit so it shows up in the price history
+func recordPriceChange(ctx context.Context, client *firestore.Client, itemID int, previousPrice, price float64) error {
+	now := time.Now().UTC()
+	_, _, err := client.Collection("priceSchedules").Add(ctx, models.PriceChange{
+		ItemID:        itemID,
+		Price:         price,
+		PreviousPrice: previousPrice,
+		EffectiveFrom: now,
+		Status:        priceChangeStatusApplied,
+		Source:        "update",
+		CreatedAt:     now,
+		AppliedAt:     now,
+	})
+	return err
+}
+
+func fetchPriceChanges(ctx context.Context, client *firestore.Client, itemID int) ([]models.PriceChange, error) {
+	changes, err := readPriceChanges(client.Collection("priceSchedules").Where("ItemID", "==", itemID).Documents(ctx))
+	if err != nil {
+		return nil, err
+	}
+	sortPriceChanges(changes)
+	return changes, nil
+}
+
+func readPriceChanges(iter *firestore.DocumentIterator) ([]models.PriceChange, error) {
+	defer iter.Stop()
+
+	var changes []models.PriceChange
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return changes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var change models.PriceChange
+		if err := doc.DataTo(&change); err != nil {
+			return nil, err
+		}
+		change.ID = doc.Ref.ID
+		changes = append(changes, change)
+	}
+}
+
+func sortPriceChanges(changes []models.PriceChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].EffectiveFrom.Before(changes[j].EffectiveFrom)
+	})
+}
+
+// resolvePrice returns the price of an item at the given time.
+// An active temporary window wins over permanent changes, and among permanent
+// changes the one that started last wins. Once the latest permanent change
+// has been applied the stored price is current, it can have been written since
+// without a recorded change, e.g. by an import. When nothing had started yet
+// at that time, the price before the first applied change is used, falling
+// back to the regular price currently stored on the item.
+func resolvePrice(storedPrice float64, changes []models.PriceChange, at time.Time) float64 {
+	storedPrice = regularPrice(storedPrice, changes)
+
+	var window, permanent, nextPermanent *models.PriceChange
+
+	for i := range changes {
+		change := &changes[i]
+		if change.Status == priceChangeStatusCancelled {
+			continue
+		}
+
+		if change.EffectiveFrom.After(at) {
+			if change.EffectiveTo.IsZero() && change.Status == priceChangeStatusApplied &&
+				(nextPermanent == nil || change.EffectiveFrom.Before(nextPermanent.EffectiveFrom)) {
+				nextPermanent = change
+			}
+			continue
+		}
+
+		if change.EffectiveTo.IsZero() {
+			if permanent == nil || !change.EffectiveFrom.Before(permanent.EffectiveFrom) {
+				permanent = change
+			}
+		} else if at.Before(change.EffectiveTo) {
+			if window == nil || !change.EffectiveFrom.Before(window.EffectiveFrom) {
+				window = change
+			}
+		}
+	}
+
+	switch {
+	case window != nil:
+		return window.Price
+	case permanent != nil && permanent.Status == priceChangeStatusApplied && nextPermanent == nil:
+		return storedPrice
+	case permanent != nil:
+		return permanent.Price
+	case nextPermanent != nil:
+		return nextPermanent.PreviousPrice
+	default:
+		return storedPrice
+	}
+}
+
+// parseAsOf parses the asOf query parameter, an empty value means now
+func parseAsOf(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now().UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// itemIDFromPath reads the trailing numeric ID from the request path
+func itemIDFromPath(r *http.Request) (int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+	return strconv.Atoi(parts[len(parts)-1])
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"example.com/capstone/models"
+)
+
+func TestResolvePrice(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	applied := models.PriceChange{Price: 90, PreviousPrice: 100, EffectiveFrom: now.Add(-10 * day), Status: priceChangeStatusApplied}
+	pending := models.PriceChange{Price: 80, PreviousPrice: 90, EffectiveFrom: now.Add(-day), Status: priceChangeStatusPending}
+	window := models.PriceChange{Price: 70, PreviousPrice: 90, EffectiveFrom: now.Add(-day), EffectiveTo: now.Add(day), Status: priceChangeStatusActive}
+	later := models.PriceChange{Price: 95, PreviousPrice: 90, EffectiveFrom: now.Add(5 * day), Status: priceChangeStatusApplied}
+
+	tests := []struct {
+		name    string
+		stored  float64
+		changes []models.PriceChange
+		at      time.Time
+		want    float64
+	}{
+		{"no changes", 100, nil, now, 100},
+		{"stored price written after the latest applied change", 85, []models.PriceChange{applied}, now, 85},
+		{"started change not applied yet", 90, []models.PriceChange{applied, pending}, now, 80},
+		{"window wins", 90, []models.PriceChange{applied, window}, now, 70},
+		{"stored window price after the window ended", 70, []models.PriceChange{applied, window}, now.Add(2 * day), 90},
+		{"before a later applied change", 95, []models.PriceChange{applied, later}, now, 90},
+		{"before any change", 90, []models.PriceChange{applied}, now.Add(-20 * day), 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := resolvePrice(test.stored, test.changes, test.at); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAdvancePriceChanges(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	window := models.PriceChange{Price: 70, EffectiveFrom: now.Add(-day), EffectiveTo: now.Add(day), Status: priceChangeStatusPending}
+	permanent := models.PriceChange{Price: 90, EffectiveFrom: now.Add(-day / 2), Status: priceChangeStatusPending}
+	future := models.PriceChange{Price: 60, EffectiveFrom: now.Add(day), Status: priceChangeStatusPending}
+
+	// the window starts and stores its price, the permanent change during it
+	// only moves the regular price
+	changes := []models.PriceChange{window, permanent, future}
+	price, changed, audits := advancePriceChanges(100, changes, now)
+	if price != 70 {
+		t.Errorf("price during the window is %v, want 70", price)
+	}
+	if !reflect.DeepEqual(changed, []int{0, 1}) || !reflect.DeepEqual(audits, []string{"price-window-start", "price-change"}) {
+		t.Errorf("changed %v with %v, want [0 1] with a window start and a price change", changed, audits)
+	}
+	if changes[0].Status != priceChangeStatusActive || changes[0].PreviousPrice != 90 {
+		t.Errorf("window is %s with previous price %v, want active with 90", changes[0].Status, changes[0].PreviousPrice)
+	}
+	if changes[1].PreviousPrice != 100 || changes[2].Status != priceChangeStatusPending {
+		t.Errorf("got changes %+v", changes)
+	}
+
+	// the window ends and gives back the regular price
+	price, _, audits = advancePriceChanges(price, changes[:1], now.Add(day))
+	if price != 90 || !reflect.DeepEqual(audits, []string{"price-window-end"}) {
+		t.Errorf("after the window got %v with %v, want 90 with a window end", price, audits)
+	}
+
+	// an item written during a window keeps the window price, the written
+	// price becomes the regular one
+	changes = []models.PriceChange{{Price: 70, PreviousPrice: 90, EffectiveFrom: now.Add(-day), EffectiveTo: now.Add(day), Status: priceChangeStatusActive}}
+	price, changed, _ = advancePriceChanges(85, changes, now)
+	if price != 70 || changes[0].PreviousPrice != 85 || !reflect.DeepEqual(changed, []int{0}) {
+		t.Errorf("after a write during the window got %v, previous price %v, changed %v", price, changes[0].PreviousPrice, changed)
+	}
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	promotionTypePercentage = "percentage"
+	promotionTypeFlat       = "flat"
+	promotionTypeBOGO       = "bogo"
+	promotionTypeMultiBuy   = "multibuy"
+)
+
+// PriceBreakdown is the outcome of evaluating promotions for a quantity of one item
+type PriceBreakdown struct {
+	Quantity          int      `json:"quantity"`
+	OriginalPrice     float64  `json:"originalPrice"`  // unit price before promotions
+	EffectivePrice    float64  `json:"effectivePrice"` // average unit price after promotions
+	LineTotal         float64  `json:"lineTotal"`
+	Discount          float64  `json:"discount"`
+	AppliedPromotions []string `json:"appliedPromotions"`
+}
+
+// CreatePromotion creates a new promotion rule.
+// @Summary Create a promotion
+// @Description Creates a promotion rule. type is one of percentage, flat, bogo (buy one get one) or multibuy (buyQuantity for the price of payQuantity). Scope it with itemIDs, categories, brands or tags; an empty scope applies to the whole catalog. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID create-promotion
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param promotion body models.Promotion true "Promotion rule"
+// @Success 201 {object} models.Promotion "Promotion created"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /createPromotion [post]
+// @Security BearerToken
+func CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isStaff(w, r) {
+		return
+	}
+
+	var promotion models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := validatePromotion(&promotion); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	promotion.Active = true
+	promotion.CreatedAt = time.Now().UTC()
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	docRef, _, err := client.Collection("promotions").Add(context.Background(), promotion)
+	if err != nil {
+		log.Print("Failed to create promotion in Firestore:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create promotion in Firestore")
+		return
+	}
+	promotion.ID = docRef.ID
+
+	auditRecord := GenerateAuditRecord("promotion-create", promotion.ID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	respondWithJSON(w, http.StatusCreated, promotion)
+	log.Print("Response Sent: CreatePromotion")
+}
+
+// ListPromotions lists promotions.
+// @Summary List promotions
+// @Description Lists all active promotions, including the ones that have not started yet or have already ended
+// @ID list-promotions
+// @Produce json
+// @Success 200 {array} models.Promotion "Promotions"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /listPromotions [get]
+func ListPromotions(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	promotions, err := fetchActivePromotions(context.Background(), client)
+	if err != nil {
+		log.Print("Failed to read promotions from Firestore:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read promotions from Firestore")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, promotions)
+}
+
+// DeletePromotion deletes a promotion.
+// @Summary Delete a promotion
+// @Description Deletes a promotion so it no longer applies. Requires an admin or manager role. Do provide 'Bearer' before adding authorization token
+// @ID delete-promotion
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param promotionId path string true "ID of the promotion"
+// @Success 200 {object} map[string]string "Promotion deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /deletePromotion/{promotionId} [delete]
+// @Security BearerToken
+func DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isStaff(w, r) {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	promotionID := parts[len(parts)-1]
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docRef := client.Collection("promotions").Doc(promotionID)
+	if _, err := docRef.Get(ctx); err != nil {
+		respondWithError(w, http.StatusNotFound, "Promotion not found")
+		return
+	}
+
+	if _, err := docRef.Delete(ctx); err != nil {
+		log.Print("Failed to delete promotion:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete promotion")
+		return
+	}
+
+	auditRecord := GenerateAuditRecord("promotion-delete", promotionID)
+	log.Printf("Audit Record: %+v", auditRecord)
+	if err := PublishAuditRecord(auditRecord); err != nil {
+		log.Println("Failed to publish audit record:", err)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Promotion deleted successfully"})
+}
+
+func validatePromotion(promotion *models.Promotion) error {
+	if strings.TrimSpace(promotion.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	switch promotion.Type {
+	case promotionTypePercentage:
+		if promotion.Value <= 0 || promotion.Value > 100 {
+			return errors.New("value of a percentage promotion must be between 0 and 100")
+		}
+	case promotionTypeFlat:
+		if promotion.Value <= 0 {
+			return errors.New("value of a flat promotion must be greater than zero")
+		}
+	case promotionTypeBOGO:
+		promotion.BuyQuantity, promotion.PayQuantity = 2, 1
+	case promotionTypeMultiBuy:
+		if promotion.PayQuantity < 1 || promotion.BuyQuantity <= promotion.PayQuantity {
+			return errors.New("multibuy needs buyQuantity greater than payQuantity, e.g. 3 for 2")
+		}
+	default:
+		return errors.New("type must be one of percentage, flat, bogo or multibuy")
+	}
+
+	if !promotion.EndsAt.IsZero() && !promotion.EndsAt.After(promotion.StartsAt) {
+		return errors.New("endsAt must be after startsAt")
+	}
+
+	return nil
+}
+
+func fetchActivePromotions(ctx context.Context, client *firestore.Client) ([]models.Promotion, error) {
+	iter := client.Collection("promotions").Where("Active", "==", true).Documents(ctx)
+
+	promotions := []models.Promotion{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var promotion models.Promotion
+		if err := doc.DataTo(&promotion); err != nil {
+			return nil, err
+		}
+		promotion.ID = doc.Ref.ID
+		promotions = append(promotions, promotion)
+	}
+
+	return promotions, nil
+}
+
+// evaluatePromotions works out the price of quantity units of item at the given time.
+//
+// Promotions are evaluated by priority (highest first, ties broken by ID) so the
+// result never depends on the order they were loaded in. The first applicable
+// promotion always applies; later ones only apply while every promotion applied
+// so far, and the candidate itself, are stackable. Per unit discounts
+// (percentage, flat) are applied first, quantity deals (bogo, multibuy) are then
+// taken off the discounted unit price.
+func evaluatePromotions(item models.GroceryItem, unitPrice float64, quantity int, promotions []models.Promotion, at time.Time) PriceBreakdown {
+	if quantity < 1 {
+		quantity = 1
+	}
+
+	candidates := make([]models.Promotion, 0, len(promotions))
+	for _, promotion := range promotions {
+		if promotionApplies(promotion, item, quantity, at) {
+			candidates = append(candidates, promotion)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Priority != candidates[j].Priority {
+			return candidates[i].Priority > candidates[j].Priority
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+
+	var selected []models.Promotion
+	for _, promotion := range candidates {
+		if len(selected) > 0 && (!promotion.Stackable || !selected[len(selected)-1].Stackable) {
+			continue
+		}
+		selected = append(selected, promotion)
+		if !promotion.Stackable {
+			break
+		}
+	}
+
+	breakdown := PriceBreakdown{
+		Quantity:          quantity,
+		OriginalPrice:     repository.RoundPrice(unitPrice),
+		AppliedPromotions: []string{},
+	}
+
+	price := unitPrice
+	for _, promotion := range selected {
+		switch promotion.Type {
+		case promotionTypePercentage:
+			price -= price * promotion.Value / 100
+		case promotionTypeFlat:
+			price -= promotion.Value
+		default:
+			continue
+		}
+		price = math.Max(price, 0)
+		breakdown.AppliedPromotions = append(breakdown.AppliedPromotions, promotion.ID)
+	}
+
+	total := price * float64(quantity)
+	for _, promotion := range selected {
+		if promotion.Type != promotionTypeBOGO && promotion.Type != promotionTypeMultiBuy {
+			continue
+		}
+		freeUnits := (quantity / promotion.BuyQuantity) * (promotion.BuyQuantity - promotion.PayQuantity)
+		total -= float64(freeUnits) * price
+		breakdown.AppliedPromotions = append(breakdown.AppliedPromotions, promotion.ID)
+	}
+
+	total = math.Max(total, 0)
+	breakdown.LineTotal = repository.RoundPrice(total)
+	breakdown.EffectivePrice = repository.RoundPrice(total / float64(quantity))
+	breakdown.Discount = repository.RoundPrice(unitPrice*float64(quantity) - breakdown.LineTotal)
+
+	return breakdown
+}
+
+// promotionApplies checks the validity window, the scope and, for quantity
+// deals, whether enough units are bought
+func promotionApplies(promotion models.Promotion, item models.GroceryItem, quantity int, at time.Time) bool {
+	if !promotion.Active || at.Before(promotion.StartsAt) {
+		return false
+	}
+	if !promotion.EndsAt.IsZero() && !at.Before(promotion.EndsAt) {
+		return false
+	}
+	if (promotion.Type == promotionTypeBOGO || promotion.Type == promotionTypeMultiBuy) &&
+		(promotion.BuyQuantity < 1 || quantity < promotion.BuyQuantity) {
+		return false
+	}
+
+	if len(promotion.ItemIDs) == 0 && len(promotion.Categories) == 0 && len(promotion.Brands) == 0 && len(promotion.Tags) == 0 {
+		return true
+	}
+
+	for _, id := range promotion.ItemIDs {
+		if id == item.ID {
+			return true
+		}
+	}
+	if containsFold(promotion.Categories, item.Category) || containsFold(promotion.Brands, item.Brand) {
+		return true
+	}
+	for _, tag := range item.Tags {
+		if containsFold(promotion.Tags, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(value)) {
+			return true
+		}
+	}
+	return false
+}
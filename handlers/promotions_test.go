@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"example.com/capstone/models"
+)
+
+func TestEvaluatePromotions(t *testing.T) {
+	at := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	item := models.GroceryItem{ID: 7, Category: "Dairy", Brand: "Amul", Tags: []string{"organic"}}
+
+	promotion := func(id, kind string, value float64, priority int, stackable bool) models.Promotion {
+		return models.Promotion{ID: id, Type: kind, Value: value, Priority: priority, Stackable: stackable, Active: true, StartsAt: at.AddDate(0, -1, 0)}
+	}
+	deal := func(id, kind string, buy, pay int) models.Promotion {
+		p := promotion(id, kind, 0, 0, false)
+		p.BuyQuantity, p.PayQuantity = buy, pay
+		return p
+	}
+	with := func(p models.Promotion, change func(p *models.Promotion)) models.Promotion {
+		change(&p)
+		return p
+	}
+
+	tests := []struct {
+		name       string
+		quantity   int
+		promotions []models.Promotion
+		want       PriceBreakdown
+	}{
+		{
+			name:     "no promotions",
+			quantity: 1,
+			want:     PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 100, LineTotal: 100, AppliedPromotions: []string{}},
+		},
+		{
+			name:       "percentage",
+			quantity:   2,
+			promotions: []models.Promotion{promotion("pct", promotionTypePercentage, 10, 0, false)},
+			want:       PriceBreakdown{Quantity: 2, OriginalPrice: 100, EffectivePrice: 90, LineTotal: 180, Discount: 20, AppliedPromotions: []string{"pct"}},
+		},
+		{
+			name:     "stackable promotions combine by priority",
+			quantity: 1,
+			promotions: []models.Promotion{
+				promotion("flat", promotionTypeFlat, 5, 1, true),
+				promotion("pct", promotionTypePercentage, 10, 2, true),
+			},
+			want: PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 85, LineTotal: 85, Discount: 15, AppliedPromotions: []string{"pct", "flat"}},
+		},
+		{
+			name:     "exclusive promotion with the highest priority wins alone",
+			quantity: 1,
+			promotions: []models.Promotion{
+				promotion("flat", promotionTypeFlat, 20, 1, true),
+				promotion("pct", promotionTypePercentage, 10, 5, false),
+			},
+			want: PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 90, LineTotal: 90, Discount: 10, AppliedPromotions: []string{"pct"}},
+		},
+		{
+			name:     "exclusive promotion doesn't join stackable ones",
+			quantity: 1,
+			promotions: []models.Promotion{
+				promotion("flat", promotionTypeFlat, 20, 1, false),
+				promotion("pct", promotionTypePercentage, 10, 5, true),
+			},
+			want: PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 90, LineTotal: 90, Discount: 10, AppliedPromotions: []string{"pct"}},
+		},
+		{
+			name:     "equal priorities are ordered by ID",
+			quantity: 1,
+			promotions: []models.Promotion{
+				promotion("b", promotionTypeFlat, 30, 1, false),
+				promotion("a", promotionTypePercentage, 10, 1, false),
+			},
+			want: PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 90, LineTotal: 90, Discount: 10, AppliedPromotions: []string{"a"}},
+		},
+		{
+			name:       "flat discount doesn't go below zero",
+			quantity:   1,
+			promotions: []models.Promotion{promotion("flat", promotionTypeFlat, 150, 0, false)},
+			want:       PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 0, LineTotal: 0, Discount: 100, AppliedPromotions: []string{"flat"}},
+		},
+		{
+			name:       "bogo",
+			quantity:   3,
+			promotions: []models.Promotion{deal("bogo", promotionTypeBOGO, 2, 1)},
+			want:       PriceBreakdown{Quantity: 3, OriginalPrice: 100, EffectivePrice: 66.67, LineTotal: 200, Discount: 100, AppliedPromotions: []string{"bogo"}},
+		},
+		{
+			name:       "bogo needs enough units",
+			quantity:   1,
+			promotions: []models.Promotion{deal("bogo", promotionTypeBOGO, 2, 1)},
+			want:       PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 100, LineTotal: 100, AppliedPromotions: []string{}},
+		},
+		{
+			name:       "multibuy",
+			quantity:   7,
+			promotions: []models.Promotion{deal("3for2", promotionTypeMultiBuy, 3, 2)},
+			want:       PriceBreakdown{Quantity: 7, OriginalPrice: 100, EffectivePrice: 71.43, LineTotal: 500, Discount: 200, AppliedPromotions: []string{"3for2"}},
+		},
+		{
+			name:     "multibuy is taken off the discounted unit price",
+			quantity: 3,
+			promotions: []models.Promotion{
+				with(deal("3for2", promotionTypeMultiBuy, 3, 2), func(p *models.Promotion) { p.Stackable = true }),
+				promotion("pct", promotionTypePercentage, 10, 1, true),
+			},
+			want: PriceBreakdown{Quantity: 3, OriginalPrice: 100, EffectivePrice: 60, LineTotal: 180, Discount: 120, AppliedPromotions: []string{"pct", "3for2"}},
+		},
+		{
+			name:     "outside the date window or inactive",
+			quantity: 1,
+			promotions: []models.Promotion{
+				with(promotion("future", promotionTypePercentage, 10, 0, true), func(p *models.Promotion) { p.StartsAt = at.Add(time.Hour) }),
+				with(promotion("ended", promotionTypePercentage, 10, 0, true), func(p *models.Promotion) { p.EndsAt = at }),
+				with(promotion("inactive", promotionTypePercentage, 10, 0, true), func(p *models.Promotion) { p.Active = false }),
+			},
+			want: PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 100, LineTotal: 100, AppliedPromotions: []string{}},
+		},
+		{
+			name:     "inside the date window",
+			quantity: 1,
+			promotions: []models.Promotion{
+				with(promotion("window", promotionTypePercentage, 10, 0, true), func(p *models.Promotion) { p.StartsAt, p.EndsAt = at, at.Add(time.Hour) }),
+			},
+			want: PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 90, LineTotal: 90, Discount: 10, AppliedPromotions: []string{"window"}},
+		},
+		{
+			name:     "scoped to tags, categories, brands and items",
+			quantity: 1,
+			promotions: []models.Promotion{
+				with(promotion("tag", promotionTypeFlat, 1, 4, true), func(p *models.Promotion) { p.Tags = []string{" ORGANIC "} }),
+				with(promotion("category", promotionTypeFlat, 2, 3, true), func(p *models.Promotion) { p.Categories = []string{"dairy"} }),
+				with(promotion("brand", promotionTypeFlat, 3, 2, true), func(p *models.Promotion) { p.Brands = []string{"Amul"} }),
+				with(promotion("item", promotionTypeFlat, 4, 1, true), func(p *models.Promotion) { p.ItemIDs = []int{7} }),
+				with(promotion("other tag", promotionTypeFlat, 50, 0, true), func(p *models.Promotion) { p.Tags = []string{"festive"} }),
+				with(promotion("other item", promotionTypeFlat, 50, 0, true), func(p *models.Promotion) { p.ItemIDs = []int{8} }),
+			},
+			want: PriceBreakdown{Quantity: 1, OriginalPrice: 100, EffectivePrice: 90, LineTotal: 90, Discount: 10, AppliedPromotions: []string{"tag", "category", "brand", "item"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := evaluatePromotions(item, 100, test.quantity, test.promotions, at)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEvaluatePromotionsIgnoresLoadOrder(t *testing.T) {
+	at := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	item := models.GroceryItem{ID: 1}
+	promotions := []models.Promotion{
+		{ID: "a", Type: promotionTypeFlat, Value: 5, Priority: 1, Stackable: true, Active: true},
+		{ID: "b", Type: promotionTypePercentage, Value: 20, Priority: 1, Stackable: true, Active: true},
+		{ID: "c", Type: promotionTypeFlat, Value: 30, Priority: 1, Active: true},
+	}
+	reversed := []models.Promotion{promotions[2], promotions[1], promotions[0]}
+
+	first := evaluatePromotions(item, 50, 1, promotions, at)
+	second := evaluatePromotions(item, 50, 1, reversed, at)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("results depend on the load order: %+v and %+v", first, second)
+	}
+}
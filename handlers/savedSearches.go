@@ -0,0 +1,593 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	savedSearchEventNewMatch  = "new-match"
+	savedSearchEventPriceDrop = "price-drop"
+
+	maxSavedSearchesPerUser = 20
+	maxInboxNotifications   = 100
+
+	// savedSearchCacheTTL bounds how long searches saved on another instance
+	// go unnoticed by the matcher of this one
+	savedSearchCacheTTL = time.Minute
+
+	// notificationQueueSize is how many item changes can wait for the
+	// notification worker before writing items waits for it too
+	notificationQueueSize = 10000
+	// notificationBatchSize is the most item changes matched at a time
+	notificationBatchSize = 200
+	// notificationsPerCommit is the most writes Firestore accepts in one batch
+	notificationsPerCommit = 500
+)
+
+// Notifier delivers saved search notifications outside the inbox, e.g. by
+// email or push. The notification is in the inbox whether delivery works or not.
+type Notifier interface {
+	Notify(ctx context.Context, notification models.Notification) error
+}
+
+// LogNotifier only logs notifications, for deployments without a delivery channel
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, n models.Notification) error {
+	log.Printf("Notification for %s: %s %q on item %d (%s)", n.UserEmail, n.Event, n.SearchName, n.ItemID, n.ProductName)
+	return nil
+}
+
+// notifier delivers the notifications recorded by the saved search matcher
+var notifier Notifier = LogNotifier{}
+
+// compiledSavedSearch is a saved search with its query parsed
+type compiledSavedSearch struct {
+	search  models.SavedSearch
+	filters []repository.Filter
+}
+
+var (
+	notificationQueue       = make(chan itemChange, notificationQueueSize)
+	startNotificationWorker sync.Once
+)
+
+var (
+	savedSearchCache       []compiledSavedSearch
+	savedSearchCacheMu     sync.Mutex
+	savedSearchCacheLoaded time.Time
+)
+
+type savedSearchRequest struct {
+	Name     string   `json:"name"`
+	Query    string   `json:"query"`    // list filters, e.g. category=Snacks&price[lt]=50&vegetarian=true
+	NotifyOn []string `json:"notifyOn"` // new-match and/or price-drop, both when empty
+}
+
+// CreateSavedSearch saves a listing query for the logged in user.
+// @Summary Save a search
+// @Description Saves list filters under a name. The user gets a notification in their inbox when an item starts matching them (new-match),
+// @Description or gets cheaper while matching them (price-drop). At most 20 searches per user. Do provide 'Bearer' before adding authorization token
+// @ID create-saved-search
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param search body savedSearchRequest true "Name and filters, e.g. {\"name\": \"cheap snacks\", \"query\": \"category=Snacks&price[lt]=50&vegetarian=true\"}"
+// @Success 201 {object} models.SavedSearch "Saved search"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /savedSearches [post]
+// @Security BearerToken
+func CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	var req savedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	search := models.SavedSearch{
+		UserEmail: email,
+		Name:      strings.TrimSpace(req.Name),
+		Query:     strings.TrimPrefix(strings.TrimSpace(req.Query), "?"),
+		NotifyOn:  req.NotifyOn,
+		CreatedAt: time.Now().UTC(),
+	}
+	if search.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if _, err := parseSavedSearchQuery(search.Query); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(search.NotifyOn) == 0 {
+		search.NotifyOn = []string{savedSearchEventNewMatch, savedSearchEventPriceDrop}
+	}
+	for _, event := range search.NotifyOn {
+		if event != savedSearchEventNewMatch && event != savedSearchEventPriceDrop {
+			respondWithError(w, http.StatusBadRequest, "notifyOn accepts new-match and price-drop")
+			return
+		}
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	existing, err := fetchSavedSearches(ctx, client.Collection("savedSearches").Where("UserEmail", "==", email))
+	if err != nil {
+		log.Print("Failed to read saved searches:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read saved searches")
+		return
+	}
+	if len(existing) >= maxSavedSearchesPerUser {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("at most %d searches can be saved", maxSavedSearchesPerUser))
+		return
+	}
+
+	ref, _, err := client.Collection("savedSearches").Add(ctx, search)
+	if err != nil {
+		log.Print("Failed to save search:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save search")
+		return
+	}
+	search.ID = ref.ID
+	invalidateSavedSearchCache()
+
+	respondWithJSON(w, http.StatusCreated, search)
+}
+
+// ListSavedSearches lists the saved searches of the logged in user.
+// @Summary List saved searches
+// @Description Lists the searches saved by the logged in user, newest first. Do provide 'Bearer' before adding authorization token
+// @ID list-saved-searches
+// @Produce json
+// @Param Authorization header string true "token"
+// @Success 200 {array} models.SavedSearch "Saved searches"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /savedSearches [get]
+// @Security BearerToken
+func ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	searches, err := fetchSavedSearches(context.Background(), client.Collection("savedSearches").Where("UserEmail", "==", email))
+	if err != nil {
+		log.Print("Failed to read saved searches:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read saved searches")
+		return
+	}
+
+	sort.SliceStable(searches, func(i, j int) bool {
+		return searches[i].CreatedAt.After(searches[j].CreatedAt)
+	})
+	respondWithJSON(w, http.StatusOK, searches)
+}
+
+// DeleteSavedSearch deletes a saved search of the logged in user.
+// @Summary Delete a saved search
+// @Description Deletes a saved search, its notifications stay in the inbox. Do provide 'Bearer' before adding authorization token
+// @ID delete-saved-search
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param searchId path string true "ID of the saved search"
+// @Success 200 {object} map[string]string "Deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /savedSearches/{searchId} [delete]
+// @Security BearerToken
+func DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	searchID := parts[len(parts)-1]
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	ref := client.Collection("savedSearches").Doc(searchID)
+	doc, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		respondWithError(w, http.StatusNotFound, "Saved search not found")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to read saved search:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read saved search")
+		return
+	}
+	var search models.SavedSearch
+	if err := doc.DataTo(&search); err != nil || search.UserEmail != email {
+		respondWithError(w, http.StatusNotFound, "Saved search not found")
+		return
+	}
+
+	if _, err := ref.Delete(ctx); err != nil {
+		log.Print("Failed to delete saved search:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete saved search")
+		return
+	}
+	invalidateSavedSearchCache()
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Saved search deleted"})
+}
+
+// ListNotifications returns the inbox of the logged in user.
+// @Summary Notification inbox
+// @Description Lists the latest 100 saved search notifications of the logged in user, newest first. Do provide 'Bearer' before adding authorization token
+// @ID list-notifications
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param unread query boolean false "Only return notifications that weren't marked read"
+// @Success 200 {array} models.Notification "Notifications"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /notifications [get]
+// @Security BearerToken
+func ListNotifications(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	query := client.Collection("notifications").Where("UserEmail", "==", email)
+	if r.URL.Query().Get("unread") == "true" {
+		query = query.Where("Read", "==", false)
+	}
+	iter := query.Documents(context.Background())
+	defer iter.Stop()
+
+	notifications := []models.Notification{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Print("Failed to read notifications from Firestore:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to read notifications from Firestore")
+			return
+		}
+		var notification models.Notification
+		if err := doc.DataTo(&notification); err != nil {
+			log.Print("Failed to parse notification:", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to parse notification")
+			return
+		}
+		notification.ID = doc.Ref.ID
+		notifications = append(notifications, notification)
+	}
+
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+	})
+	if len(notifications) > maxInboxNotifications {
+		notifications = notifications[:maxInboxNotifications]
+	}
+
+	respondWithJSON(w, http.StatusOK, notifications)
+}
+
+// MarkNotificationRead marks a notification of the logged in user as read.
+// @Summary Mark a notification read
+// @Description Marks a notification as read so it no longer shows with unread=true. Do provide 'Bearer' before adding authorization token
+// @ID mark-notification-read
+// @Produce json
+// @Param Authorization header string true "token"
+// @Param notificationId path string true "ID of the notification"
+// @Success 200 {object} map[string]string "Marked read"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Not Found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /notifications/{notificationId}/read [post]
+// @Security BearerToken
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	email, err := authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	notificationID := parts[len(parts)-2]
+
+	client, err := utils.CreateFirestoreClient()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	ref := client.Collection("notifications").Doc(notificationID)
+	doc, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		respondWithError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+	if err != nil {
+		log.Print("Failed to read notification:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to read notification")
+		return
+	}
+	var notification models.Notification
+	if err := doc.DataTo(&notification); err != nil || notification.UserEmail != email {
+		respondWithError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+
+	if _, err := ref.Update(ctx, []firestore.Update{{Path: "Read", Value: true}}); err != nil {
+		log.Print("Failed to update notification:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update notification")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Notification marked read"})
+}
+
+// parseSavedSearchQuery parses the filters of a saved search, only list
+// filters are allowed
+func parseSavedSearchQuery(query string) ([]repository.Filter, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("query is not a valid query string: %v", err)
+	}
+	filters, err := repository.ParseFilters(values)
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("query needs at least one filter, e.g. category=Snacks&price[lt]=50")
+	}
+	return filters, nil
+}
+
+// matchSavedSearch tells which event, if any, a change of an item is for a
+// saved search. An item that starts matching is a new match even if its price
+// dropped too; an item that stops matching is not reported.
+func matchSavedSearch(search compiledSavedSearch, prev, cur *models.GroceryItem) (string, bool) {
+	if cur == nil || !repository.Matches(*cur, search.filters) {
+		return "", false
+	}
+
+	event := savedSearchEventNewMatch
+	if prev != nil && repository.Matches(*prev, search.filters) {
+		if cur.Price >= prev.Price {
+			return "", false
+		}
+		event = savedSearchEventPriceDrop
+	}
+
+	for _, wanted := range search.search.NotifyOn {
+		if wanted == event {
+			return event, true
+		}
+	}
+	return "", false
+}
+
+// notifySavedSearches queues the change of an item for the notification
+// worker, so writing items doesn't wait for the saved searches to be matched
+// and the notifications to be recorded. It only blocks while the queue is full.
+func notifySavedSearches(prev, cur *models.GroceryItem) {
+	if cur == nil {
+		return
+	}
+
+	startNotificationWorker.Do(func() { go runNotificationWorker() })
+	notificationQueue <- newItemChange(prev, cur)
+}
+
+// runNotificationWorker takes the queued changes a batch at a time and
+// records their notifications with one Firestore client, kept for as long as
+// it works
+func runNotificationWorker() {
+	var client *firestore.Client
+	for change := range notificationQueue {
+		batch := []itemChange{change}
+	drain:
+		for len(batch) < notificationBatchSize {
+			select {
+			case change := <-notificationQueue:
+				batch = append(batch, change)
+			default:
+				break drain
+			}
+		}
+
+		if client == nil {
+			var err error
+			client, err = utils.CreateFirestoreClient()
+			if err != nil {
+				log.Printf("Failed to create Firestore client for notifications, dropping %d changes: %v", len(batch), err)
+				continue
+			}
+		}
+		if err := recordNotifications(context.Background(), client, batch); err != nil {
+			log.Print("Failed to record notifications:", err)
+			client.Close()
+			client = nil
+		}
+	}
+}
+
+// recordNotifications records a notification for every saved search a change
+// of batch is news for, and hands them to the notifier
+func recordNotifications(ctx context.Context, client *firestore.Client, batch []itemChange) error {
+	searches, err := loadSavedSearches(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	notifications := savedSearchNotifications(searches, batch, time.Now().UTC())
+	for start := 0; start < len(notifications); start += notificationsPerCommit {
+		chunk := notifications[start:min(start+notificationsPerCommit, len(notifications))]
+		writes := client.Batch()
+		for i := range chunk {
+			ref := client.Collection("notifications").NewDoc()
+			chunk[i].ID = ref.ID
+			writes.Create(ref, chunk[i])
+		}
+		if _, err := writes.Commit(ctx); err != nil {
+			return err
+		}
+
+		for _, notification := range chunk {
+			if err := notifier.Notify(ctx, notification); err != nil {
+				log.Print("Failed to deliver notification:", err)
+			}
+		}
+	}
+	return nil
+}
+
+// savedSearchNotifications returns a notification for every saved search each
+// change is news for
+func savedSearchNotifications(searches []compiledSavedSearch, changes []itemChange, now time.Time) []models.Notification {
+	var notifications []models.Notification
+	for _, change := range changes {
+		for _, search := range searches {
+			event, ok := matchSavedSearch(search, change.prev, change.cur)
+			if !ok {
+				continue
+			}
+
+			notification := models.Notification{
+				UserEmail:     search.search.UserEmail,
+				SavedSearchID: search.search.ID,
+				SearchName:    search.search.Name,
+				Event:         event,
+				ItemID:        change.cur.ID,
+				ProductName:   change.cur.ProductName,
+				Price:         change.cur.Price,
+				CreatedAt:     now,
+			}
+			if event == savedSearchEventPriceDrop {
+				notification.PreviousPrice = change.prev.Price
+			}
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications
+}
+
+// loadSavedSearches returns every saved search, read again from Firestore
+// once the cached ones are older than savedSearchCacheTTL
+func loadSavedSearches(ctx context.Context, client *firestore.Client) ([]compiledSavedSearch, error) {
+	savedSearchCacheMu.Lock()
+	defer savedSearchCacheMu.Unlock()
+
+	if time.Since(savedSearchCacheLoaded) <= savedSearchCacheTTL {
+		return savedSearchCache, nil
+	}
+
+	searches, err := fetchSavedSearches(ctx, client.Collection("savedSearches").Query)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := make([]compiledSavedSearch, 0, len(searches))
+	for _, search := range searches {
+		filters, err := parseSavedSearchQuery(search.Query)
+		if err != nil {
+			// validated when saved, only possible if the query grammar changed since
+			log.Printf("Skipping saved search %s: %v", search.ID, err)
+			continue
+		}
+		compiled = append(compiled, compiledSavedSearch{search: search, filters: filters})
+	}
+
+	savedSearchCache = compiled
+	savedSearchCacheLoaded = time.Now()
+	return compiled, nil
+}
+
+func invalidateSavedSearchCache() {
+	savedSearchCacheMu.Lock()
+	savedSearchCacheLoaded = time.Time{}
+	savedSearchCacheMu.Unlock()
+}
+
+func fetchSavedSearches(ctx context.Context, query firestore.Query) ([]models.SavedSearch, error) {
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	searches := []models.SavedSearch{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return searches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var search models.SavedSearch
+		if err := doc.DataTo(&search); err != nil {
+			return nil, err
+		}
+		search.ID = doc.Ref.ID
+		searches = append(searches, search)
+	}
+}
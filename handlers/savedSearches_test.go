@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"example.com/capstone/models"
+)
+
+func TestSavedSearchNotifications(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	compile := func(id, query string, notifyOn ...string) compiledSavedSearch {
+		filters, err := parseSavedSearchQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		search := models.SavedSearch{ID: id, UserEmail: id + "@example.com", Name: id, Query: query, NotifyOn: notifyOn}
+		return compiledSavedSearch{search: search, filters: filters}
+	}
+	searches := []compiledSavedSearch{
+		compile("cheap", "category=Snacks&price[lt]=50", savedSearchEventNewMatch, savedSearchEventPriceDrop),
+		compile("snacks", "category=Snacks", savedSearchEventPriceDrop),
+	}
+
+	bhujia := models.GroceryItem{ID: 1, ProductName: "Bhujia", Category: "Snacks", Price: 60}
+	// a price window starting takes the shown price from 60 to 45, and a
+	// window ending takes it back
+	window := bhujia
+	window.Price = 45
+	ghee := models.GroceryItem{ID: 2, ProductName: "Ghee", Category: "Dairy", Price: 40}
+
+	changes := []itemChange{
+		newItemChange(&bhujia, &window),
+		newItemChange(&window, &bhujia),
+		newItemChange(nil, &ghee),
+	}
+	got := savedSearchNotifications(searches, changes, now)
+
+	want := []models.Notification{
+		{UserEmail: "cheap@example.com", SavedSearchID: "cheap", SearchName: "cheap", Event: savedSearchEventNewMatch, ItemID: 1, ProductName: "Bhujia", Price: 45, CreatedAt: now},
+		{UserEmail: "snacks@example.com", SavedSearchID: "snacks", SearchName: "snacks", Event: savedSearchEventPriceDrop, ItemID: 1, ProductName: "Bhujia", Price: 45, PreviousPrice: 60, CreatedAt: now},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
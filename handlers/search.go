@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+	"example.com/capstone/search"
+	"example.com/capstone/utils"
+)
+
+// searchIndexTTL bounds how stale the index, and the suggestions built along
+// with it, can get when the catalog is changed by another instance, after that
+// they are rebuilt from the repository in the background
+const searchIndexTTL = 10 * time.Minute
+
+// searchIndexes are the search index and the suggestions, built together from
+// the same items
+type searchIndexes struct {
+	index     *search.Index
+	suggester *search.Suggester
+	built     time.Time
+}
+
+var (
+	// currentSearchIndexes is replaced as a whole by a rebuild, so requests
+	// keep searching the previous indexes until the new ones are complete
+	currentSearchIndexes atomic.Pointer[searchIndexes]
+	// searchBuildMu is held by the one rebuild running at a time
+	searchBuildMu sync.Mutex
+	// searchIndexMu guards the changes synced while a rebuild runs, which
+	// are replayed on the new indexes before they are swapped in
+	searchIndexMu         sync.Mutex
+	rebuildingSearchIndex bool
+	pendingSearchChanges  []itemChange
+)
+
+// defaultSearchFields are returned by search when fields isn't set
+var defaultSearchFields = []string{"id", "productName", "brand", "category", "price", "thumbnailURL"}
+
+// SearchResult is one page of search hits, each with its relevance score
+type SearchResult struct {
+	Items      []map[string]interface{} `json:"items"`
+	TotalCount int                      `json:"totalCount"`
+	Facets     []repository.FacetResult `json:"facets,omitempty"` // counted over every hit, not only this page
+}
+
+// SearchItems searches the catalog.
+// @Summary Search grocery items
+// @Description Full-text search over productName, brand, manufacturer, category and packageInformation.
+// @Description Words are matched regardless of case and plural form, partial words match as prefixes and small typos are tolerated.
+// @Description Items matching every word come first, ranked by relevance. The list filters (e.g. price[lte]=100&vegetarian=true) can be combined with q.
+// @ID search-items
+// @Produce json
+// @Param q query string true "Search text, e.g. baby care oil"
+// @Param fields query string false "Fields to return, e.g. id,productName,price. Defaults to id,productName,brand,category,price,thumbnailURL"
+// @Param facets query string false "Facets to count over all hits, same syntax as in listGroceryItems, e.g. category,vegetarian,price:0-50-100-500"
+// @Param pageSize query integer false "Number of hits per page, 10 by default and at most 100" format(int32)
+// @Param pageNumber query integer false "Page number" format(int32)
+// @Success 200 {object} SearchResult "Matching grocery items"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /search [get]
+func SearchItems(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		respondWithError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	filters, err := repository.ParseFilters(r.URL.Query(), "q", "fields", "facets", "pageSize", "pageNumber")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	facetRequests, err := repository.ParseFacets(r.URL.Query().Get("facets"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fields, err := repository.ParseFields(r.URL.Query().Get("fields"), defaultSearchFields...)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	indexes, err := loadSearchIndex(context.Background())
+	if err != nil {
+		log.Print("Failed to build search index:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to build search index")
+		return
+	}
+
+	hits := indexes.index.Search(q, func(item models.GroceryItem) bool {
+		return repository.Matches(item, filters)
+	})
+
+	result := SearchResult{Items: []map[string]interface{}{}, TotalCount: len(hits)}
+	start := (page.number - 1) * page.size
+	for i := start; i < len(hits) && i < start+page.size; i++ {
+		hit := repository.Project(hits[i].Item, fields)
+		hit["score"] = hits[i].Score
+		result.Items = append(result.Items, hit)
+	}
+
+	if len(facetRequests) > 0 {
+		items := make([]models.GroceryItem, len(hits))
+		for i, hit := range hits {
+			items[i] = hit.Item
+		}
+		result.Facets = repository.CountFacets(items, facetRequests)
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+	log.Print("Response Sent: SearchItems")
+}
+
+// RebuildSearchIndex rebuilds the search index from the repository.
+// @Summary Rebuild the search index
+// @Description Reindexes the whole catalog and the typeahead suggestions. The index is kept in sync by the item endpoints and rebuilt periodically, this is for changes made directly in the database. Do provide 'Bearer' before adding authorization token
+// @ID rebuild-search-index
+// @Produce json
+// @Param Authorization header string true "token"
+// @Success 200 {object} map[string]int "Number of indexed items"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /search/rebuild [post]
+// @Security BearerToken
+func RebuildSearchIndex(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	if !isAuthorized(w, r) {
+		return
+	}
+
+	searchBuildMu.Lock()
+	err := rebuildSearchIndex(context.Background())
+	searchBuildMu.Unlock()
+	if err != nil {
+		log.Print("Failed to rebuild search index:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to rebuild search index")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]int{"indexed": currentSearchIndexes.Load().index.Len()})
+}
+
+// loadSearchIndex returns the search indexes. Only the first call waits for
+// them to be built, once they are older than searchIndexTTL they are served
+// as they are while a rebuild runs in the background.
+func loadSearchIndex(ctx context.Context) (*searchIndexes, error) {
+	if indexes := currentSearchIndexes.Load(); indexes != nil {
+		if time.Since(indexes.built) > searchIndexTTL {
+			refreshSearchIndex()
+		}
+		return indexes, nil
+	}
+
+	searchBuildMu.Lock()
+	defer searchBuildMu.Unlock()
+
+	// another request may have built them while this one waited
+	if indexes := currentSearchIndexes.Load(); indexes != nil {
+		return indexes, nil
+	}
+	if err := rebuildSearchIndex(ctx); err != nil {
+		return nil, err
+	}
+	return currentSearchIndexes.Load(), nil
+}
+
+// refreshSearchIndex rebuilds the search indexes in the background, unless a
+// rebuild is running already
+func refreshSearchIndex() {
+	if !searchBuildMu.TryLock() {
+		return
+	}
+	go func() {
+		defer searchBuildMu.Unlock()
+		if err := rebuildSearchIndex(context.Background()); err != nil {
+			log.Print("Failed to refresh search index:", err)
+		}
+	}()
+}
+
+// rebuildSearchIndex builds new search indexes from the repository and swaps
+// them in. It has to be called with searchBuildMu held.
+func rebuildSearchIndex(ctx context.Context) error {
+	searchIndexMu.Lock()
+	rebuildingSearchIndex = true
+	searchIndexMu.Unlock()
+
+	indexes, err := buildSearchIndex(ctx)
+
+	searchIndexMu.Lock()
+	defer searchIndexMu.Unlock()
+	if err == nil {
+		// the items were listed before these changes, or already have them,
+		// replaying them either way leaves the indexes up to date
+		for _, change := range pendingSearchChanges {
+			indexes.sync(change.prev, change.cur)
+		}
+		currentSearchIndexes.Store(indexes)
+		log.Printf("Search index rebuilt with %d items", indexes.index.Len())
+	}
+	rebuildingSearchIndex = false
+	pendingSearchChanges = nil
+	return err
+}
+
+func buildSearchIndex(ctx context.Context) (*searchIndexes, error) {
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Close()
+
+	built := time.Now()
+	items, err := repo.List(ctx, repository.Query{})
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := &searchIndexes{index: search.NewIndex(), suggester: search.NewSuggester(), built: built}
+	indexes.index.Rebuild(items)
+	indexes.suggester.Rebuild(items)
+	return indexes, nil
+}
+
+func syncSearchIndex(prev, cur *models.GroceryItem) {
+	searchIndexMu.Lock()
+	defer searchIndexMu.Unlock()
+
+	if rebuildingSearchIndex {
+		pendingSearchChanges = append(pendingSearchChanges, newItemChange(prev, cur))
+	}
+	if indexes := currentSearchIndexes.Load(); indexes != nil {
+		indexes.sync(prev, cur)
+	}
+}
+
+func (x *searchIndexes) sync(prev, cur *models.GroceryItem) {
+	switch {
+	case cur != nil:
+		x.index.Add(*cur)
+		x.suggester.Add(*cur)
+	case prev != nil:
+		x.index.Remove(prev.ID)
+		x.suggester.Remove(prev.ID)
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"example.com/capstone/repository"
+	"example.com/capstone/utils"
+)
+
+// VegetarianStats counts the matching vegetarian and non-vegetarian items
+type VegetarianStats struct {
+	Vegetarian    int64 `json:"vegetarian"`
+	NonVegetarian int64 `json:"nonVegetarian"`
+}
+
+// PriceStatistics computes price statistics per group.
+// @Summary Price statistics
+// @Description Count, min, max, average and median price of the items matching the filters, overall and per category, brand or countryOfOrigin.
+// @Description Takes the same filters as listGroceryItems, e.g. price[lte]=100&vegetarian=true. Range filters can only be on price.
+// @Description At most 50 groups are returned, the first ones by value, and truncated is true when more groups match.
+// @ID price-statistics
+// @Produce json
+// @Param groupBy query string false "category (default), brand or countryOfOrigin"
+// @Param category query string false "Filter by category"
+// @Param vegetarian query boolean false "Filter by vegetarian"
+// @Success 200 {object} repository.PriceStatsResult "Price statistics"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /stats/prices [get]
+func PriceStatistics(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	filters, err := repository.ParseFilters(r.URL.Query(), "groupBy")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	groupBy := r.URL.Query().Get("groupBy")
+	if groupBy == "" {
+		groupBy = "category"
+	}
+	groupField, err := repository.LookupStatsGroupField(groupBy)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	defer repo.Close()
+
+	stats, err := repo.PriceStats(context.Background(), filters, groupField)
+	var queryErr *repository.QueryError
+	if errors.As(err, &queryErr) {
+		respondWithError(w, http.StatusBadRequest, queryErr.Error())
+		return
+	}
+	if err != nil {
+		log.Print("Failed to compute price statistics:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to compute price statistics")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, stats)
+	log.Print("Response Sent: PriceStatistics")
+}
+
+// VegetarianStatistics counts vegetarian and non-vegetarian items.
+// @Summary Vegetarian statistics
+// @Description Number of vegetarian and non-vegetarian items matching the filters, which are the same as in listGroceryItems.
+// @ID vegetarian-statistics
+// @Produce json
+// @Param category query string false "Filter by category"
+// @Success 200 {object} VegetarianStats "Item counts"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /stats/vegetarian [get]
+func VegetarianStatistics(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	filters, err := repository.ParseFilters(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	defer repo.Close()
+
+	// two count aggregations, no document is read
+	vegetarian, _ := repository.LookupField("vegetarian")
+	count := func(value bool) (int64, error) {
+		with := append(filters[:len(filters):len(filters)], repository.Filter{Field: vegetarian, Op: repository.OpEq, Values: []interface{}{value}})
+		return repo.Count(context.Background(), with)
+	}
+
+	var stats VegetarianStats
+	if stats.Vegetarian, err = count(true); err == nil {
+		stats.NonVegetarian, err = count(false)
+	}
+	if err != nil {
+		log.Print("Failed to count items:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to count items")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, stats)
+	log.Print("Response Sent: VegetarianStatistics")
+}
+
+// ExpiryStatistics counts items per expiry month.
+// @Summary Expiry statistics
+// @Description Number of items matching the filters expiring in each month, earliest first. Takes the same filters as listGroceryItems.
+// @ID expiry-statistics
+// @Produce json
+// @Param category query string false "Filter by category"
+// @Success 200 {array} repository.MonthCount "Item counts per month"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /stats/expiry [get]
+func ExpiryStatistics(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	filters, err := repository.ParseFilters(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	repo, err := utils.CreateGroceryRepository()
+	if err != nil {
+		log.Print("Failed to create grocery repository:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create grocery repository")
+		return
+	}
+	defer repo.Close()
+
+	months, err := repo.ExpiryMonths(context.Background(), filters)
+	if err != nil {
+		log.Print("Failed to count items per expiry month:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to count items per expiry month")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, months)
+	log.Print("Response Sent: ExpiryStatistics")
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/capstone/search"
+	"example.com/capstone/utils"
+)
+
+// defaultSuggestLimit is the number of suggestions returned when limit isn't set
+const defaultSuggestLimit = 8
+
+// SuggestItems completes a typed prefix.
+// @Summary Typeahead suggestions
+// @Description Completes a prefix with product names, brands and categories, e.g. "hal" gives Haldiram's (brand) and Haldiram's Bhujia (product).
+// @Description Any word of a name can be completed; completions of the start of a name rank first, then brands and categories before products, bigger ones first.
+// @ID suggest-items
+// @Produce json
+// @Param q query string true "Typed prefix"
+// @Param limit query integer false "Number of suggestions, 8 by default and at most 20" format(int32)
+// @Success 200 {array} search.Suggestion "Suggestions"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /suggest [get]
+func SuggestItems(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		respondWithError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	limit := defaultSuggestLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > search.MaxSuggestions {
+			respondWithError(w, http.StatusBadRequest, "limit must be a number between 1 and "+strconv.Itoa(search.MaxSuggestions))
+			return
+		}
+		limit = n
+	}
+
+	// the suggestions are built together with the search index
+	indexes, err := loadSearchIndex(context.Background())
+	if err != nil {
+		log.Print("Failed to build search index:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to build search index")
+		return
+	}
+
+	suggestions := indexes.suggester.Suggest(q, limit)
+	if suggestions == nil {
+		suggestions = []search.Suggestion{}
+	}
+	respondWithJSON(w, http.StatusOK, suggestions)
+}
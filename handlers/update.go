@@ -12,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
 	"example.com/capstone/utils"
 	"github.com/dgrijalva/jwt-go"
 	"google.golang.org/api/iterator"
@@ -192,24 +194,47 @@ func UpdateGroceryItem(w http.ResponseWriter, r *http.Request) {
 
 	docRef := client.Collection("groceryItems").Doc(doc.Ref.ID)
 
-	// Set the existing ID to updatedGroceryItem
-	updatedGroceryItem["ID"] = id
+	// keep the old price around for the price history
+	var existingItem models.GroceryItem
+	if err := doc.DataTo(&existingItem); err != nil {
+		log.Print("Failed to parse grocery item data from Firestore:", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to parse grocery item data from Firestore")
+		return
+	}
 
-	// Unmarshal the JSON data into the existing grocery item
-	if err := json.Unmarshal([]byte(jsonData), &updatedGroceryItem); err != nil {
+	// apply the update on top of the stored item, so the fields the request
+	// leaves out, e.g. the image hash or stock, are kept, and write it back
+	// under the same field names as every other item
+	updatedItem := existingItem
+	if err := json.Unmarshal([]byte(jsonData), &updatedItem); err != nil {
 		log.Println("Failed to unmarshal JSON:", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
+	updatedItem.ID = id
+	if imageURL, ok := updatedGroceryItem["Image"].(string); ok {
+		updatedItem.Image = imageURL
+	}
+	if thumbnailURL, ok := updatedGroceryItem["Thumbnail"].(string); ok {
+		updatedItem.Thumbnail = thumbnailURL
+	}
 
 	// Update existing fields with new values
-	_, err = docRef.Set(context.Background(), updatedGroceryItem)
+	_, err = docRef.Set(context.Background(), repository.StoredItem(updatedItem))
 	if err != nil {
 		log.Print("Failed to update grocery item in Firestore:", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to update grocery item in Firestore")
 		return
 	}
 
+	catalogChanged(&existingItem, &updatedItem)
+
+	if updatedItem.Price != existingItem.Price {
+		if err := recordPriceChange(context.Background(), client, id, existingItem.Price, updatedItem.Price); err != nil {
+			log.Print("Failed to record price change:", err)
+		}
+	}
+
 	// Generate audit record for update
 	auditRecord := GenerateAuditRecord("update", strconv.Itoa(id))
 
@@ -0,0 +1,279 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// ErrBlobNotFound is returned when a blob doesn't exist
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps the files imports read, in a bucket or a local directory.
+// Names are slash separated paths, e.g. imports/3kTq9/items.csv.
+type BlobStore interface {
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+	Attrs(ctx context.Context, name string) (BlobAttrs, error)
+	// List returns the blobs whose names start with prefix, sorted by name
+	List(ctx context.Context, prefix string) ([]BlobAttrs, error)
+	// Move renames a blob, replacing any blob named dst
+	Move(ctx context.Context, src, dst string) error
+	Close() error
+}
+
+// BlobAttrs describes a blob. Generation changes whenever the blob is
+// written, Hash identifies its content.
+type BlobAttrs struct {
+	Name       string
+	Size       int64
+	Generation int64
+	Hash       string
+	Updated    time.Time
+}
+
+// BucketBlobStore keeps blobs in a Cloud Storage bucket
+type BucketBlobStore struct {
+	client *storage.Client
+	bucket *storage.BucketHandle
+}
+
+// NewBucketBlobStore takes ownership of client, Close closes it
+func NewBucketBlobStore(client *storage.Client, bucket string) *BucketBlobStore {
+	return &BucketBlobStore{client: client, bucket: client.Bucket(bucket)}
+}
+
+func (b *BucketBlobStore) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	return b.bucket.Object(name).NewWriter(ctx), nil
+}
+
+func (b *BucketBlobStore) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	r, err := b.bucket.Object(name).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, ErrBlobNotFound
+	}
+	return r, err
+}
+
+func (b *BucketBlobStore) Delete(ctx context.Context, name string) error {
+	err := b.bucket.Object(name).Delete(ctx)
+	if err == storage.ErrObjectNotExist {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+func (b *BucketBlobStore) Attrs(ctx context.Context, name string) (BlobAttrs, error) {
+	attrs, err := b.bucket.Object(name).Attrs(ctx)
+	if err == storage.ErrObjectNotExist {
+		return BlobAttrs{}, ErrBlobNotFound
+	}
+	if err != nil {
+		return BlobAttrs{}, err
+	}
+	return bucketBlobAttrs(attrs), nil
+}
+
+func (b *BucketBlobStore) List(ctx context.Context, prefix string) ([]BlobAttrs, error) {
+	var blobs []BlobAttrs
+	iter := b.bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := iter.Next()
+		if err == iterator.Done {
+			return blobs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, bucketBlobAttrs(attrs))
+	}
+}
+
+// Move copies the object and deletes the source, buckets have no rename
+func (b *BucketBlobStore) Move(ctx context.Context, src, dst string) error {
+	source := b.bucket.Object(src)
+	if _, err := b.bucket.Object(dst).CopierFrom(source).Run(ctx); err != nil {
+		if err == storage.ErrObjectNotExist {
+			return ErrBlobNotFound
+		}
+		return err
+	}
+	return source.Delete(ctx)
+}
+
+func (b *BucketBlobStore) Close() error {
+	return b.client.Close()
+}
+
+// bucketBlobAttrs hashes with the MD5 of the object, composite objects only
+// have a CRC32C
+func bucketBlobAttrs(attrs *storage.ObjectAttrs) BlobAttrs {
+	hash := "crc32c:" + base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, attrs.CRC32C))
+	if len(attrs.MD5) > 0 {
+		hash = "md5:" + hex.EncodeToString(attrs.MD5)
+	}
+	return BlobAttrs{
+		Name:       attrs.Name,
+		Size:       attrs.Size,
+		Generation: attrs.Generation,
+		Hash:       hash,
+		Updated:    attrs.Updated,
+	}
+}
+
+// DirBlobStore keeps blobs as files under a directory, for local development
+type DirBlobStore struct {
+	root string
+}
+
+func NewDirBlobStore(root string) *DirBlobStore {
+	return &DirBlobStore{root: root}
+}
+
+func (d *DirBlobStore) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+func (d *DirBlobStore) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+func (d *DirBlobStore) Delete(ctx context.Context, name string) error {
+	path, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+// Attrs hashes the file, the generation is its modification time
+func (d *DirBlobStore) Attrs(ctx context.Context, name string) (BlobAttrs, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return BlobAttrs{}, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return BlobAttrs{}, ErrBlobNotFound
+	}
+	if err != nil {
+		return BlobAttrs{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return BlobAttrs{}, err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return BlobAttrs{}, err
+	}
+	return BlobAttrs{
+		Name:       name,
+		Size:       info.Size(),
+		Generation: info.ModTime().UnixNano(),
+		Hash:       "sha256:" + hex.EncodeToString(hash.Sum(nil)),
+		Updated:    info.ModTime(),
+	}, nil
+}
+
+// List leaves Hash empty, Attrs has it
+func (d *DirBlobStore) List(ctx context.Context, prefix string) ([]BlobAttrs, error) {
+	var blobs []BlobAttrs
+	err := filepath.WalkDir(d.root, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(d.root, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, BlobAttrs{
+			Name:       name,
+			Size:       info.Size(),
+			Generation: info.ModTime().UnixNano(),
+			Updated:    info.ModTime(),
+		})
+		return nil
+	})
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].Name < blobs[j].Name })
+	return blobs, err
+}
+
+func (d *DirBlobStore) Move(ctx context.Context, src, dst string) error {
+	srcPath, err := d.path(src)
+	if err != nil {
+		return err
+	}
+	dstPath, err := d.path(dst)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+		return err
+	}
+	err = os.Rename(srcPath, dstPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+// Close is a no-op, the files stay
+func (d *DirBlobStore) Close() error {
+	return nil
+}
+
+// path maps a blob name into the root, refusing names that would leave it
+func (d *DirBlobStore) path(name string) (string, error) {
+	clean := filepath.Clean("/" + name)
+	if strings.Contains(name, "..") || clean == "/" {
+		return "", errors.New("invalid blob name " + name)
+	}
+	return filepath.Join(d.root, filepath.FromSlash(clean)), nil
+}
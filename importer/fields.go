@@ -0,0 +1,206 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"example.com/capstone/models"
+)
+
+// itemField is a grocery item field an import column can fill
+type itemField struct {
+	name     string // API name, e.g. productName
+	required bool
+	set      func(item *models.GroceryItem, value interface{}) error
+}
+
+var itemFields = []itemField{
+	{"productName", true, func(i *models.GroceryItem, v interface{}) error { i.ProductName = toString(v); return nil }},
+	{"category", true, func(i *models.GroceryItem, v interface{}) error { i.Category = toString(v); return nil }},
+	{"price", true, func(i *models.GroceryItem, v interface{}) (err error) { i.Price, err = toFloat(v); return }},
+	{"weight", true, func(i *models.GroceryItem, v interface{}) (err error) { i.Weight, err = toFloat(v); return }},
+	{"weightUnit", true, func(i *models.GroceryItem, v interface{}) error {
+		i.WeightUnit = strings.ToLower(toString(v))
+		return nil
+	}},
+	{"vegetarian", false, func(i *models.GroceryItem, v interface{}) (err error) { i.Vegetarian, err = toBool(v); return }},
+	{"imageURL", false, func(i *models.GroceryItem, v interface{}) error { i.Image = toString(v); return nil }},
+	{"thumbnailURL", false, func(i *models.GroceryItem, v interface{}) error { i.Thumbnail = toString(v); return nil }},
+	{"manufacturer", true, func(i *models.GroceryItem, v interface{}) error { i.Manufacturer = toString(v); return nil }},
+	{"brand", true, func(i *models.GroceryItem, v interface{}) error { i.Brand = toString(v); return nil }},
+	{"itemPackageQuantity", true, func(i *models.GroceryItem, v interface{}) (err error) { i.ItemPackageQuantity, err = toInt(v); return }},
+	{"packageInformation", true, func(i *models.GroceryItem, v interface{}) error { i.PackageInformation = toString(v); return nil }},
+	{"mfgDate", true, func(i *models.GroceryItem, v interface{}) (err error) { i.MfgDate, err = toMonthYear(v); return }},
+	{"expDate", true, func(i *models.GroceryItem, v interface{}) (err error) { i.ExpDate, err = toMonthYear(v); return }},
+	{"countryOfOrigin", true, func(i *models.GroceryItem, v interface{}) error { i.CountryOfOrigin = toString(v); return nil }},
+	{"tags", false, func(i *models.GroceryItem, v interface{}) (err error) { i.Tags, err = toList(v); return }},
+	{"stock", false, func(i *models.GroceryItem, v interface{}) error {
+		stock, err := toInt(v)
+		i.Stock = &stock
+		return err
+	}},
+	{"barcode", false, func(i *models.GroceryItem, v interface{}) error { i.Barcode = toString(v); return nil }},
+}
+
+// columnAliases are other names a column of a field goes by, the Go field
+// names used by older files and the API names are matched without them
+var columnAliases = map[string]string{
+	"image":     "imageURL",
+	"thumbnail": "thumbnailURL",
+	"ean":       "barcode",
+	"gtin":      "barcode",
+	"upc":       "barcode",
+}
+
+// ignoredColumns are known but not imported, IDs are always allocated
+var ignoredColumns = map[string]bool{"id": true, "imagehash": true}
+
+// lookupColumn finds the field a column fills, ignoring case, spaces and
+// punctuation, so Product Name, product_name and ProductName all match
+func lookupColumn(column string) (itemField, bool) {
+	key := normalizeColumn(column)
+	for alias, name := range columnAliases {
+		if key == normalizeColumn(alias) {
+			key = normalizeColumn(name)
+		}
+	}
+	for _, f := range itemFields {
+		if normalizeColumn(f.name) == key {
+			return f, true
+		}
+	}
+	return itemField{}, false
+}
+
+func normalizeColumn(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isEmpty reports whether a value counts as missing
+func isEmpty(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(value) == ""
+	case []interface{}:
+		return len(value) == 0
+	}
+	return false
+}
+
+func toString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	var f float64
+	var err error
+	switch value := v.(type) {
+	case json.Number:
+		f, err = value.Float64()
+	case float64:
+		f = value
+	case string:
+		f, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+	default:
+		err = fmt.Errorf("unexpected %T", v)
+	}
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%v is not a number", v)
+	}
+	return f, nil
+}
+
+func toInt(v interface{}) (int, error) {
+	f, err := toFloat(v)
+	if err != nil || f != math.Trunc(f) {
+		return 0, fmt.Errorf("%v is not a whole number", v)
+	}
+	return int(f), nil
+}
+
+func toBool(v interface{}) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	switch strings.ToLower(toString(v)) {
+	case "true", "1", "yes", "y":
+		return true, nil
+	case "false", "0", "no", "n":
+		return false, nil
+	}
+	return false, fmt.Errorf("%v is not true or false", v)
+}
+
+// monthYearLayouts are the date formats accepted for mfgDate and expDate
+var monthYearLayouts = []string{"01/2006", "1/2006", "2006-01", "2006-01-02", "Jan 2006", "January 2006"}
+
+// toMonthYear accepts a date in one of monthYearLayouts, or an object with
+// Month and Year like the API returns
+func toMonthYear(v interface{}) (models.MonthYear, error) {
+	if object, ok := v.(map[string]interface{}); ok {
+		var my models.MonthYear
+		for key, value := range object {
+			n, err := toInt(value)
+			if err != nil {
+				return models.MonthYear{}, fmt.Errorf("%s %v", key, err)
+			}
+			switch strings.ToLower(key) {
+			case "month":
+				my.Month = time.Month(n)
+			case "year":
+				my.Year = n
+			}
+		}
+		if my.Month < time.January || my.Month > time.December {
+			return models.MonthYear{}, fmt.Errorf("month %d is out of range", my.Month)
+		}
+		return my, nil
+	}
+
+	text := toString(v)
+	for _, layout := range monthYearLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return models.MonthYear{Month: t.Month(), Year: t.Year()}, nil
+		}
+	}
+	return models.MonthYear{}, fmt.Errorf("%q is not a date, use MM/YYYY or YYYY-MM", text)
+}
+
+// toList accepts a JSON array or text separated by ; or |
+func toList(v interface{}) ([]string, error) {
+	var list []string
+	switch value := v.(type) {
+	case []interface{}:
+		for _, element := range value {
+			if s := toString(element); s != "" {
+				list = append(list, s)
+			}
+		}
+	default:
+		for _, part := range strings.FieldsFunc(toString(v), func(r rune) bool { return r == ';' || r == '|' }) {
+			if s := strings.TrimSpace(part); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list, nil
+}
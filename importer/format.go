@@ -0,0 +1,278 @@
+// Package importer loads grocery items from supplier files into the catalog.
+// Files are read row by row, so their size is not bound by memory; every row
+// is mapped to an item, validated, given an ID and written in batches, and the
+// outcome of each row ends up in a Report.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// File formats
+const (
+	FormatCSV   = "csv"
+	FormatJSON  = "json"  // a JSON array of objects
+	FormatJSONL = "jsonl" // one JSON object per line
+	FormatXLSX  = "xlsx"  // an Excel workbook, one sheet of which is read
+)
+
+// sniffSize is how much of a file DetectFormat looks at
+const sniffSize = 512
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// zipMagic starts every zip file, and so every workbook
+var zipMagic = []byte("PK\x03\x04")
+
+// ErrUnknownFormat is returned when the format of a file can't be told
+var ErrUnknownFormat = errors.New("unsupported file format, use CSV, a JSON array, JSON lines or an XLSX workbook")
+
+// DetectFormat tells the format of a file from its name, or else from its
+// first bytes: a workbook is a zip file, a JSON array starts with [, JSON
+// lines with {, anything else with a comma separated header is CSV.
+func DetectFormat(filename string, head []byte) (string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	}
+	if bytes.HasPrefix(head, zipMagic) {
+		return FormatXLSX, nil
+	}
+
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, utf8BOM), " \t\r\n")
+	switch {
+	case len(head) == 0:
+		return "", ErrUnknownFormat
+	case head[0] == '[':
+		return FormatJSON, nil
+	case head[0] == '{':
+		return FormatJSONL, nil
+	}
+
+	firstLine, _, _ := bytes.Cut(head, []byte("\n"))
+	if bytes.Contains(firstLine, []byte(",")) || bytes.HasPrefix(firstLine, []byte("sep=")) {
+		return FormatCSV, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Sniff detects the format of r without consuming it, the returned reader
+// still yields the whole file
+func Sniff(filename string, r io.Reader) (string, io.Reader, error) {
+	buffered := bufio.NewReaderSize(r, sniffSize)
+	head, err := buffered.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", nil, err
+	}
+	format, err := DetectFormat(filename, head)
+	return format, buffered, err
+}
+
+// record is one row of a file: values keyed by column name, strings for CSV
+// and workbooks and decoded JSON values otherwise
+type record struct {
+	row    int // 1-based, not counting the header
+	values map[string]interface{}
+}
+
+// recordReader reads the rows of a file one at a time
+type recordReader interface {
+	// columns lists the column names known up front, the CSV or sheet header
+	columns() []string
+	// next returns io.EOF after the last row. A *rowError leaves the reader
+	// usable, any other error ends the file.
+	next() (record, error)
+}
+
+// rowError is a row that can't be decoded while the rows after it can
+type rowError struct {
+	row int
+	err error
+}
+
+func (e *rowError) Error() string {
+	return e.err.Error()
+}
+
+// newRecordReader reads r in format. The sheet of a workbook and its header
+// row are picked with opts; a reader that is an io.Closer has to be closed.
+func newRecordReader(format string, r io.Reader, opts Options) (recordReader, error) {
+	switch format {
+	case FormatXLSX:
+		return newXLSXReader(r, opts)
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSON:
+		return newJSONArrayReader(r)
+	case FormatJSONL:
+		return &jsonLinesReader{scanner: newLineScanner(r)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+	row    int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	buffered := bufio.NewReader(r)
+	if head, err := buffered.Peek(len(utf8BOM)); err == nil && bytes.Equal(head, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+
+	// a spreadsheet hint line such as sep=; sets the delimiter
+	reader := csv.NewReader(buffered)
+	if head, err := buffered.Peek(5); err == nil && bytes.HasPrefix(head, []byte("sep=")) {
+		line, _ := buffered.ReadString('\n')
+		if sep := strings.TrimSpace(strings.TrimPrefix(line, "sep=")); len(sep) == 1 {
+			reader.Comma = rune(sep[0])
+		}
+	}
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read the CSV header: %v", err)
+	}
+
+	c := &csvReader{reader: reader}
+	for _, name := range header {
+		c.header = append(c.header, strings.TrimSpace(name))
+	}
+	return c, nil
+}
+
+func (c *csvReader) columns() []string {
+	return c.header
+}
+
+func (c *csvReader) next() (record, error) {
+	fields, err := c.reader.Read()
+	if err == io.EOF {
+		return record{}, io.EOF
+	}
+	c.row++
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return record{}, &rowError{row: c.row, err: fmt.Errorf("malformed CSV on line %d: %v", parseErr.Line, parseErr.Err)}
+		}
+		return record{}, err
+	}
+	if len(fields) > len(c.header) {
+		return record{}, &rowError{row: c.row, err: fmt.Errorf("row has %d values but the header has %d columns", len(fields), len(c.header))}
+	}
+
+	rec := record{row: c.row, values: make(map[string]interface{}, len(fields))}
+	for i, value := range fields {
+		rec.values[c.header[i]] = value
+	}
+	return rec, nil
+}
+
+type jsonArrayReader struct {
+	decoder *json.Decoder
+	row     int
+}
+
+func newJSONArrayReader(r io.Reader) (*jsonArrayReader, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("can't read the JSON array: %v", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("the JSON file has to hold an array of items")
+	}
+	return &jsonArrayReader{decoder: decoder}, nil
+}
+
+func (j *jsonArrayReader) columns() []string {
+	return nil
+}
+
+func (j *jsonArrayReader) next() (record, error) {
+	if !j.decoder.More() {
+		return record{}, io.EOF
+	}
+	j.row++
+
+	// an element that isn't an object is consumed whole, so the array can go on
+	var raw json.RawMessage
+	if err := j.decoder.Decode(&raw); err != nil {
+		return record{}, fmt.Errorf("malformed JSON in item %d: %v", j.row, err)
+	}
+	values, err := decodeObject(raw)
+	if err != nil {
+		return record{}, &rowError{row: j.row, err: err}
+	}
+	return record{row: j.row, values: values}, nil
+}
+
+type jsonLinesReader struct {
+	scanner *bufio.Scanner
+	row     int
+}
+
+// maxLineSize bounds a JSON line, far above any real item
+const maxLineSize = 1 << 20
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return scanner
+}
+
+func (j *jsonLinesReader) columns() []string {
+	return nil
+}
+
+func (j *jsonLinesReader) next() (record, error) {
+	for j.scanner.Scan() {
+		line := bytes.TrimSpace(bytes.TrimPrefix(j.scanner.Bytes(), utf8BOM))
+		if len(line) == 0 {
+			continue
+		}
+		j.row++
+		values, err := decodeObject(line)
+		if err != nil {
+			return record{}, &rowError{row: j.row, err: err}
+		}
+		return record{row: j.row, values: values}, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return record{}, err
+	}
+	return record{}, io.EOF
+}
+
+func decodeObject(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil || values == nil {
+		return nil, errors.New("not a JSON object")
+	}
+	return values, nil
+}
@@ -0,0 +1,440 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+)
+
+// Row outcomes. A created row was inserted as a new item, an updated or
+// unchanged row matched an item of the catalog.
+const (
+	RowCreated   = "created"
+	RowUpdated   = "updated"
+	RowUnchanged = "unchanged"
+	RowSkipped   = "skipped"
+	RowFailed    = "failed"
+)
+
+// batchSize is how many valid rows are written together, enough for the
+// repository to commit several of its batches in parallel
+const batchSize = 2000
+
+// RowResult is what happened to one row of the file
+type RowResult struct {
+	Row     int      `json:"row"` // 1-based, not counting the CSV header
+	Status  string   `json:"status"`
+	ItemID  int      `json:"itemID,omitempty"`
+	Reasons []string `json:"reasons,omitempty"`
+	Changes []Change `json:"changes,omitempty"` // what an updated row changes in the item
+}
+
+// Change is a field of an item changed by an import
+type Change struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Report sums up an import
+type Report struct {
+	Format          string      `json:"format"`
+	DryRun          bool        `json:"dryRun,omitempty"` // nothing was written, the outcomes are what the import would do
+	Rows            int         `json:"rows"`
+	Created         int         `json:"created"`
+	Updated         int         `json:"updated"`
+	Unchanged       int         `json:"unchanged"`
+	Skipped         int         `json:"skipped"`
+	Failed          int         `json:"failed"`
+	UnmappedColumns []string    `json:"unmappedColumns,omitempty"` // columns that don't fill any field
+	MissingFields   []string    `json:"missingFields,omitempty"`   // required fields no column or default fills, CSV only
+	Error           string      `json:"error,omitempty"`           // why the file wasn't read to the end
+	Results         []RowResult `json:"results"`
+}
+
+// Options describe the file being imported and how rows matching items of
+// the catalog are handled
+type Options struct {
+	Filename string   // used to detect the format
+	Format   string   // csv, json, jsonl or xlsx, detected when empty
+	Sheet    string   // name or 1-based position of the sheet of a workbook, the first when empty
+	Key      []string // fields identifying a product, DefaultKey when empty
+	Conflict string   // one of the Conflict strategies, ConflictSkip when empty
+	DryRun   bool     // parse, validate and match the rows but write nothing
+	Profile  *Profile // how the columns are read, field names when nil
+}
+
+// Importer writes the rows of files to a catalog
+type Importer struct {
+	Repo repository.GroceryItemRepository
+
+	// OnCreated, when set, is called for every item written
+	OnCreated func(item models.GroceryItem)
+	// OnUpdated, when set, is called for every item changed by a row
+	OnUpdated func(prev, item models.GroceryItem)
+	// OnProgress, when set, is called after every batch written
+	OnProgress func(p Progress)
+}
+
+// pendingRow is a row waiting for its batch to be matched and written
+type pendingRow struct {
+	result int // index in Report.Results
+	row    *mappedRow
+	key    string
+	keyed  bool // every key field has a value, so the row can match items
+}
+
+// mappedRow is a row read into an item
+type mappedRow struct {
+	item     models.GroceryItem
+	present  map[string]bool // fields the row has a column for
+	filled   map[string]bool // fields the row has a value for
+	invalid  map[string]bool // fields whose value couldn't be read
+	problems []string
+}
+
+// validate returns what is wrong with item, the row merged into an item of
+// the catalog or the row on its own. A value that couldn't be read is
+// reported once, not again as missing.
+func (row *mappedRow) validate(item models.GroceryItem) []string {
+	problems := append([]string(nil), row.problems...)
+	for _, problem := range validateItem(item) {
+		name, _, _ := strings.Cut(problem, " ")
+		if !row.invalid[name] {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// Run imports the file read from r. It only fails when the file can't be
+// imported at all, e.g. in an unknown format; problems with rows, or with the
+// file after some rows were imported, are in the report.
+func (im *Importer) Run(ctx context.Context, r io.Reader, opts Options) (*Report, error) {
+	format := opts.Format
+	if format == "" {
+		var err error
+		format, r, err = Sniff(opts.Filename, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	fields, _ := keyFields(opts.Key)
+
+	reader, err := newRecordReader(format, r, opts)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	report := &Report{Format: format, DryRun: opts.DryRun, Results: []RowResult{}}
+	unmapped := map[string]bool{}
+	for _, column := range reader.columns() {
+		if _, ok := opts.Profile.lookup(column); !ok && !ignoredColumns[normalizeColumn(column)] {
+			unmapped[column] = true
+		}
+	}
+
+	// without a column for a required field no row can be created, only rows
+	// merged into items of the catalog can be complete
+	if columns := reader.columns(); len(columns) > 0 {
+		report.MissingFields = opts.Profile.missingFields(columns)
+		if len(report.MissingFields) > 0 && opts.Conflict != ConflictMerge && opts.Conflict != ConflictOverwrite {
+			report.Error = "no column for the required fields " + strings.Join(report.MissingFields, ", ")
+			report.UnmappedColumns = sortedKeys(unmapped)
+			return report, nil
+		}
+	}
+
+	// rows describing the same product as an earlier row are skipped
+	seen := map[string]int{}
+	var pending []pendingRow
+
+	for {
+		if err := ctx.Err(); err != nil {
+			report.Error = "import cancelled"
+			break
+		}
+
+		rec, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if rowErr, ok := err.(*rowError); ok {
+			report.Results = append(report.Results, RowResult{Row: rowErr.row, Status: RowFailed, Reasons: []string{rowErr.Error()}})
+			continue
+		}
+		if err != nil {
+			report.Error = err.Error()
+			break
+		}
+
+		result := RowResult{Row: rec.row}
+		row := mapRecord(rec, opts.Profile, unmapped)
+		switch {
+		case row == nil:
+			result.Status = RowSkipped
+			result.Reasons = []string{"empty row"}
+		case len(row.problems) > 0:
+			result.Status = RowFailed
+			result.Reasons = row.validate(row.item)
+		default:
+			p := pendingRow{result: len(report.Results), row: row, key: itemKey(row.item, fields), keyed: true}
+			for _, field := range fields {
+				p.keyed = p.keyed && row.filled[field.Name]
+			}
+			if p.keyed {
+				if first, ok := seen[p.key]; ok {
+					result.Status = RowSkipped
+					result.Reasons = []string{fmt.Sprintf("same product as row %d", first)}
+					break
+				}
+				seen[p.key] = rec.row
+			}
+			pending = append(pending, p)
+		}
+		report.Results = append(report.Results, result)
+
+		if len(pending) == batchSize {
+			im.write(ctx, report, pending, fields, opts)
+			pending = pending[:0]
+		}
+	}
+
+	if ctx.Err() != nil {
+		// rows read before the cancellation are reported, not written
+		for _, p := range pending {
+			report.Results[p.result].Status = RowSkipped
+			report.Results[p.result].Reasons = []string{"import cancelled"}
+		}
+	} else {
+		im.write(ctx, report, pending, fields, opts)
+	}
+
+	report.UnmappedColumns = sortedKeys(unmapped)
+
+	p := report.progress()
+	report.Rows, report.Created, report.Updated, report.Unchanged = p.Rows, p.Created, p.Updated, p.Unchanged
+	report.Skipped, report.Failed = p.Skipped, p.Failed
+	return report, nil
+}
+
+// progress counts the rows of the report by outcome. Rows waiting to be
+// written count towards Rows only.
+func (r *Report) progress() Progress {
+	p := Progress{Rows: len(r.Results)}
+	for _, result := range r.Results {
+		switch result.Status {
+		case RowCreated:
+			p.Created++
+		case RowUpdated:
+			p.Updated++
+		case RowUnchanged:
+			p.Unchanged++
+		case RowSkipped:
+			p.Skipped++
+		case RowFailed:
+			p.Failed++
+		}
+	}
+	return p
+}
+
+// write matches the pending rows with the catalog. Rows matching nothing are
+// given IDs and created, the others are handled following the conflict
+// strategy. A dry run only reports what would be done.
+func (im *Importer) write(ctx context.Context, report *Report, pending []pendingRow, fields []repository.Field, opts Options) {
+	if len(pending) == 0 {
+		return
+	}
+
+	existing, err := im.findExisting(ctx, fields, pending)
+	if err != nil {
+		for _, p := range pending {
+			report.set(p, RowFailed, 0, "can't look up existing items: "+err.Error())
+		}
+		return
+	}
+
+	var inserts []pendingRow
+	var updates []pendingRow
+	var prev, updated []models.GroceryItem
+	for _, p := range pending {
+		matches := existing[p.key]
+		if !p.keyed || len(matches) == 0 {
+			if problems := p.row.validate(p.row.item); len(problems) > 0 {
+				report.set(p, RowFailed, 0, problems...)
+				continue
+			}
+			inserts = append(inserts, p)
+			continue
+		}
+
+		match := matches[0]
+		switch conflict := opts.Conflict; {
+		case conflict == ConflictSkip || conflict == "":
+			report.set(p, RowSkipped, match.ID, fmt.Sprintf("matches item %d", match.ID))
+			continue
+		case conflict == ConflictFail:
+			report.set(p, RowFailed, 0, fmt.Sprintf("matches item %d", match.ID))
+			continue
+		case len(matches) > 1:
+			report.set(p, RowFailed, 0, "matches several items: "+itemIDs(matches))
+			continue
+		}
+
+		item := applyRow(match, p.row, opts.Conflict)
+		item.ID = match.ID
+		if problems := p.row.validate(item); len(problems) > 0 {
+			report.set(p, RowFailed, 0, problems...)
+			continue
+		}
+		changes := itemChanges(match, item)
+		if len(changes) == 0 {
+			report.set(p, RowUnchanged, match.ID)
+			continue
+		}
+		report.Results[p.result].Changes = changes
+		updates = append(updates, p)
+		prev = append(prev, match)
+		updated = append(updated, item)
+	}
+
+	if opts.DryRun {
+		for i, p := range updates {
+			report.set(p, RowUpdated, updated[i].ID)
+		}
+		for _, p := range inserts {
+			report.set(p, RowCreated, 0)
+		}
+	} else {
+		im.store(ctx, report, inserts, updates, prev, updated)
+	}
+
+	if im.OnProgress != nil {
+		im.OnProgress(report.progress())
+	}
+}
+
+// set records the outcome of a pending row
+func (r *Report) set(p pendingRow, status string, itemID int, reasons ...string) {
+	r.Results[p.result].Status = status
+	r.Results[p.result].ItemID = itemID
+	r.Results[p.result].Reasons = reasons
+}
+
+// store writes the updated items, then the new ones
+func (im *Importer) store(ctx context.Context, report *Report, inserts, updates []pendingRow, prev, updated []models.GroceryItem) {
+	if len(updates) > 0 {
+		errs := im.Repo.UpdateItems(ctx, updated)
+		for i, p := range updates {
+			if errs[i] != nil {
+				report.Results[p.result].Changes = nil
+				report.set(p, RowFailed, 0, "can't update the item: "+errs[i].Error())
+				continue
+			}
+			report.set(p, RowUpdated, updated[i].ID)
+			if im.OnUpdated != nil {
+				im.OnUpdated(prev[i], updated[i])
+			}
+		}
+	}
+
+	if len(inserts) > 0 {
+		im.create(ctx, report, inserts)
+	}
+}
+
+// create gives the rows their IDs and stores them
+func (im *Importer) create(ctx context.Context, report *Report, rows []pendingRow) {
+	first, err := im.Repo.AllocateIDs(ctx, len(rows))
+	if err != nil {
+		for _, p := range rows {
+			report.set(p, RowFailed, 0, "can't allocate an item ID: "+err.Error())
+		}
+		return
+	}
+
+	items := make([]models.GroceryItem, len(rows))
+	for i, p := range rows {
+		p.row.item.ID = first + i
+		items[i] = p.row.item
+	}
+
+	errs := im.Repo.CreateItems(ctx, items)
+	for i, p := range rows {
+		if errs[i] != nil {
+			report.set(p, RowFailed, 0, "can't store the item: "+errs[i].Error())
+			continue
+		}
+		report.set(p, RowCreated, items[i].ID)
+		if im.OnCreated != nil {
+			im.OnCreated(items[i])
+		}
+	}
+}
+
+// mapRecord reads a row into an item, following the profile when not nil. It
+// returns nil for a row without any value. Columns that don't fill any field
+// are added to unmapped.
+func mapRecord(rec record, profile *Profile, unmapped map[string]bool) *mappedRow {
+	row := &mappedRow{present: map[string]bool{}, filled: map[string]bool{}, invalid: map[string]bool{}}
+	empty := true
+
+	// sorted so problems come in the same order for every row
+	columns := make([]string, 0, len(rec.values))
+	for column := range rec.values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		value := rec.values[column]
+		field, ok := profile.lookup(column)
+		if ok {
+			row.present[field.name] = true
+		}
+		if isEmpty(value) {
+			continue
+		}
+		empty = false
+
+		if !ok {
+			if !ignoredColumns[normalizeColumn(column)] {
+				unmapped[column] = true
+			}
+			continue
+		}
+		row.filled[field.name] = true
+		if err := profile.set(&row.item, field, value, row.filled); err != nil {
+			row.problems = append(row.problems, fmt.Sprintf("%s: %v", field.name, err))
+			row.invalid[field.name] = true
+		}
+	}
+
+	if empty {
+		return nil
+	}
+	profile.applyDefaults(row)
+	return row
+}
+
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
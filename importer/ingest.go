@@ -0,0 +1,335 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Folders of the data files ingested from a blob store. Files dropped in
+// IngestPrefix are imported, then moved with their report next to them.
+const (
+	IngestPrefix    = "dataFiles/"
+	ProcessedPrefix = "processed/"
+	FailedPrefix    = "failed/"
+
+	reportSuffix = ".report.json"
+
+	// ingestSettleTime is how long the watcher leaves a file alone after it
+	// was written, so it doesn't read a file being copied in
+	ingestSettleTime = 2 * time.Second
+)
+
+// Ingestion outcomes
+const (
+	IngestProcessed = "processed"
+	IngestFailed    = "failed"
+	IngestDuplicate = "duplicate"
+)
+
+// IngestReport is the sidecar written next to an ingested file
+type IngestReport struct {
+	File        string    `json:"file"`
+	Generation  int64     `json:"generation"`
+	Hash        string    `json:"hash"`
+	Status      string    `json:"status"`
+	DuplicateOf string    `json:"duplicateOf,omitempty"` // file with the same content ingested before
+	Error       string    `json:"error,omitempty"`
+	Report      *Report   `json:"report,omitempty"`
+	IngestedAt  time.Time `json:"ingestedAt"`
+}
+
+// IngestRecord is what the ledger remembers of an ingested file
+type IngestRecord struct {
+	File       string
+	Generation int64
+	Hash       string
+	Status     string // empty while the file is being ingested
+	MovedTo    string
+	IngestedAt time.Time
+}
+
+// IngestLedger remembers the files ingested so far, by generation and by
+// content hash, so events delivered twice and files uploaded twice are
+// imported once
+type IngestLedger interface {
+	// Claim records key unless it exists, in which case the existing record
+	// is returned with false
+	Claim(ctx context.Context, key string, record IngestRecord) (IngestRecord, bool, error)
+	Finish(ctx context.Context, key string, record IngestRecord) error
+	// Release forgets a claim, so the file is ingested again on a retry
+	Release(ctx context.Context, key string) error
+	Close() error
+}
+
+// Ingester imports the data files dropped in a blob store
+type Ingester struct {
+	Blobs       BlobStore
+	Ledger      IngestLedger
+	NewImporter ImporterFactory
+}
+
+// Ingest imports the file name, written with the given generation (zero for
+// the current one), and moves it to processed/ or failed/ with its report.
+// Files outside dataFiles/, gone already or seen before are left alone. An
+// error means the file stays and the ingestion should be retried.
+func (in *Ingester) Ingest(ctx context.Context, name string, generation int64) (*IngestReport, error) {
+	if !strings.HasPrefix(name, IngestPrefix) || strings.HasSuffix(name, "/") || strings.HasSuffix(name, reportSuffix) {
+		return nil, nil
+	}
+
+	attrs, err := in.Blobs.Attrs(ctx, name)
+	if err == ErrBlobNotFound {
+		// moved by an earlier delivery of the event
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if generation != 0 && generation != attrs.Generation {
+		// overwritten since, the event of the newer generation handles it
+		return nil, nil
+	}
+
+	record := IngestRecord{File: name, Generation: attrs.Generation, Hash: attrs.Hash}
+	generationKey := "generation:" + name + "#" + strconv.FormatInt(attrs.Generation, 10)
+	if _, claimed, err := in.Ledger.Claim(ctx, generationKey, record); err != nil || !claimed {
+		return nil, err
+	}
+
+	ingest := IngestReport{File: name, Generation: attrs.Generation, Hash: attrs.Hash, IngestedAt: time.Now().UTC()}
+	hashKey := "hash:" + attrs.Hash
+	previous, claimed, err := in.Ledger.Claim(ctx, hashKey, record)
+	if err != nil {
+		in.Ledger.Release(ctx, generationKey)
+		return nil, err
+	}
+
+	var runErr error
+	if !claimed {
+		ingest.Status = IngestDuplicate
+		ingest.DuplicateOf = previous.File
+	} else {
+		ingest.Report, runErr = in.run(ctx, name)
+		switch {
+		case ctx.Err() != nil:
+			in.Ledger.Release(ctx, hashKey)
+			in.Ledger.Release(ctx, generationKey)
+			return nil, ctx.Err()
+		case runErr != nil:
+			ingest.Status = IngestFailed
+			ingest.Error = runErr.Error()
+		case ingest.Report.Error != "":
+			ingest.Status = IngestFailed
+			ingest.Error = ingest.Report.Error
+		case ingest.Report.Created == 0 && ingest.Report.Failed > 0:
+			ingest.Status = IngestFailed
+			ingest.Error = "no row could be imported"
+		default:
+			ingest.Status = IngestProcessed
+		}
+	}
+
+	record.Status = ingest.Status
+	record.IngestedAt = ingest.IngestedAt
+	// the items are in the catalog by now, a file that can't be moved stays
+	// where it is rather than being imported again
+	if err := in.moveAway(ctx, name, &ingest); err != nil {
+		log.Printf("Failed to move ingested file %s: %v", name, err)
+	} else {
+		record.MovedTo = movedName(name, ingest.Status)
+	}
+	for _, key := range []string{generationKey, hashKey} {
+		if key == hashKey && !claimed {
+			continue
+		}
+		if err := in.Ledger.Finish(ctx, key, record); err != nil {
+			log.Printf("Failed to record the ingestion of %s: %v", name, err)
+		}
+	}
+	return &ingest, nil
+}
+
+// Watch ingests the files in dataFiles/ every interval until ctx is done, the
+// stand in for bucket notifications when the blobs are in a local directory
+func (in *Ingester) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		blobs, err := in.Blobs.List(ctx, IngestPrefix)
+		if err != nil {
+			log.Print("Failed to list data files:", err)
+		}
+		for _, blob := range blobs {
+			if time.Since(blob.Updated) < ingestSettleTime {
+				continue
+			}
+			ingest, err := in.Ingest(ctx, blob.Name, blob.Generation)
+			if err != nil {
+				log.Printf("Failed to ingest %s: %v", blob.Name, err)
+			} else if ingest != nil {
+				log.Printf("Ingested %s: %s", blob.Name, ingest.Status)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (in *Ingester) run(ctx context.Context, name string) (*Report, error) {
+	file, err := in.Blobs.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	im, release, err := in.NewImporter()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return im.Run(ctx, file, Options{Filename: path.Base(name)})
+}
+
+// moveAway writes the report and moves the file next to it
+func (in *Ingester) moveAway(ctx context.Context, name string, ingest *IngestReport) error {
+	dst := movedName(name, ingest.Status)
+
+	data, err := json.MarshalIndent(ingest, "", "  ")
+	if err != nil {
+		return err
+	}
+	writer, err := in.Blobs.Create(ctx, dst+reportSuffix)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return in.Blobs.Move(ctx, name, dst)
+}
+
+// movedName is where an ingested file goes, keeping its path under dataFiles/
+func movedName(name, status string) string {
+	prefix := ProcessedPrefix
+	if status == IngestFailed {
+		prefix = FailedPrefix
+	}
+	return prefix + strings.TrimPrefix(name, IngestPrefix)
+}
+
+// MemoryIngestLedger keeps the ledger in memory, for local development
+type MemoryIngestLedger struct {
+	mu      sync.Mutex
+	records map[string]IngestRecord
+}
+
+func NewMemoryIngestLedger() *MemoryIngestLedger {
+	return &MemoryIngestLedger{records: map[string]IngestRecord{}}
+}
+
+func (m *MemoryIngestLedger) Claim(ctx context.Context, key string, record IngestRecord) (IngestRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.records[key]; ok {
+		return existing, false, nil
+	}
+	m.records[key] = record
+	return record, true, nil
+}
+
+func (m *MemoryIngestLedger) Finish(ctx context.Context, key string, record IngestRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[key] = record
+	return nil
+}
+
+func (m *MemoryIngestLedger) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+	return nil
+}
+
+// Close is a no-op, the records live as long as the process
+func (m *MemoryIngestLedger) Close() error {
+	return nil
+}
+
+const ingestedFilesCollection = "ingestedFiles"
+
+// FirestoreIngestLedger keeps the ledger in the ingestedFiles collection.
+// Keys contain slashes, documents are named after their hash.
+type FirestoreIngestLedger struct {
+	client *firestore.Client
+}
+
+// NewFirestoreIngestLedger takes ownership of client, Close closes it
+func NewFirestoreIngestLedger(client *firestore.Client) *FirestoreIngestLedger {
+	return &FirestoreIngestLedger{client: client}
+}
+
+func (f *FirestoreIngestLedger) Claim(ctx context.Context, key string, record IngestRecord) (IngestRecord, bool, error) {
+	ref := f.doc(key)
+	_, err := ref.Create(ctx, record)
+	if err == nil {
+		return record, true, nil
+	}
+	if status.Code(err) != codes.AlreadyExists {
+		return IngestRecord{}, false, err
+	}
+
+	doc, err := ref.Get(ctx)
+	if err != nil {
+		return IngestRecord{}, false, err
+	}
+	var existing IngestRecord
+	if err := doc.DataTo(&existing); err != nil {
+		return IngestRecord{}, false, err
+	}
+	return existing, false, nil
+}
+
+func (f *FirestoreIngestLedger) Finish(ctx context.Context, key string, record IngestRecord) error {
+	_, err := f.doc(key).Set(ctx, record)
+	return err
+}
+
+func (f *FirestoreIngestLedger) Release(ctx context.Context, key string) error {
+	_, err := f.doc(key).Delete(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	return err
+}
+
+func (f *FirestoreIngestLedger) Close() error {
+	return f.client.Close()
+}
+
+func (f *FirestoreIngestLedger) doc(key string) *firestore.DocumentRef {
+	sum := sha256.Sum256([]byte(key))
+	return f.client.Collection(ingestedFilesCollection).Doc(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,294 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Job states. queued and running jobs are active, a previewed dry run waits
+// to be approved or cancelled, the others are final.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobPreviewed = "previewed"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// ErrJobNotFound is returned for an unknown job ID
+var ErrJobNotFound = errors.New("import job not found")
+
+// Job is an import running in the background
+type Job struct {
+	ID              string    `json:"id" firestore:"-"`
+	Status          string    `json:"status"`
+	Filename        string    `json:"filename"`
+	Format          string    `json:"format,omitempty"` // detected when the job runs if empty
+	Sheet           string    `json:"sheet,omitempty"`
+	Source          string    `json:"source"` // blob holding the file
+	Key             []string  `json:"key,omitempty"`
+	Conflict        string    `json:"onConflict,omitempty"`
+	DryRun          bool      `json:"dryRun"`            // cleared when the preview is approved
+	Profile         *Profile  `json:"profile,omitempty"` // as it was when the job was created
+	Progress        Progress  `json:"progress"`
+	Error           string    `json:"error,omitempty"`
+	CancelRequested bool      `json:"cancelRequested"`
+	CreatedBy       string    `json:"createdBy"`
+	CreatedAt       time.Time `json:"createdAt"`
+	ApprovedBy      string    `json:"approvedBy,omitempty"`
+	ApprovedAt      time.Time `json:"approvedAt"`
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+}
+
+// Progress counts the rows handled so far
+type Progress struct {
+	Rows      int `json:"rows"`
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"`
+	Failed    int `json:"failed"`
+}
+
+// Done reports whether the job reached a final state
+func (j Job) Done() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// JobStore keeps import jobs and their reports
+type JobStore interface {
+	CreateJob(ctx context.Context, job Job) (string, error)
+	Job(ctx context.Context, id string) (Job, error)
+	// UpdateJob applies change to the stored job atomically. An error returned
+	// by change aborts the update and is returned.
+	UpdateJob(ctx context.Context, id string, change func(job *Job) error) (Job, error)
+	SaveReport(ctx context.Context, id string, report *Report) error
+	Report(ctx context.Context, id string) (*Report, error)
+	Close() error
+}
+
+// MemoryJobStore keeps jobs in memory, for local development
+type MemoryJobStore struct {
+	mu      sync.Mutex
+	jobs    map[string]Job
+	reports map[string]*Report
+	nextID  int
+}
+
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: map[string]Job{}, reports: map[string]*Report{}}
+}
+
+func (m *MemoryJobStore) CreateJob(ctx context.Context, job Job) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	job.ID = fmt.Sprintf("job-%d", m.nextID)
+	m.jobs[job.ID] = job
+	return job.ID, nil
+}
+
+func (m *MemoryJobStore) Job(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+func (m *MemoryJobStore) UpdateJob(ctx context.Context, id string, change func(job *Job) error) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	if err := change(&job); err != nil {
+		return Job{}, err
+	}
+	m.jobs[id] = job
+	return job, nil
+}
+
+func (m *MemoryJobStore) SaveReport(ctx context.Context, id string, report *Report) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reports[id] = report
+	return nil
+}
+
+func (m *MemoryJobStore) Report(ctx context.Context, id string) (*Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report, ok := m.reports[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return report, nil
+}
+
+// Close is a no-op, the jobs live as long as the process
+func (m *MemoryJobStore) Close() error {
+	return nil
+}
+
+const (
+	importJobsCollection = "importJobs"
+
+	// reportChunkSize is how many row results go in one document, which keeps
+	// the documents of large reports under the Firestore size limit
+	reportChunkSize = 1000
+)
+
+// reportChunk is a slice of the row results of a report, the summary is
+// stored in the report document
+type reportChunk struct {
+	Index   int
+	Results []RowResult
+}
+
+// FirestoreJobStore keeps jobs in the importJobs collection. A report is
+// stored in the reports subcollection of its job, split in chunks.
+type FirestoreJobStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreJobStore takes ownership of client, Close closes it
+func NewFirestoreJobStore(client *firestore.Client) *FirestoreJobStore {
+	return &FirestoreJobStore{client: client}
+}
+
+func (f *FirestoreJobStore) CreateJob(ctx context.Context, job Job) (string, error) {
+	ref, _, err := f.client.Collection(importJobsCollection).Add(ctx, job)
+	if err != nil {
+		return "", err
+	}
+	return ref.ID, nil
+}
+
+func (f *FirestoreJobStore) Job(ctx context.Context, id string) (Job, error) {
+	doc, err := f.client.Collection(importJobsCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return Job{}, ErrJobNotFound
+	}
+	if err != nil {
+		return Job{}, err
+	}
+
+	var job Job
+	if err := doc.DataTo(&job); err != nil {
+		return Job{}, err
+	}
+	job.ID = doc.Ref.ID
+	return job, nil
+}
+
+func (f *FirestoreJobStore) UpdateJob(ctx context.Context, id string, change func(job *Job) error) (Job, error) {
+	ref := f.client.Collection(importJobsCollection).Doc(id)
+
+	var job Job
+	err := f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		job = Job{}
+		if err := doc.DataTo(&job); err != nil {
+			return err
+		}
+		job.ID = id
+		if err := change(&job); err != nil {
+			return err
+		}
+		return tx.Set(ref, job)
+	})
+	return job, err
+}
+
+func (f *FirestoreJobStore) SaveReport(ctx context.Context, id string, report *Report) error {
+	reports := f.client.Collection(importJobsCollection).Doc(id).Collection("reports")
+
+	summary := *report
+	summary.Results = nil
+	if _, err := reports.Doc("summary").Set(ctx, summary); err != nil {
+		return err
+	}
+
+	chunks := 0
+	for start := 0; start < len(report.Results); chunks, start = chunks+1, start+reportChunkSize {
+		chunk := reportChunk{Index: chunks, Results: report.Results[start:min(start+reportChunkSize, len(report.Results))]}
+		if _, err := reports.Doc(fmt.Sprintf("chunk-%05d", chunks)).Set(ctx, chunk); err != nil {
+			return err
+		}
+	}
+
+	// chunks of a longer report saved before, i.e. the preview of the job
+	stale, err := reports.Where("Index", ">=", chunks).Documents(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+	for _, doc := range stale {
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FirestoreJobStore) Report(ctx context.Context, id string) (*Report, error) {
+	reports := f.client.Collection(importJobsCollection).Doc(id).Collection("reports")
+
+	doc, err := reports.Doc("summary").Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := doc.DataTo(&report); err != nil {
+		return nil, err
+	}
+	report.Results = []RowResult{}
+
+	iter := reports.Where("Index", ">=", 0).OrderBy("Index", firestore.Asc).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return &report, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var chunk reportChunk
+		if err := doc.DataTo(&chunk); err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, chunk.Results...)
+	}
+}
+
+func (f *FirestoreJobStore) Close() error {
+	return f.client.Close()
+}
@@ -0,0 +1,178 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+)
+
+// What to do with a row matching an item of the catalog
+const (
+	ConflictSkip      = "skip"      // leave the item alone
+	ConflictOverwrite = "overwrite" // replace the fields the file has a column for, empty cells clear them
+	ConflictMerge     = "merge"     // replace the fields the row has a value for
+	ConflictFail      = "fail"      // report the row as failed
+)
+
+// DefaultKey identifies a product when the import doesn't name a key
+var DefaultKey = []string{"productName", "brand", "weight", "weightUnit"}
+
+// maxLookupValues is how many key values are looked up per query, the most
+// Firestore accepts in an in filter
+const maxLookupValues = 30
+
+// Validate checks the key, conflict strategy and profile. Key fields have to be
+// queryable and importable text or number fields, e.g. barcode.
+func (o Options) Validate() error {
+	switch o.Conflict {
+	case "", ConflictSkip, ConflictOverwrite, ConflictMerge, ConflictFail:
+	default:
+		return fmt.Errorf("onConflict must be one of %s, %s, %s or %s", ConflictSkip, ConflictOverwrite, ConflictMerge, ConflictFail)
+	}
+	if _, err := keyFields(o.Key); err != nil {
+		return err
+	}
+	if o.Profile != nil {
+		return o.Profile.Validate()
+	}
+	return nil
+}
+
+// ParseKey splits a comma separated list of key fields
+func ParseKey(s string) []string {
+	var key []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			key = append(key, name)
+		}
+	}
+	return key
+}
+
+func keyFields(names []string) ([]repository.Field, error) {
+	if len(names) == 0 {
+		names = DefaultKey
+	}
+
+	fields := make([]repository.Field, 0, len(names))
+	for _, name := range names {
+		field, ok := repository.LookupField(name)
+		_, importable := lookupColumn(name)
+		if !ok || !importable || field.Path == "" {
+			return nil, fmt.Errorf("%s can't be part of the key", name)
+		}
+		switch field.Kind {
+		case repository.KindString, repository.KindNumber, repository.KindInteger:
+		default:
+			return nil, fmt.Errorf("%s can't be part of the key", name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// itemKey is the key of item, text compared without case
+func itemKey(item models.GroceryItem, fields []repository.Field) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		switch value := field.Value(item).(type) {
+		case string:
+			parts[i] = strings.ToLower(strings.TrimSpace(value))
+		case nil:
+		default:
+			parts[i] = fmt.Sprintf("%g", value)
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+// findExisting returns the catalog items having the keys of the rows, by key.
+// Only the first key field can be queried, on its exact value; the others are
+// compared here.
+func (im *Importer) findExisting(ctx context.Context, fields []repository.Field, rows []pendingRow) (map[string][]models.GroceryItem, error) {
+	var values []interface{}
+	seen := map[interface{}]bool{}
+	for _, p := range rows {
+		if !p.keyed {
+			continue
+		}
+		value := fields[0].Value(p.row.item)
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+
+	existing := map[string][]models.GroceryItem{}
+	for start := 0; start < len(values); start += maxLookupValues {
+		items, err := im.Repo.List(ctx, repository.Query{
+			Filters: []repository.Filter{{Field: fields[0], Op: repository.OpIn, Values: values[start:min(start+maxLookupValues, len(values))]}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			key := itemKey(item, fields)
+			existing[key] = append(existing[key], item)
+		}
+	}
+	for _, items := range existing {
+		sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	}
+	return existing, nil
+}
+
+// applyRow returns item with the values of the row, following the strategy
+func applyRow(item models.GroceryItem, row *mappedRow, conflict string) models.GroceryItem {
+	fields := row.filled
+	if conflict == ConflictOverwrite {
+		fields = row.present
+	}
+	for name := range fields {
+		copyField(&item, &row.item, name)
+	}
+	return item
+}
+
+// copyField copies the field with the given json name
+func copyField(dst, src *models.GroceryItem, name string) {
+	t := reflect.TypeOf(*dst)
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			reflect.ValueOf(dst).Elem().Field(i).Set(reflect.ValueOf(src).Elem().Field(i))
+			return
+		}
+	}
+}
+
+// itemChanges lists the fields that differ between two versions of an item,
+// by json name. An empty list is the same as no list.
+func itemChanges(prev, item models.GroceryItem) []Change {
+	var changes []Change
+	before, after := reflect.ValueOf(prev), reflect.ValueOf(item)
+	for i := 0; i < before.NumField(); i++ {
+		from, to := before.Field(i).Interface(), after.Field(i).Interface()
+		if before.Field(i).Kind() == reflect.Slice && before.Field(i).Len() == 0 && after.Field(i).Len() == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(from, to) {
+			name, _, _ := strings.Cut(before.Type().Field(i).Tag.Get("json"), ",")
+			changes = append(changes, Change{Field: name, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+func itemIDs(items []models.GroceryItem) string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = fmt.Sprint(item.ID)
+	}
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,245 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/capstone/models"
+)
+
+// Value transforms a profile can apply to a column before it is read
+const (
+	TransformUnit      = "unit"      // weight with its unit, e.g. "500 g" or "1.5kg", also fills weightUnit
+	TransformCurrency  = "currency"  // number with a currency symbol or thousands separators, e.g. "₹1,299.00"
+	TransformLowercase = "lowercase" // text in lower case
+	TransformUppercase = "uppercase" // text in upper case
+)
+
+// Profile describes the files of a supplier whose columns don't go by the
+// field names: which column fills which field, how values are written, and
+// values for fields the files don't have.
+type Profile struct {
+	ID          string            `json:"id" firestore:"-"`
+	Name        string            `json:"name"`
+	Columns     map[string]string `json:"columns"`     // header to field, e.g. "MRP": "price", matched ignoring case and punctuation
+	Transforms  map[string]string `json:"transforms"`  // field to transform, e.g. "weight": "unit"
+	DateFormats []string          `json:"dateFormats"` // tried before the usual formats, e.g. DD/MM/YYYY or MMM-YY
+	TrueWords   []string          `json:"trueWords"`   // read as true, e.g. Veg
+	FalseWords  []string          `json:"falseWords"`  // read as false, e.g. Non-Veg
+	Defaults    map[string]string `json:"defaults"`    // field to value for rows without one, e.g. "countryOfOrigin": "India"
+	CreatedBy   string            `json:"createdBy"`
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+// dateTokens are the parts of a profile date format, longest first
+var dateTokens = regexp.MustCompile(`YYYY|YY|MMMM|MMM|MM|M|DD|D`)
+
+var dateLayouts = map[string]string{
+	"YYYY": "2006", "YY": "06",
+	"MMMM": "January", "MMM": "Jan", "MM": "01", "M": "1",
+	"DD": "02", "D": "2",
+}
+
+// unitValue splits a weight from its unit
+var unitValue = regexp.MustCompile(`^([0-9]+(?:[.,][0-9]+)?)\s*([a-zA-Z]*)\.?$`)
+
+// Validate checks that the profile only names known fields and transforms,
+// and that its formats and defaults can be read
+func (p *Profile) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	for column, name := range p.Columns {
+		if _, ok := fieldByName(name); !ok {
+			return fmt.Errorf("column %q maps to unknown field %s", column, name)
+		}
+	}
+	for name, transform := range p.Transforms {
+		if _, ok := fieldByName(name); !ok {
+			return fmt.Errorf("transform of unknown field %s", name)
+		}
+		switch transform {
+		case TransformCurrency, TransformLowercase, TransformUppercase:
+		case TransformUnit:
+			if name != "weight" {
+				return fmt.Errorf("the %s transform only applies to weight", TransformUnit)
+			}
+		default:
+			return fmt.Errorf("transform of %s must be one of %s, %s, %s or %s", name, TransformUnit, TransformCurrency, TransformLowercase, TransformUppercase)
+		}
+	}
+	for _, format := range p.DateFormats {
+		if _, err := dateLayout(format); err != nil {
+			return err
+		}
+	}
+	for _, word := range p.TrueWords {
+		for _, other := range p.FalseWords {
+			if strings.EqualFold(word, other) {
+				return fmt.Errorf("%q can't be both true and false", word)
+			}
+		}
+	}
+	for name, value := range p.Defaults {
+		field, ok := fieldByName(name)
+		if !ok {
+			return fmt.Errorf("default of unknown field %s", name)
+		}
+		var item models.GroceryItem
+		if err := p.set(&item, field, value, nil); err != nil {
+			return fmt.Errorf("default of %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// dateLayout turns a format like DD/MM/YYYY into a time layout
+func dateLayout(format string) (string, error) {
+	tokens := dateTokens.FindAllString(format, -1)
+	hasYear, hasMonth := false, false
+	for _, token := range tokens {
+		hasYear = hasYear || strings.HasPrefix(token, "Y")
+		hasMonth = hasMonth || strings.HasPrefix(token, "M")
+	}
+	if !hasYear || !hasMonth {
+		return "", fmt.Errorf("date format %q needs a year (YYYY or YY) and a month (MM, M, MMM or MMMM)", format)
+	}
+	return dateTokens.ReplaceAllStringFunc(format, func(token string) string { return dateLayouts[token] }), nil
+}
+
+// lookup finds the field a column fills, with the columns of the profile
+// first. A nil profile only has the usual columns.
+func (p *Profile) lookup(column string) (itemField, bool) {
+	if p != nil {
+		key := normalizeColumn(column)
+		for header, name := range p.Columns {
+			if normalizeColumn(header) == key {
+				return fieldByName(name)
+			}
+		}
+	}
+	return lookupColumn(column)
+}
+
+// set reads value into the field of item, through the transforms, words and
+// formats of the profile. filled, when not nil, records the fields set.
+func (p *Profile) set(item *models.GroceryItem, field itemField, value interface{}, filled map[string]bool) error {
+	if p == nil {
+		return field.set(item, value)
+	}
+
+	text, isText := value.(string)
+	if isText {
+		switch p.Transforms[field.name] {
+		case TransformLowercase:
+			value = strings.ToLower(text)
+		case TransformUppercase:
+			value = strings.ToUpper(text)
+		case TransformCurrency:
+			value = strings.Map(func(r rune) rune {
+				if r >= '0' && r <= '9' || r == '.' || r == '-' {
+					return r
+				}
+				return -1
+			}, text)
+		case TransformUnit:
+			match := unitValue.FindStringSubmatch(strings.TrimSpace(text))
+			if match == nil {
+				return fmt.Errorf("%q is not a weight with a unit", text)
+			}
+			value = strings.Replace(match[1], ",", ".", 1)
+			if match[2] != "" {
+				unit, _ := fieldByName("weightUnit")
+				if err := unit.set(item, match[2]); err != nil {
+					return err
+				}
+				if filled != nil {
+					filled[unit.name] = true
+				}
+			}
+		}
+
+		switch {
+		case field.name == "vegetarian" && containsFold(p.TrueWords, text):
+			value = true
+		case field.name == "vegetarian" && containsFold(p.FalseWords, text):
+			value = false
+		case field.name == "mfgDate" || field.name == "expDate":
+			for _, format := range p.DateFormats {
+				layout, _ := dateLayout(format)
+				if t, err := time.Parse(layout, strings.TrimSpace(text)); err == nil {
+					value = t.Format("01/2006")
+					break
+				}
+			}
+		}
+	}
+	return field.set(item, value)
+}
+
+// applyDefaults fills the fields the row has no value for
+func (p *Profile) applyDefaults(row *mappedRow) {
+	if p == nil {
+		return
+	}
+	for name, value := range p.Defaults {
+		if row.filled[name] || row.invalid[name] {
+			continue
+		}
+		field, _ := fieldByName(name)
+		if err := p.set(&row.item, field, value, row.filled); err == nil {
+			row.filled[name] = true
+			row.present[name] = true
+		}
+	}
+}
+
+// missingFields are the required fields that no column and no default of
+// the profile fill
+func (p *Profile) missingFields(columns []string) []string {
+	covered := map[string]bool{}
+	for _, column := range columns {
+		if field, ok := p.lookup(column); ok {
+			covered[field.name] = true
+			if p != nil && field.name == "weight" && p.Transforms["weight"] == TransformUnit {
+				covered["weightUnit"] = true
+			}
+		}
+	}
+	if p != nil {
+		for name := range p.Defaults {
+			covered[name] = true
+		}
+	}
+
+	var missing []string
+	for _, field := range itemFields {
+		if field.required && !covered[field.name] {
+			missing = append(missing, field.name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func fieldByName(name string) (itemField, bool) {
+	for _, f := range itemFields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return itemField{}, false
+}
+
+func containsFold(words []string, s string) bool {
+	s = strings.TrimSpace(s)
+	for _, word := range words {
+		if strings.EqualFold(word, s) {
+			return true
+		}
+	}
+	return false
+}
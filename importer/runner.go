@@ -0,0 +1,218 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// cancelPollInterval is how often a running job looks for a cancellation
+// requested through another process
+const cancelPollInterval = 2 * time.Second
+
+// ErrJobFinished is returned when cancelling a job that already finished
+var ErrJobFinished = errors.New("import job already finished")
+
+// ErrJobNotPreviewed is returned when approving a job that isn't a finished
+// dry run
+var ErrJobNotPreviewed = errors.New("import job is not a previewed dry run")
+
+var errJobNotQueued = errors.New("import job is not queued")
+
+// ImporterFactory sets up the importer of one job, release frees what it holds
+type ImporterFactory func() (im *Importer, release func(), err error)
+
+// Runner runs import jobs. Jobs are either enqueued to the pool of workers of
+// the process, or passed to Process by a worker of its own, e.g. a Cloud
+// Function reading job IDs from Pub/Sub.
+type Runner struct {
+	jobs        JobStore
+	blobs       BlobStore
+	newImporter ImporterFactory
+	queue       chan string
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc // jobs running in this process
+}
+
+func NewRunner(jobs JobStore, blobs BlobStore, newImporter ImporterFactory) *Runner {
+	return &Runner{
+		jobs:        jobs,
+		blobs:       blobs,
+		newImporter: newImporter,
+		queue:       make(chan string, 100),
+		running:     map[string]context.CancelFunc{},
+	}
+}
+
+// Start starts workers goroutines running the enqueued jobs
+func (r *Runner) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for id := range r.queue {
+				if err := r.Process(context.Background(), id); err != nil {
+					log.Printf("Import job %s: %v", id, err)
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue hands a queued job to the workers without waiting for one to be free
+func (r *Runner) Enqueue(id string) {
+	go func() { r.queue <- id }()
+}
+
+// Cancel cancels a job. A queued job is cancelled right away, a running one
+// stops after the row it is at, keeping what it wrote so far.
+func (r *Runner) Cancel(ctx context.Context, id string) (Job, error) {
+	job, err := r.jobs.UpdateJob(ctx, id, func(job *Job) error {
+		switch job.Status {
+		case JobQueued, JobPreviewed:
+			job.Status = JobCancelled
+			job.FinishedAt = time.Now().UTC()
+		case JobRunning:
+			job.CancelRequested = true
+		default:
+			return ErrJobFinished
+		}
+		return nil
+	})
+	if err != nil {
+		return Job{}, err
+	}
+
+	r.mu.Lock()
+	if cancel, ok := r.running[id]; ok {
+		cancel()
+	}
+	r.mu.Unlock()
+	return job, nil
+}
+
+// Approve turns a previewed dry run into the real import of the same file
+// with the same options, queued again. The caller enqueues it.
+func (r *Runner) Approve(ctx context.Context, id, approvedBy string) (Job, error) {
+	return r.jobs.UpdateJob(ctx, id, func(job *Job) error {
+		if job.Status != JobPreviewed {
+			return ErrJobNotPreviewed
+		}
+		job.Status = JobQueued
+		job.DryRun = false
+		job.ApprovedBy = approvedBy
+		job.ApprovedAt = time.Now().UTC()
+		job.Progress = Progress{}
+		job.StartedAt, job.FinishedAt = time.Time{}, time.Time{}
+		return nil
+	})
+}
+
+// Process runs a queued job to the end and stores its report. A job that
+// isn't queued any more, because it was cancelled or already picked up, is
+// left alone.
+func (r *Runner) Process(ctx context.Context, id string) error {
+	job, err := r.jobs.UpdateJob(ctx, id, func(job *Job) error {
+		if job.Status != JobQueued {
+			return errJobNotQueued
+		}
+		job.Status = JobRunning
+		job.StartedAt = time.Now().UTC()
+		return nil
+	})
+	if err == errJobNotQueued {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.mu.Lock()
+	r.running[id] = cancel
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, id)
+		r.mu.Unlock()
+	}()
+	go r.watchCancel(runCtx, id, cancel)
+
+	report, runErr := r.run(runCtx, job)
+
+	if report != nil {
+		if err := r.jobs.SaveReport(ctx, id, report); err != nil {
+			log.Printf("Failed to save the report of import job %s: %v", id, err)
+		}
+	}
+
+	_, err = r.jobs.UpdateJob(ctx, id, func(job *Job) error {
+		job.FinishedAt = time.Now().UTC()
+		switch {
+		case runErr != nil:
+			job.Status = JobFailed
+			job.Error = runErr.Error()
+			return nil
+		case runCtx.Err() != nil && ctx.Err() == nil:
+			job.Status = JobCancelled
+		case report.Error != "":
+			job.Status = JobFailed
+			job.Error = report.Error
+		case job.DryRun:
+			job.Status = JobPreviewed
+		default:
+			job.Status = JobSucceeded
+		}
+		job.Format = report.Format
+		job.Progress = report.progress()
+		return nil
+	})
+	return err
+}
+
+func (r *Runner) run(ctx context.Context, job Job) (*Report, error) {
+	file, err := r.blobs.Open(ctx, job.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	im, release, err := r.newImporter()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	im.OnProgress = func(p Progress) {
+		_, err := r.jobs.UpdateJob(context.Background(), job.ID, func(job *Job) error {
+			job.Progress = p
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to record the progress of import job %s: %v", job.ID, err)
+		}
+	}
+	return im.Run(ctx, file, Options{Filename: job.Filename, Format: job.Format, Sheet: job.Sheet, Key: job.Key, Conflict: job.Conflict, DryRun: job.DryRun, Profile: job.Profile})
+}
+
+// watchCancel cancels a running job once a cancellation is requested in the
+// store, which is how Cancel reaches jobs running in another process
+func (r *Runner) watchCancel(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job, err := r.jobs.Job(ctx, id)
+			if err == nil && job.CancelRequested {
+				cancel()
+				return
+			}
+		}
+	}
+}
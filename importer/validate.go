@@ -0,0 +1,60 @@
+package importer
+
+import (
+	"fmt"
+
+	"example.com/capstone/models"
+)
+
+// weightUnits are the accepted weight units
+var weightUnits = map[string]bool{"mg": true, "g": true, "gm": true, "kg": true, "ml": true, "l": true}
+
+// validateItem returns what is wrong with an imported item, nothing when it
+// can be stored
+func validateItem(item models.GroceryItem) []string {
+	var problems []string
+	required := func(name, value string) {
+		if value == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+
+	required("productName", item.ProductName)
+	required("category", item.Category)
+	required("manufacturer", item.Manufacturer)
+	required("brand", item.Brand)
+	required("packageInformation", item.PackageInformation)
+	required("countryOfOrigin", item.CountryOfOrigin)
+
+	if item.Price <= 0 {
+		problems = append(problems, "price must be positive")
+	}
+	if item.Weight <= 0 {
+		problems = append(problems, "weight must be positive")
+	}
+	if !weightUnits[item.WeightUnit] {
+		problems = append(problems, fmt.Sprintf("weightUnit %q is not one of mg, g, gm, kg, ml or l", item.WeightUnit))
+	}
+	if item.ItemPackageQuantity < 1 {
+		problems = append(problems, "itemPackageQuantity must be at least 1")
+	}
+	if item.Stock != nil && *item.Stock < 0 {
+		problems = append(problems, "stock can't be negative")
+	}
+
+	if item.MfgDate.Month == 0 {
+		problems = append(problems, "mfgDate is required")
+	}
+	if item.ExpDate.Month == 0 {
+		problems = append(problems, "expDate is required")
+	}
+	if item.MfgDate.Month != 0 && item.ExpDate.Month != 0 && monthIndex(item.ExpDate) < monthIndex(item.MfgDate) {
+		problems = append(problems, "expDate is before mfgDate")
+	}
+
+	return problems
+}
+
+func monthIndex(my models.MonthYear) int {
+	return my.Year*12 + int(my.Month) - 1
+}
@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// headerSearchRows is how far down a sheet the header row is looked for,
+// above it there may be a title, notes or blank rows
+const headerSearchRows = 20
+
+// xlsxReader reads the rows of one sheet of a workbook. Workbooks are zip
+// files, so the whole file is read before the first row.
+type xlsxReader struct {
+	file     *excelize.File
+	rows     *excelize.Rows
+	header   []string
+	dates    map[int]bool // columns of date fields, which hold date serials
+	date1904 bool
+	row      int
+}
+
+// newXLSXReader opens the sheet named by opts.Sheet, its name or 1-based
+// position, or else the first sheet
+func newXLSXReader(r io.Reader, opts Options) (*xlsxReader, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("the workbook can't be read: %v", err)
+	}
+
+	sheet, err := pickSheet(file, opts.Sheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	rows, err := file.Rows(sheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	x := &xlsxReader{file: file, rows: rows, dates: map[int]bool{}}
+	if props, err := file.GetWorkbookProps(); err == nil && props.Date1904 != nil {
+		x.date1904 = *props.Date1904
+	}
+	if err := x.findHeader(opts.Profile); err != nil {
+		x.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+func pickSheet(file *excelize.File, want string) (string, error) {
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return "", fmt.Errorf("the workbook has no sheet")
+	}
+	if want == "" {
+		return sheets[0], nil
+	}
+	for _, sheet := range sheets {
+		if strings.EqualFold(sheet, want) {
+			return sheet, nil
+		}
+	}
+	if n, err := strconv.Atoi(want); err == nil && n >= 1 && n <= len(sheets) {
+		return sheets[n-1], nil
+	}
+	return "", fmt.Errorf("sheet %q not found, the workbook has %s", want, strings.Join(sheets, ", "))
+}
+
+// findHeader takes the first row with at least two columns naming fields as
+// the header, or the first row with a value when none does
+func (x *xlsxReader) findHeader(profile *Profile) error {
+	var skipped [][]string
+	for len(skipped) < headerSearchRows && x.rows.Next() {
+		cells, err := x.rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return err
+		}
+		known := 0
+		for _, cell := range cells {
+			if _, ok := profile.lookup(cell); ok {
+				known++
+			}
+		}
+		if known >= 2 {
+			x.setHeader(cells, profile)
+			return nil
+		}
+		skipped = append(skipped, cells)
+	}
+	if err := x.rows.Error(); err != nil {
+		return err
+	}
+
+	// no row names fields, the rows after the first one with a value are lost
+	// as they were read already, which only leaves files that fail anyway
+	for _, cells := range skipped {
+		if !blankRow(cells) {
+			x.setHeader(cells, profile)
+			return nil
+		}
+	}
+	return errors.New("the sheet is empty")
+}
+
+func (x *xlsxReader) setHeader(cells []string, profile *Profile) {
+	x.header = make([]string, len(cells))
+	for i, cell := range cells {
+		x.header[i] = strings.TrimSpace(cell)
+		if field, ok := profile.lookup(cell); ok && (field.name == "mfgDate" || field.name == "expDate") {
+			x.dates[i] = true
+		}
+	}
+}
+
+func (x *xlsxReader) columns() []string {
+	return x.header
+}
+
+func (x *xlsxReader) next() (record, error) {
+	if x.header == nil || !x.rows.Next() {
+		if err := x.rows.Error(); err != nil {
+			return record{}, err
+		}
+		return record{}, io.EOF
+	}
+
+	cells, err := x.rows.Columns(excelize.Options{RawCellValue: true})
+	if err != nil {
+		x.row++
+		return record{}, &rowError{row: x.row, err: err}
+	}
+	// blank rows, e.g. between sections, are skipped like blank CSV lines
+	if blankRow(cells) {
+		return x.next()
+	}
+	x.row++
+
+	values := make(map[string]interface{}, len(x.header))
+	for i, column := range x.header {
+		if column == "" {
+			continue
+		}
+		var value string
+		if i < len(cells) {
+			value = cells[i]
+		}
+		if x.dates[i] {
+			value = x.fromSerial(value)
+		}
+		values[column] = value
+	}
+	return record{row: x.row, values: values}, nil
+}
+
+func blankRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// fromSerial turns a date cell, a number of days since 1900 or 1904, into
+// MM/YYYY. Dates typed as text are left as they are.
+func (x *xlsxReader) fromSerial(value string) string {
+	serial, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return value
+	}
+	t, err := excelize.ExcelDateToTime(serial, x.date1904)
+	if err != nil {
+		return value
+	}
+	return t.Format("01/2006")
+}
+
+// Close releases the temporary files of a large workbook
+func (x *xlsxReader) Close() error {
+	x.rows.Close()
+	return x.file.Close()
+}
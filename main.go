@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"os"
 
 	_ "example.com/capstone/docs"
 
@@ -21,15 +23,86 @@ import (
 // @host localhost:8080
 // @schemes http
 func main() {
+	if err := handlers.ConfigurePayments(); err != nil {
+		log.Fatal("Payments can't be set up: ", err)
+	}
+	if err := handlers.ConfigurePageTokens(); err != nil {
+		log.Fatal("Page tokens can't be set up: ", err)
+	}
+
 	r := mux.NewRouter()
 
 	r.HandleFunc("/createGroceryItem", handlers.CreateGroceryItem).Methods("POST")
 	r.HandleFunc("/bulkupload", handlers.BulkUpload).Methods("POST")
+	r.HandleFunc("/imports", handlers.ImportGroceryItems).Methods("POST")
+	r.HandleFunc("/imports/{jobId}", handlers.FetchImportJob).Methods("GET")
+	r.HandleFunc("/imports/{jobId}/errors", handlers.ImportJobErrors).Methods("GET")
+	r.HandleFunc("/imports/{jobId}/report", handlers.FetchImportReport).Methods("GET")
+	r.HandleFunc("/imports/{jobId}/approve", handlers.ApproveImportJob).Methods("POST")
+	r.HandleFunc("/imports/{jobId}/cancel", handlers.CancelImportJob).Methods("POST")
+	r.HandleFunc("/importProfiles", handlers.CreateImportProfile).Methods("POST")
+	r.HandleFunc("/importProfiles", handlers.ListImportProfiles).Methods("GET")
+	r.HandleFunc("/importProfiles/{profileId}", handlers.DeleteImportProfile).Methods("DELETE")
+	r.HandleFunc("/export", handlers.ExportCatalog).Methods("GET")
+	r.HandleFunc("/exports", handlers.CreateExportJob).Methods("POST")
+	r.HandleFunc("/exports/{jobId}", handlers.FetchExportJob).Methods("GET")
+	r.HandleFunc("/exports/{jobId}/download", handlers.DownloadExport).Methods("GET")
 	r.HandleFunc("/listGroceryItems", handlers.ListItemsBY).Methods("GET")
+	r.HandleFunc("/backfillUnitPrices", handlers.BackfillUnitPrices).Methods("POST")
 	r.HandleFunc("/updateGroceryItemByID/{id:[0-9]+}", handlers.UpdateGroceryItem).Methods("PUT") // impliment patch as well
 	r.HandleFunc("/deleteGroceryItemByID/{id:[0-9]+}", handlers.DeleteItemByID).Methods("DELETE")
 	r.HandleFunc("/fetchGroceryItemByID/{id:[0-9]+}", handlers.FetchItemByID).Methods("GET")
+	r.HandleFunc("/items", handlers.FetchItems).Methods("GET")
+	r.HandleFunc("/items/batch", handlers.FetchItemsBatch).Methods("POST")
 	r.HandleFunc("/imageUpload", handlers.UploadHandler).Methods("POST")
+	r.HandleFunc("/imageImports", handlers.ImportImages).Methods("POST")
+
+	// search
+	r.HandleFunc("/search", handlers.SearchItems).Methods("GET")
+	r.HandleFunc("/search/rebuild", handlers.RebuildSearchIndex).Methods("POST")
+	r.HandleFunc("/suggest", handlers.SuggestItems).Methods("GET")
+
+	// catalog statistics
+	r.HandleFunc("/stats/prices", handlers.PriceStatistics).Methods("GET")
+	r.HandleFunc("/stats/vegetarian", handlers.VegetarianStatistics).Methods("GET")
+	r.HandleFunc("/stats/expiry", handlers.ExpiryStatistics).Methods("GET")
+
+	// saved searches and notifications
+	r.HandleFunc("/savedSearches", handlers.CreateSavedSearch).Methods("POST")
+	r.HandleFunc("/savedSearches", handlers.ListSavedSearches).Methods("GET")
+	r.HandleFunc("/savedSearches/{searchId}", handlers.DeleteSavedSearch).Methods("DELETE")
+	r.HandleFunc("/notifications", handlers.ListNotifications).Methods("GET")
+	r.HandleFunc("/notifications/{notificationId}/read", handlers.MarkNotificationRead).Methods("POST")
+
+	// price schedules
+	r.HandleFunc("/schedulePriceChange/{id:[0-9]+}", handlers.SchedulePriceChange).Methods("POST")
+	r.HandleFunc("/cancelPriceChange/{changeId}", handlers.CancelPriceChange).Methods("DELETE")
+	r.HandleFunc("/priceHistory/{id:[0-9]+}", handlers.PriceHistory).Methods("GET")
+	r.HandleFunc("/applyPriceSchedules", handlers.ApplyPriceSchedules).Methods("POST")
+
+	// promotions
+	r.HandleFunc("/createPromotion", handlers.CreatePromotion).Methods("POST")
+	r.HandleFunc("/listPromotions", handlers.ListPromotions).Methods("GET")
+	r.HandleFunc("/deletePromotion/{promotionId}", handlers.DeletePromotion).Methods("DELETE")
+
+	// cart and orders
+	r.HandleFunc("/cart", handlers.GetCart).Methods("GET")
+	r.HandleFunc("/cart/items", handlers.AddCartItem).Methods("POST")
+	r.HandleFunc("/cart/items/{id:[0-9]+}", handlers.UpdateCartItem).Methods("PUT")
+	r.HandleFunc("/cart/items/{id:[0-9]+}", handlers.RemoveCartItem).Methods("DELETE")
+	r.HandleFunc("/checkout", handlers.Checkout).Methods("POST")
+	r.HandleFunc("/orders", handlers.ListOrders).Methods("GET")
+	r.HandleFunc("/orders/{orderId}", handlers.FetchOrder).Methods("GET")
+	r.HandleFunc("/orders/{orderId}/status", handlers.UpdateOrderStatus).Methods("PUT")
+	r.HandleFunc("/releaseStaleOrders", handlers.ReleaseStaleOrders).Methods("POST")
+	r.HandleFunc("/payments/webhook", handlers.PaymentWebhook).Methods("POST")
+
+	// coupons
+	r.HandleFunc("/coupons", handlers.CreateCoupon).Methods("POST")
+	r.HandleFunc("/coupons", handlers.ListCoupons).Methods("GET")
+	r.HandleFunc("/coupons/{code}", handlers.DeactivateCoupon).Methods("DELETE")
+	r.HandleFunc("/coupons/{code}/redemptions", handlers.CouponRedemptions).Methods("GET")
+	r.HandleFunc("/cart/validateCoupon", handlers.ValidateCartCoupon).Methods("POST")
 
 	// users
 	r.HandleFunc("/users", users.CreateNewUser).Methods("POST")
@@ -42,6 +115,11 @@ func main() {
 
 	http.Handle("/", r)
 
+	// local stand in for the storage triggered import of data files
+	if os.Getenv("INGEST_WATCH") == "true" {
+		handlers.StartIngestWatcher()
+	}
+
 	// Run the server
 	fmt.Print("Server is up and running!")
 	http.ListenAndServe(":8080", nil)
@@ -22,10 +22,13 @@ type GroceryItem struct {
 	MfgDate             MonthYear `json:"mfgDate" validate:"required"`
 	ExpDate             MonthYear `json:"expDate" validate:"required"`
 	CountryOfOrigin     string    `json:"countryOfOrigin" validate:"required"`
+	Tags                []string  `json:"tags"`    // free form labels, e.g. "festive", "organic" - used to scope promotions
+	Stock               *int      `json:"stock"`   // units available to order, reserved at checkout; nil when stock isn't tracked
+	Barcode             string    `json:"barcode"` // EAN/UPC printed on the package, empty when unknown
 }
 
 type MonthYear struct {
-	Month time.Month
+	Month time.Month `swaggertype:"integer"`
 	Year  int
 }
 
@@ -53,3 +56,156 @@ type AuditRecord struct {
 	Timestamp time.Time `json:"timestamp"`
 	// PerformedBy string    `json:"performedBy"`
 }
+
+// PriceChange is a scheduled or recorded change to the price of a grocery item.
+// A zero EffectiveTo means the change is permanent, otherwise the price only
+// applies inside the [EffectiveFrom, EffectiveTo) window.
+type PriceChange struct {
+	ID            string    `json:"id" firestore:"-"`
+	ItemID        int       `json:"itemID"`
+	Price         float64   `json:"price"`
+	PreviousPrice float64   `json:"previousPrice"` // filled in when the change is applied, an active window gives it back when it ends
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	EffectiveTo   time.Time `json:"effectiveTo"`
+	Status        string    `json:"status"` // pending, active, applied, expired or cancelled
+	Source        string    `json:"source"` // schedule - created by a price schedule, update - direct edit of the item
+	CreatedAt     time.Time `json:"createdAt"`
+	AppliedAt     time.Time `json:"appliedAt"`
+}
+
+// Promotion is a discount rule. It applies to items matching any of its scopes
+// (item IDs, categories, brands or tags), or to every item when no scope is set.
+type Promotion struct {
+	ID          string    `json:"id" firestore:"-"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`        // percentage, flat, bogo or multibuy
+	Value       float64   `json:"value"`       // percent off for percentage, amount off per unit for flat
+	BuyQuantity int       `json:"buyQuantity"` // multibuy only - buy this many ...
+	PayQuantity int       `json:"payQuantity"` // ... and pay for this many, e.g. 3 for 2
+	ItemIDs     []int     `json:"itemIDs"`
+	Categories  []string  `json:"categories"`
+	Brands      []string  `json:"brands"`
+	Tags        []string  `json:"tags"`
+	StartsAt    time.Time `json:"startsAt"`
+	EndsAt      time.Time `json:"endsAt"`    // zero value - no end date
+	Priority    int       `json:"priority"`  // higher priority promotions are evaluated first
+	Stackable   bool      `json:"stackable"` // can be combined with other stackable promotions
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type Cart struct {
+	UserEmail string     `json:"userEmail"`
+	Lines     []CartLine `json:"lines"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+type CartLine struct {
+	ItemID   int `json:"itemID"`
+	Quantity int `json:"quantity"`
+}
+
+// OrderLine is a priced cart line. Orders keep a snapshot of it so later price
+// changes don't affect placed orders.
+type OrderLine struct {
+	ItemID            int      `json:"itemID"`
+	ProductName       string   `json:"productName"`
+	Quantity          int      `json:"quantity"`
+	UnitPrice         float64  `json:"unitPrice"`      // price effective at the time, before promotions
+	EffectivePrice    float64  `json:"effectivePrice"` // average unit price after promotions
+	LineTotal         float64  `json:"lineTotal"`
+	Discount          float64  `json:"discount"`
+	AppliedPromotions []string `json:"appliedPromotions"`
+	Reserved          bool     `json:"reserved"` // stock was taken at checkout, and is given back when the order is released
+}
+
+type Order struct {
+	ID               string              `json:"id" firestore:"-"`
+	UserEmail        string              `json:"userEmail"`
+	Lines            []OrderLine         `json:"lines"`
+	Subtotal         float64             `json:"subtotal"`
+	Discount         float64             `json:"discount"`
+	Total            float64             `json:"total"`
+	Status           string              `json:"status"` // pending-payment, payment-failed, placed, packed, out-for-delivery, delivered or cancelled
+	StatusHistory    []OrderStatusChange `json:"statusHistory"`
+	PaymentProvider  string              `json:"paymentProvider"`
+	PaymentReference string              `json:"paymentReference"` // capture reference at the provider, used for refunds
+	PaymentStatus    string              `json:"paymentStatus"`    // authorized, captured, failed, refunded or refund-failed
+	CouponCode       string              `json:"couponCode"`
+	CouponDiscount   float64             `json:"couponDiscount"` // included in Discount
+	CreatedAt        time.Time           `json:"createdAt"`
+	UpdatedAt        time.Time           `json:"updatedAt"`
+}
+
+type OrderStatusChange struct {
+	Status    string    `json:"status"`
+	ChangedBy string    `json:"changedBy"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// PaymentAttempt is one call to a payment provider, or one webhook received from it
+type PaymentAttempt struct {
+	ID        string    `json:"id" firestore:"-"`
+	OrderID   string    `json:"orderID"`
+	Provider  string    `json:"provider"`
+	Operation string    `json:"operation"` // authorize, capture, refund or webhook
+	Amount    float64   `json:"amount"`
+	Status    string    `json:"status"` // succeeded, declined or failed
+	Reference string    `json:"reference"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Coupon is a code customers enter at checkout. The document ID is the code in upper case.
+type Coupon struct {
+	Code           string    `json:"code"`
+	Description    string    `json:"description"`
+	Type           string    `json:"type"`           // percentage or flat
+	Value          float64   `json:"value"`          // percent off or amount off the eligible lines
+	MaxRedemptions int       `json:"maxRedemptions"` // 1 for single-use, 0 for unlimited
+	PerUserLimit   int       `json:"perUserLimit"`   // 0 for unlimited
+	MinCartValue   float64   `json:"minCartValue"`   // cart total after promotions
+	ExpiresAt      time.Time `json:"expiresAt"`      // zero value - never expires
+	Categories     []string  `json:"categories"`     // when set, only lines of these categories are discounted
+	Brands         []string  `json:"brands"`         // when set, only lines of these brands are discounted
+	Redemptions    int       `json:"redemptions"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// CouponRedemption ties a coupon to the order it was used on. The document ID is the order ID.
+type CouponRedemption struct {
+	Code       string    `json:"code"`
+	UserEmail  string    `json:"userEmail"`
+	OrderID    string    `json:"orderID"`
+	Discount   float64   `json:"discount"`
+	RedeemedAt time.Time `json:"redeemedAt"`
+	Released   bool      `json:"released"` // the order was cancelled or not paid, the use was given back
+}
+
+// SavedSearch is a listing query a user wants to hear about. Query holds list
+// filters in query string form, e.g. category=Snacks&price[lt]=50&vegetarian=true
+type SavedSearch struct {
+	ID        string    `json:"id" firestore:"-"`
+	UserEmail string    `json:"userEmail"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	NotifyOn  []string  `json:"notifyOn"` // new-match and/or price-drop, both when empty
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Notification tells a user an item started matching one of their saved
+// searches, or got cheaper while matching it
+type Notification struct {
+	ID            string    `json:"id" firestore:"-"`
+	UserEmail     string    `json:"userEmail"`
+	SavedSearchID string    `json:"savedSearchID"`
+	SearchName    string    `json:"searchName"`
+	Event         string    `json:"event"` // new-match or price-drop
+	ItemID        int       `json:"itemID"`
+	ProductName   string    `json:"productName"`
+	Price         float64   `json:"price"`
+	PreviousPrice float64   `json:"previousPrice"` // price-drop only
+	Read          bool      `json:"read"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
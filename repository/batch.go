@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WriteOptions tune the bulk writes of CreateItems and UpdateItems. Items are
+// committed in batches of BatchSize, Concurrency batches at a time, and a
+// batch failing with a transient error is tried up to MaxAttempts times,
+// waiting Backoff before the second attempt and twice as long after each.
+type WriteOptions struct {
+	BatchSize   int
+	Concurrency int
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// DefaultWriteOptions are the write options of new repositories
+var DefaultWriteOptions = WriteOptions{BatchSize: maxBatchWrites, Concurrency: 4, MaxAttempts: 5, Backoff: 200 * time.Millisecond}
+
+// withDefaults fills the options left at zero, and caps the batch size at
+// what Firestore accepts
+func (o WriteOptions) withDefaults() WriteOptions {
+	if o.BatchSize <= 0 || o.BatchSize > maxBatchWrites {
+		o.BatchSize = DefaultWriteOptions.BatchSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultWriteOptions.Concurrency
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultWriteOptions.MaxAttempts
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultWriteOptions.Backoff
+	}
+	return o
+}
+
+// sequence returns the indexes of n items
+func sequence(n int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return indexes
+}
+
+// IsTransient reports whether a failed write may succeed when tried again
+func IsTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal:
+		return true
+	}
+	return false
+}
+
+// failsEveryWrite reports whether err would fail any write, so splitting the
+// batch can't single out the documents at fault
+func failsEveryWrite(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled, codes.PermissionDenied, codes.Unauthenticated:
+		return true
+	}
+	return IsTransient(err)
+}
+
+// commitFunc writes the items at indexes atomically. attempt is 1 for the
+// first try of a batch and grows with every retry.
+type commitFunc func(ctx context.Context, indexes []int, attempt int) error
+
+// writeBatches commits the items at indexes in batches, a few at a time, and
+// records the error of each item in errs. A batch failing for a reason other
+// than a transient one is split in halves until the items at fault are on
+// their own, so one bad item doesn't fail the rest of its batch.
+func writeBatches(ctx context.Context, indexes []int, errs []error, opts WriteOptions, commit commitFunc) {
+	opts = opts.withDefaults()
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, opts.Concurrency)
+	for start := 0; start < len(indexes); start += opts.BatchSize {
+		batch := indexes[start:min(start+opts.BatchSize, len(indexes))]
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			// batches don't share items, so each writes its own entries of errs
+			writeBatch(ctx, batch, errs, opts, commit)
+		}()
+	}
+	wg.Wait()
+}
+
+func writeBatch(ctx context.Context, batch []int, errs []error, opts WriteOptions, commit commitFunc) {
+	err := commitWithRetry(ctx, batch, opts, commit)
+	if err == nil {
+		return
+	}
+	if len(batch) == 1 || failsEveryWrite(err) || ctx.Err() != nil {
+		for _, i := range batch {
+			errs[i] = err
+		}
+		return
+	}
+	half := len(batch) / 2
+	writeBatch(ctx, batch[:half], errs, opts, commit)
+	writeBatch(ctx, batch[half:], errs, opts, commit)
+}
+
+func commitWithRetry(ctx context.Context, batch []int, opts WriteOptions, commit commitFunc) error {
+	wait := opts.Backoff
+	for attempt := 1; ; attempt++ {
+		err := commit(ctx, batch, attempt)
+		if err == nil || !IsTransient(err) || attempt == opts.MaxAttempts {
+			return err
+		}
+
+		// jitter keeps batches failing together from retrying together
+		jittered := wait/2 + time.Duration(rand.Int63n(int64(wait)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(jittered):
+		}
+		wait *= 2
+	}
+}
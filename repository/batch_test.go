@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"example.com/capstone/models"
+)
+
+// BenchmarkCreateItems compares writing items one commit at a time, as
+// imports used to, with writeBatches committing batches one at a time and a
+// few at a time. Commits to the memory repository take a latency standing in
+// for Firestore.
+func BenchmarkCreateItems(b *testing.B) {
+	const items = 1000
+	catalog := make([]models.GroceryItem, items)
+	for i := range catalog {
+		catalog[i] = models.GroceryItem{ID: i + 1, ProductName: fmt.Sprintf("Item %d", i+1), Category: "Bench", Price: 1.99, ItemPackageQuantity: 1}
+	}
+
+	benchmarks := []struct {
+		name string
+		opts WriteOptions
+	}{
+		{"sequential", WriteOptions{BatchSize: 1, Concurrency: 1}},
+		{"batched", WriteOptions{Concurrency: 1}},
+		{"batched parallel", WriteOptions{BatchSize: 100, Concurrency: DefaultWriteOptions.Concurrency}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				repo := NewMemoryRepository()
+				repo.SetCommitLatency(2*time.Millisecond, 20*time.Microsecond)
+				repo.SetWriteOptions(bm.opts)
+
+				for _, err := range repo.CreateItems(context.Background(), catalog) {
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(items*b.N)/b.Elapsed().Seconds(), "items/s")
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"example.com/capstone/models"
+)
+
+// pageToken is the content of a page token. Query is a fingerprint of the
+// filters and sort the token was issued for, so a token can't be replayed
+// against a different listing.
+type pageToken struct {
+	After []interface{} `json:"a"`
+	Query string        `json:"q"`
+}
+
+// EncodePageToken returns an opaque token for the page that follows last in
+// the results of q. The token is signed with secret.
+func EncodePageToken(secret []byte, q Query, last models.GroceryItem) (string, error) {
+	payload, err := json.Marshal(pageToken{After: SortValues(q, last), Query: fingerprint(q)})
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + sign(secret, encoded), nil
+}
+
+// DecodePageToken checks token and returns the position to pass as q.After.
+// The token may be signed with any of secrets, so tokens signed with a secret
+// being rotated out stay valid. The error is a *QueryError when the token is
+// malformed, forged or was issued for another query.
+func DecodePageToken(secrets [][]byte, token string, q Query) ([]interface{}, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !signedWithAny(secrets, encoded, signature) {
+		return nil, queryErrorf("invalid page token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, queryErrorf("invalid page token")
+	}
+
+	var decoded pageToken
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, queryErrorf("invalid page token")
+	}
+	if decoded.Query != fingerprint(q) || len(decoded.After) != len(effectiveSort(q)) {
+		return nil, queryErrorf("page token does not belong to this query")
+	}
+
+	return decoded.After, nil
+}
+
+func signedWithAny(secrets [][]byte, encoded, signature string) bool {
+	for _, secret := range secrets {
+		if hmac.Equal([]byte(signature), []byte(sign(secret, encoded))) {
+			return true
+		}
+	}
+	return false
+}
+
+func sign(secret []byte, encoded string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// fingerprint identifies the filters and order of q
+func fingerprint(q Query) string {
+	var b strings.Builder
+	b.WriteString(filtersKey(q.Filters))
+	for _, key := range effectiveSort(q) {
+		fmt.Fprintf(&b, "%s:%t;", key.Field.Name, key.Desc)
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
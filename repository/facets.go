@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/capstone/models"
+)
+
+// maxFacetValues caps the number of values returned for a terms facet
+const maxFacetValues = 50
+
+// FacetRequest asks for counts per value of a field, or per range when Bounds
+// is set
+type FacetRequest struct {
+	Field  Field
+	Bounds []float64
+}
+
+// FacetValue is the number of items having a value, or falling in a range
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// FacetResult holds the counts of one facet, largest first for terms facets
+// and in bound order for range facets. Partial is set when only some of the
+// matching items were counted.
+type FacetResult struct {
+	Field   string       `json:"field"`
+	Values  []FacetValue `json:"values"`
+	Partial bool         `json:"partial,omitempty"`
+}
+
+// ParseFacets parses a comma separated list of facets. A plain field name
+// counts items per value, field:b0-b1-...-bn counts items per range
+// [b0,b1), [b1,b2) ... and bn+, e.g. category,vegetarian,price:0-50-100-500.
+func ParseFacets(value string) ([]FacetRequest, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var requests []FacetRequest
+	for _, part := range strings.Split(value, ",") {
+		name, spec, ranged := strings.Cut(strings.TrimSpace(part), ":")
+
+		field, ok := LookupField(name)
+		if !ok {
+			return nil, queryErrorf("can't facet on %q", name)
+		}
+		request := FacetRequest{Field: field}
+
+		if !ranged {
+			if field.Kind == KindNumber || field.Kind == KindInteger {
+				return nil, queryErrorf("%s facet needs ranges, e.g. %s:0-50-100", field.Name, field.Name)
+			}
+			requests = append(requests, request)
+			continue
+		}
+
+		if field.Kind != KindNumber && field.Kind != KindInteger {
+			return nil, queryErrorf("%s is not a number, it can't have ranges", field.Name)
+		}
+		for _, bound := range strings.Split(spec, "-") {
+			b, err := strconv.ParseFloat(bound, 64)
+			if err != nil {
+				return nil, queryErrorf("invalid range bound %q for %s", bound, field.Name)
+			}
+			if n := len(request.Bounds); n > 0 && b <= request.Bounds[n-1] {
+				return nil, queryErrorf("range bounds of %s must be increasing", field.Name)
+			}
+			request.Bounds = append(request.Bounds, b)
+		}
+		if len(request.Bounds) < 2 {
+			return nil, queryErrorf("%s facet needs at least two bounds", field.Name)
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// CountFacets computes facets over items. Both backends use it, so the counts
+// only depend on the items matched.
+func CountFacets(items []models.GroceryItem, requests []FacetRequest) []FacetResult {
+	results := make([]FacetResult, 0, len(requests))
+	for _, request := range requests {
+		if len(request.Bounds) > 0 {
+			results = append(results, countRanges(items, request))
+		} else {
+			results = append(results, countTerms(items, request))
+		}
+	}
+	return results
+}
+
+func countTerms(items []models.GroceryItem, request FacetRequest) FacetResult {
+	counts := map[string]int{}
+	for _, item := range items {
+		switch value := request.Field.Value(item).(type) {
+		case []string:
+			for _, element := range value {
+				counts[element]++
+			}
+		case string:
+			if value != "" {
+				counts[value]++
+			}
+		case nil:
+		default:
+			counts[fmt.Sprint(value)]++
+		}
+	}
+
+	values := []FacetValue{}
+	for value, count := range counts {
+		values = append(values, FacetValue{Value: value, Count: count})
+	}
+	return FacetResult{Field: request.Field.Name, Values: rankFacetValues(values)}
+}
+
+// rankFacetValues orders the values of a terms facet, largest first, and
+// keeps the first maxFacetValues
+func rankFacetValues(values []FacetValue) []FacetValue {
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	if len(values) > maxFacetValues {
+		values = values[:maxFacetValues]
+	}
+	return values
+}
+
+func countRanges(items []models.GroceryItem, request FacetRequest) FacetResult {
+	bounds := request.Bounds
+	counts := make([]int, len(bounds))
+	for _, item := range items {
+		raw := request.Field.Value(item)
+		if raw == nil {
+			continue
+		}
+		value := toFloat(raw)
+		if value < bounds[0] {
+			continue
+		}
+		// index of the last bound <= value
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] > value }) - 1
+		counts[i]++
+	}
+
+	result := FacetResult{Field: request.Field.Name, Values: make([]FacetValue, len(bounds))}
+	for i := range bounds {
+		result.Values[i] = FacetValue{Value: rangeLabel(bounds, i), Count: counts[i]}
+	}
+	return result
+}
+
+// rangeLabel names the range starting at bounds[i], e.g. 50-100, or 500+ for
+// the last one
+func rangeLabel(bounds []float64, i int) string {
+	if i == len(bounds)-1 {
+		return formatBound(bounds[i]) + "+"
+	}
+	return formatBound(bounds[i]) + "-" + formatBound(bounds[i+1])
+}
+
+func formatBound(b float64) string {
+	return strconv.FormatFloat(b, 'f', -1, 64)
+}
+
+// facetPaths are the Firestore fields needed to compute requests
+func facetPaths(requests []FacetRequest) []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, request := range requests {
+		for _, path := range request.Field.paths() {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// FacetCache keeps recently computed facets so hot listings don't recount the
+// catalog on every request. Entries expire after ttl, and Invalidate drops them
+// all when the catalog changes.
+type FacetCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]facetCacheEntry
+	generation int // bumped by Invalidate, so counts started before it aren't cached
+}
+
+type facetCacheEntry struct {
+	results []FacetResult
+	expires time.Time
+}
+
+func NewFacetCache(ttl time.Duration, maxEntries int) *FacetCache {
+	return &FacetCache{ttl: ttl, maxEntries: maxEntries, entries: map[string]facetCacheEntry{}}
+}
+
+// Facets returns the facets of the items matching filters, from the cache
+// when possible
+func (c *FacetCache) Facets(ctx context.Context, repo GroceryItemRepository, filters []Filter, requests []FacetRequest) ([]FacetResult, error) {
+	key := facetCacheKey(filters, requests)
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.results, nil
+	}
+
+	results, err := repo.Facets(ctx, filters, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return results, nil
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = facetCacheEntry{results: results, expires: now.Add(c.ttl)}
+	return results, nil
+}
+
+// Invalidate drops every cached entry
+func (c *FacetCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]facetCacheEntry{}
+	c.generation++
+}
+
+// evict drops expired entries, or the one closest to expiring when none has
+// expired yet
+func (c *FacetCache) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expires.Before(oldest) {
+			oldestKey, oldest = key, entry.expires
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
+
+func facetCacheKey(filters []Filter, requests []FacetRequest) string {
+	var b strings.Builder
+	b.WriteString(filtersKey(filters))
+	b.WriteString("|")
+	for _, request := range requests {
+		fmt.Fprintf(&b, "%s:%v;", request.Field.Name, request.Bounds)
+	}
+	return b.String()
+}
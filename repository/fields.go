@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"strings"
+
+	"example.com/capstone/models"
+)
+
+// Kind is the type of a queryable field
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindNumber
+	KindInteger
+	KindBool
+	KindStringList
+	KindOther // dates, URLs and other fields that are only returned, never queried
+)
+
+// Field is a grocery item field that can be used in queries. Only the fields
+// listed in fields can be queried, anything else is rejected.
+type Field struct {
+	Name  string // name used in the API, same as the json name
+	Path  string // field path in Firestore, empty for fields derived from others
+	Kind  Kind
+	value func(item models.GroceryItem) interface{}
+}
+
+// Value returns the value of the field on item. Numbers are returned as
+// float64 so they compare the same way whatever their Kind.
+func (f Field) Value(item models.GroceryItem) interface{} {
+	return f.value(item)
+}
+
+var fields = []Field{
+	{"id", "ID", KindInteger, func(i models.GroceryItem) interface{} { return float64(i.ID) }},
+	{"productName", "ProductName", KindString, func(i models.GroceryItem) interface{} { return i.ProductName }},
+	{"category", "Category", KindString, func(i models.GroceryItem) interface{} { return i.Category }},
+	{"price", "Price", KindNumber, func(i models.GroceryItem) interface{} { return i.Price }},
+	{"weight", "Weight", KindNumber, func(i models.GroceryItem) interface{} { return i.Weight }},
+	{"weightUnit", "WeightUnit", KindString, func(i models.GroceryItem) interface{} { return i.WeightUnit }},
+	{"vegetarian", "Vegetarian", KindBool, func(i models.GroceryItem) interface{} { return i.Vegetarian }},
+	{"manufacturer", "Manufacturer", KindString, func(i models.GroceryItem) interface{} { return i.Manufacturer }},
+	{"brand", "Brand", KindString, func(i models.GroceryItem) interface{} { return i.Brand }},
+	{"itemPackageQuantity", "ItemPackageQuantity", KindInteger, func(i models.GroceryItem) interface{} { return float64(i.ItemPackageQuantity) }},
+	{"packageInformation", "PackageInformation", KindString, func(i models.GroceryItem) interface{} { return i.PackageInformation }},
+	{"countryOfOrigin", "CountryOfOrigin", KindString, func(i models.GroceryItem) interface{} { return i.CountryOfOrigin }},
+	{"stock", "Stock", KindInteger, stockValue},
+	{"barcode", "Barcode", KindString, func(i models.GroceryItem) interface{} { return i.Barcode }},
+	{"tags", "Tags", KindStringList, func(i models.GroceryItem) interface{} { return i.Tags }},
+}
+
+// stockValue is nil for items whose stock isn't tracked, which filters don't
+// match, like Firestore leaves out documents without the field
+func stockValue(i models.GroceryItem) interface{} {
+	if i.Stock == nil {
+		return nil
+	}
+	return float64(*i.Stock)
+}
+
+// derivedFields are computed from the stored fields. The memory backend sorts
+// on them directly. Firestore orders by their denormalizedPaths and CheckQuery
+// rejects sorting on one without a denormalized path.
+var derivedFields = []Field{
+	{"unitPrice", "", KindNumber, func(i models.GroceryItem) interface{} { return UnitPrice(i) }},
+}
+
+// derivedPaths are the stored fields each derived field is computed from
+var derivedPaths = map[string][]string{
+	"unitPrice": {"Price", "Weight", "WeightUnit"},
+}
+
+// denormalizedPaths are the Firestore fields derived fields are also stored
+// in, kept up to date on every write, so Firestore can order by them
+var denormalizedPaths = map[string]string{
+	"unitPrice": "UnitPrice",
+}
+
+// orderPath is the Firestore field to order by f, empty when there is none
+func (f Field) orderPath() string {
+	if f.Path == "" {
+		return denormalizedPaths[f.Name]
+	}
+	return f.Path
+}
+
+// paths are the Firestore fields needed to know the value of f
+func (f Field) paths() []string {
+	if f.Path == "" {
+		return derivedPaths[f.Name]
+	}
+	return []string{f.Path}
+}
+
+// weightUnits converts weight units to kilograms or litres
+var weightUnits = map[string]float64{
+	"mg": 0.000001,
+	"g":  0.001,
+	"gm": 0.001,
+	"kg": 1,
+	"ml": 0.001,
+	"l":  1,
+}
+
+// UnitPrice is the price per kilogram or litre. Items with an unknown weight
+// unit are priced per unit of their own weight, items without a weight at
+// their price.
+func UnitPrice(item models.GroceryItem) float64 {
+	if item.Weight <= 0 {
+		return item.Price
+	}
+	factor, ok := weightUnits[strings.ToLower(strings.TrimSpace(item.WeightUnit))]
+	if !ok {
+		factor = 1
+	}
+	return item.Price / (item.Weight * factor)
+}
+
+// LookupField finds a queryable field by its API name. The lookup ignores case
+// so the Firestore names used by older clients (Category, ProductName) still work.
+func LookupField(name string) (Field, bool) {
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// LookupSortField finds a field that can be sorted on, stored or derived
+func LookupSortField(name string) (Field, bool) {
+	if f, ok := LookupField(name); ok {
+		return f, f.Kind != KindStringList
+	}
+	for _, f := range derivedFields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
@@ -0,0 +1,792 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"example.com/capstone/models"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	groceryItemsCollection   = "groceryItems"
+	priceSchedulesCollection = "priceSchedules"
+)
+
+// idCounterDoc holds the next free item ID, in the counters collection
+const idCounterDoc = "groceryItems"
+
+// maxBatchWrites is the most writes Firestore accepts in one batch
+const maxBatchWrites = 500
+
+// maxFacetScan bounds the documents read to count the facets that can't be
+// counted with aggregation queries
+const maxFacetScan = 5000
+
+// maxConcurrentCounts is the most aggregation queries run at a time by facets
+// and statistics
+const maxConcurrentCounts = 8
+
+// firestoreOperators maps filter operators to Firestore operators, list
+// fields use the array variants instead
+var firestoreOperators = map[Operator]string{
+	OpEq:  "==",
+	OpNe:  "!=",
+	OpGt:  ">",
+	OpGte: ">=",
+	OpLt:  "<",
+	OpLte: "<=",
+	OpIn:  "in",
+}
+
+// FirestoreRepository reads grocery items from the groceryItems collection.
+// Range filters on more than one field need a composite index in Firestore.
+type FirestoreRepository struct {
+	client *firestore.Client
+	writes WriteOptions
+}
+
+// NewFirestoreRepository takes ownership of client, Close closes it
+func NewFirestoreRepository(client *firestore.Client) *FirestoreRepository {
+	return &FirestoreRepository{client: client, writes: DefaultWriteOptions}
+}
+
+// SetWriteOptions sets how CreateItems and UpdateItems write in bulk
+func (r *FirestoreRepository) SetWriteOptions(opts WriteOptions) {
+	r.writes = opts
+}
+
+// List runs q in Firestore, so only the requested page is read. Orders
+// Firestore can't produce are rejected by CheckQuery rather than sorted in
+// memory, which would read every matching document for each page.
+func (r *FirestoreRepository) List(ctx context.Context, q Query) ([]models.GroceryItem, error) {
+	if err := r.CheckQuery(q); err != nil {
+		return nil, err
+	}
+
+	keys := effectiveSort(q)
+	query := r.compile(q.Filters)
+	if len(q.Fields) > 0 {
+		query = query.Select(selectPaths(q.Fields, keys)...)
+	}
+
+	for _, key := range keys {
+		dir := firestore.Asc
+		if key.Desc {
+			dir = firestore.Desc
+		}
+		query = query.OrderBy(key.Field.orderPath(), dir)
+	}
+	if len(q.After) > 0 {
+		query = query.StartAfter(q.After...)
+	}
+	if q.Offset > 0 {
+		query = query.Offset(q.Offset)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+	return readItems(query.Documents(ctx))
+}
+
+// Count counts the items matching filters with an aggregation query, without
+// reading the documents
+func (r *FirestoreRepository) Count(ctx context.Context, filters []Filter) (int64, error) {
+	return countQuery(ctx, r.compile(filters))
+}
+
+// countQuery counts the documents matching query with an aggregation query
+func countQuery(ctx context.Context, query firestore.Query) (int64, error) {
+	result, err := query.NewAggregationQuery().WithCount("total").Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	total, ok := result["total"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count result %v", result["total"])
+	}
+	return total.GetIntegerValue(), nil
+}
+
+// Facets counts range facets, and facets of yes/no fields, with a count
+// aggregation query per bucket, all run at the same time, so no document is
+// read. The values of other fields aren't known in advance, their facets are
+// counted over the first maxFacetScan matching documents, projected to the
+// faceted fields, and flagged partial when more documents match. A range
+// facet is scanned as well when a range filter is on another field, since a
+// query can't have range filters on two fields.
+func (r *FirestoreRepository) Facets(ctx context.Context, filters []Filter, requests []FacetRequest) ([]FacetResult, error) {
+	results := make([]FacetResult, len(requests))
+	var scanned []int
+
+	type bucketCount struct {
+		request, bucket int
+		query           firestore.Query
+	}
+	var counts []bucketCount
+	buckets := make([][]FacetValue, len(requests))
+	for i, request := range requests {
+		queries, labels, ok := r.facetBuckets(filters, request)
+		if !ok {
+			scanned = append(scanned, i)
+			continue
+		}
+		buckets[i] = make([]FacetValue, len(labels))
+		for b := range queries {
+			buckets[i][b].Value = labels[b]
+			counts = append(counts, bucketCount{i, b, queries[b]})
+		}
+	}
+
+	counted := make(chan error, 1)
+	go func() {
+		counted <- runConcurrently(len(counts), func(c int) error {
+			n, err := countQuery(ctx, counts[c].query)
+			buckets[counts[c].request][counts[c].bucket].Count = int(n)
+			return err
+		})
+	}()
+
+	if len(scanned) > 0 {
+		scannedRequests := make([]FacetRequest, len(scanned))
+		for j, i := range scanned {
+			scannedRequests[j] = requests[i]
+		}
+		query := r.compile(filters).Select(facetPaths(scannedRequests)...).Limit(maxFacetScan + 1)
+		items, err := readItems(query.Documents(ctx))
+		if err != nil {
+			<-counted
+			return nil, err
+		}
+		partial := len(items) > maxFacetScan
+		if partial {
+			items = items[:maxFacetScan]
+		}
+		for j, result := range CountFacets(items, scannedRequests) {
+			result.Partial = partial
+			results[scanned[j]] = result
+		}
+	}
+
+	if err := <-counted; err != nil {
+		return nil, err
+	}
+
+	for i, request := range requests {
+		if buckets[i] == nil {
+			continue
+		}
+		values := buckets[i]
+		if len(request.Bounds) == 0 {
+			// like counted terms, values no item has are left out
+			values = []FacetValue{}
+			for _, value := range buckets[i] {
+				if value.Count > 0 {
+					values = append(values, value)
+				}
+			}
+			values = rankFacetValues(values)
+		}
+		results[i] = FacetResult{Field: request.Field.Name, Values: values}
+	}
+	return results, nil
+}
+
+// facetBuckets returns a query matching the items of every bucket of request,
+// with its label, or false when the buckets can't be queried
+func (r *FirestoreRepository) facetBuckets(filters []Filter, request FacetRequest) ([]firestore.Query, []string, bool) {
+	path := request.Field.orderPath()
+	if path == "" {
+		return nil, nil, false
+	}
+	base := r.compile(filters)
+
+	if len(request.Bounds) == 0 {
+		if request.Field.Kind != KindBool {
+			return nil, nil, false
+		}
+		return []firestore.Query{base.Where(path, "==", true), base.Where(path, "==", false)}, []string{"true", "false"}, true
+	}
+
+	for _, field := range inequalityFields(filters) {
+		if field.Name != request.Field.Name {
+			return nil, nil, false
+		}
+	}
+	bounds := request.Bounds
+	queries := make([]firestore.Query, len(bounds))
+	labels := make([]string, len(bounds))
+	for i := range bounds {
+		queries[i] = base.Where(path, ">=", bounds[i])
+		if i < len(bounds)-1 {
+			queries[i] = queries[i].Where(path, "<", bounds[i+1])
+		}
+		labels[i] = rangeLabel(bounds, i)
+	}
+	return queries, labels, true
+}
+
+// PriceStats computes the statistics of all matching items and of every group
+// with queries rather than by reading the matching documents. Count and
+// average are aggregations, min and max read the cheapest and the dearest
+// item, and the median reads the middle one or two, which Firestore bills as
+// reads of the items skipped to reach them as well. Range filters can only be
+// on price, since the items are ordered by price.
+//
+// Finding the groups reads one document per group of the matching items, or
+// of the whole catalog with a price range filter, as Firestore can't order by
+// the group first then. At most maxStatsGroups groups are computed.
+func (r *FirestoreRepository) PriceStats(ctx context.Context, filters []Filter, groupBy Field) (PriceStatsResult, error) {
+	inequalities := inequalityFields(filters)
+	for _, field := range inequalities {
+		if field.Name != "price" {
+			return PriceStatsResult{}, queryErrorf("price statistics can only have range filters on price, not on %s", field.Name)
+		}
+	}
+
+	base := r.compile(filters)
+	groupsOf := base
+	if len(inequalities) > 0 {
+		groupsOf = r.client.Collection(groceryItemsCollection).Query
+	}
+	groups, err := distinctValues(ctx, groupsOf, maxStatsGroups+1, groupBy.Path)
+	if err != nil {
+		return PriceStatsResult{}, err
+	}
+	truncated := len(groups) > maxStatsGroups
+	if truncated {
+		groups = groups[:maxStatsGroups]
+	}
+
+	stats := make([]PriceStats, len(groups)+1)
+	err = runConcurrently(len(stats), func(i int) error {
+		var err error
+		if i == 0 {
+			stats[i], err = priceStatsOf(ctx, base, "")
+		} else {
+			value := groups[i-1][0]
+			stats[i], err = priceStatsOf(ctx, base.Where(groupBy.Path, "==", value), fmt.Sprint(value))
+		}
+		return err
+	})
+	if err != nil {
+		return PriceStatsResult{}, err
+	}
+
+	result := PriceStatsResult{GroupBy: groupBy.Name, Overall: stats[0], Groups: []PriceStats{}, Truncated: truncated}
+	for _, group := range stats[1:] {
+		if group.Count > 0 {
+			result.Groups = append(result.Groups, group)
+		}
+	}
+	sortPriceGroups(result.Groups)
+	return result, nil
+}
+
+// priceStatsOf computes the price statistics of the documents matching query
+func priceStatsOf(ctx context.Context, query firestore.Query, group string) (PriceStats, error) {
+	stats := PriceStats{Group: group}
+	result, err := query.NewAggregationQuery().WithCount("count").WithAvg("Price", "avg").Get(ctx)
+	if err != nil {
+		return stats, err
+	}
+	count, ok := result["count"].(*firestorepb.Value)
+	if !ok {
+		return stats, fmt.Errorf("unexpected count result %v", result["count"])
+	}
+	stats.Count = int(count.GetIntegerValue())
+	if stats.Count == 0 {
+		return stats, nil
+	}
+	avg, ok := result["avg"].(*firestorepb.Value)
+	if !ok {
+		return stats, fmt.Errorf("unexpected average result %v", result["avg"])
+	}
+	stats.Avg = RoundPrice(avg.GetDoubleValue())
+
+	byPrice := query.Select("Price").OrderBy("Price", firestore.Asc)
+	cheapest, err := readItems(byPrice.Limit(1).Documents(ctx))
+	if err != nil {
+		return stats, err
+	}
+	dearest, err := readItems(query.Select("Price").OrderBy("Price", firestore.Desc).Limit(1).Documents(ctx))
+	if err != nil {
+		return stats, err
+	}
+	middle, err := readItems(byPrice.Offset((stats.Count - 1) / 2).Limit(2 - stats.Count%2).Documents(ctx))
+	if err != nil {
+		return stats, err
+	}
+	// the items may have changed between the queries
+	if len(cheapest) == 0 || len(dearest) == 0 || len(middle) == 0 {
+		return stats, fmt.Errorf("items of %q changed while computing their price statistics", group)
+	}
+
+	stats.Min = cheapest[0].Price
+	stats.Max = dearest[0].Price
+	stats.Median = middle[0].Price
+	if len(middle) == 2 {
+		stats.Median = RoundPrice((middle[0].Price + middle[1].Price) / 2)
+	}
+	return stats, nil
+}
+
+// ExpiryMonths counts the matching documents of every expiry month with an
+// aggregation query. Finding the months reads one document per expiry month
+// of the whole catalog.
+func (r *FirestoreRepository) ExpiryMonths(ctx context.Context, filters []Filter) ([]MonthCount, error) {
+	months, err := distinctValues(ctx, r.client.Collection(groceryItemsCollection).Query, 0, "ExpDate.Year", "ExpDate.Month")
+	if err != nil {
+		return nil, err
+	}
+
+	base := r.compile(filters)
+	counts := make([]MonthCount, len(months))
+	err = runConcurrently(len(months), func(i int) error {
+		year, month := months[i][0], months[i][1]
+		counts[i].Month = fmt.Sprintf("%04d-%02d", year, month)
+		if month == int64(0) {
+			// no expiry date
+			return nil
+		}
+		n, err := countQuery(ctx, base.Where("ExpDate.Year", "==", year).Where("ExpDate.Month", "==", month))
+		counts[i].Count = int(n)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// months are in order already
+	result := []MonthCount{}
+	for _, count := range counts {
+		if count.Count > 0 {
+			result = append(result, count)
+		}
+	}
+	return result, nil
+}
+
+// distinctValues returns the distinct values of paths among the documents of
+// query, in order, at most limit of them unless limit is 0. Each query reads
+// the first document after the previous values, so it reads one document per
+// distinct value.
+func distinctValues(ctx context.Context, query firestore.Query, limit int, paths ...string) ([][]interface{}, error) {
+	query = query.Select(paths...)
+	for _, path := range paths {
+		query = query.OrderBy(path, firestore.Asc)
+	}
+
+	var values [][]interface{}
+	for limit == 0 || len(values) < limit {
+		next := query.Limit(1)
+		if len(values) > 0 {
+			next = next.StartAfter(values[len(values)-1]...)
+		}
+		docs, err := next.Documents(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) == 0 {
+			return values, nil
+		}
+
+		value := make([]interface{}, len(paths))
+		for i, path := range paths {
+			if value[i], err = docs[0].DataAt(path); err != nil {
+				return nil, err
+			}
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// runConcurrently calls run for 0 to n-1, maxConcurrentCounts at a time, and
+// returns the first error
+func runConcurrently(n int, run func(i int) error) error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentCounts)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer func() { <-slots; wg.Done() }()
+			errs[i] = run(i)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *FirestoreRepository) Get(ctx context.Context, id int, fields ...Field) (models.GroceryItem, error) {
+	query := r.client.Collection(groceryItemsCollection).Where("ID", "==", id).Limit(1)
+	if len(fields) > 0 {
+		query = query.Select(selectPaths(fields, nil)...)
+	}
+
+	items, err := readItems(query.Documents(ctx))
+	if err != nil {
+		return models.GroceryItem{}, err
+	}
+	if len(items) == 0 {
+		return models.GroceryItem{}, ErrNotFound
+	}
+	return items[0], nil
+}
+
+// GetMany returns the items with the given IDs that exist, in no particular
+// order. Documents aren't keyed by item ID, so it runs one in query per
+// maxInValues IDs, all at the same time.
+func (r *FirestoreRepository) GetMany(ctx context.Context, ids []int, fields ...Field) ([]models.GroceryItem, error) {
+	var chunks [][]int
+	for start := 0; start < len(ids); start += maxInValues {
+		chunks = append(chunks, ids[start:min(start+maxInValues, len(ids))])
+	}
+
+	results := make([][]models.GroceryItem, len(chunks))
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []int) {
+			defer wg.Done()
+			query := r.client.Collection(groceryItemsCollection).Where("ID", "in", chunk)
+			if len(fields) > 0 {
+				query = query.Select(selectPaths(fields, nil)...)
+			}
+			results[i], errs[i] = readItems(query.Documents(ctx))
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	items := []models.GroceryItem{}
+	for i := range chunks {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		items = append(items, results[i]...)
+	}
+	return items, nil
+}
+
+func (r *FirestoreRepository) PriceChanges(ctx context.Context, itemID int) ([]models.PriceChange, error) {
+	iter := r.client.Collection(priceSchedulesCollection).Where("ItemID", "==", itemID).Documents(ctx)
+	defer iter.Stop()
+
+	var changes []models.PriceChange
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var change models.PriceChange
+		if err := doc.DataTo(&change); err != nil {
+			return nil, err
+		}
+		change.ID = doc.Ref.ID
+		changes = append(changes, change)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].EffectiveFrom.Before(changes[j].EffectiveFrom)
+	})
+	return changes, nil
+}
+
+// AllocateIDs reserves IDs with a counter document, in a transaction. The
+// counter never goes below the highest ID stored, so items created without it
+// don't get their IDs handed out again.
+func (r *FirestoreRepository) AllocateIDs(ctx context.Context, n int) (int, error) {
+	counterRef := r.client.Collection("counters").Doc(idCounterDoc)
+	highest := r.client.Collection(groceryItemsCollection).OrderBy("ID", firestore.Desc).Limit(1)
+
+	var first int
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		first = 1
+
+		doc, err := tx.Get(counterRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if next, err := doc.DataAt("Next"); err == nil {
+				if next, ok := next.(int64); ok {
+					first = int(next)
+				}
+			}
+		}
+
+		docs, err := tx.Documents(highest).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(docs) > 0 {
+			if id, err := docs[0].DataAt("ID"); err == nil {
+				if id, ok := id.(int64); ok {
+					first = max(first, int(id)+1)
+				}
+			}
+		}
+
+		return tx.Set(counterRef, map[string]interface{}{"Next": first + n})
+	})
+	return first, err
+}
+
+// CreateItems writes the items as new documents, in batches committed a few
+// at a time and retried on transient errors. The documents are named before
+// the first commit, so a retried batch that turns out to have been written
+// already is told apart from a conflict.
+func (r *FirestoreRepository) CreateItems(ctx context.Context, items []models.GroceryItem) []error {
+	errs := make([]error, len(items))
+	collection := r.client.Collection(groceryItemsCollection)
+	refs := make([]*firestore.DocumentRef, len(items))
+	for i := range items {
+		refs[i] = collection.NewDoc()
+	}
+
+	writeBatches(ctx, sequence(len(items)), errs, r.writes, func(ctx context.Context, batch []int, attempt int) error {
+		writes := r.client.Batch()
+		for _, i := range batch {
+			writes.Create(refs[i], StoredItem(items[i]))
+		}
+		_, err := writes.Commit(ctx)
+		if attempt > 1 && status.Code(err) == codes.AlreadyExists {
+			return nil
+		}
+		return err
+	})
+	return errs
+}
+
+// UpdateItems finds the documents of the items, maxInValues IDs per query, and
+// overwrites them in batches committed a few at a time
+func (r *FirestoreRepository) UpdateItems(ctx context.Context, items []models.GroceryItem) []error {
+	errs := make([]error, len(items))
+	collection := r.client.Collection(groceryItemsCollection)
+
+	refs := map[int]*firestore.DocumentRef{}
+	for start := 0; start < len(items); start += maxInValues {
+		end := min(start+maxInValues, len(items))
+		ids := make([]int, 0, end-start)
+		for _, item := range items[start:end] {
+			ids = append(ids, item.ID)
+		}
+
+		docs, err := collection.Where("ID", "in", ids).Select("ID").Documents(ctx).GetAll()
+		if err != nil {
+			for i := start; i < end; i++ {
+				errs[i] = err
+			}
+			continue
+		}
+		for _, doc := range docs {
+			if id, err := doc.DataAt("ID"); err == nil {
+				if id, ok := id.(int64); ok {
+					refs[int(id)] = doc.Ref
+				}
+			}
+		}
+	}
+
+	var found []int
+	for i, item := range items {
+		if errs[i] != nil {
+			continue
+		}
+		if _, ok := refs[item.ID]; !ok {
+			errs[i] = ErrNotFound
+			continue
+		}
+		found = append(found, i)
+	}
+
+	writeBatches(ctx, found, errs, r.writes, func(ctx context.Context, batch []int, attempt int) error {
+		writes := r.client.Batch()
+		for _, i := range batch {
+			writes.Set(refs[items[i].ID], StoredItem(items[i]))
+		}
+		_, err := writes.Commit(ctx)
+		return err
+	})
+	return errs
+}
+
+func (r *FirestoreRepository) Close() error {
+	return r.client.Close()
+}
+
+// CheckQuery returns a *QueryError when Firestore can't order the results of
+// q. Derived fields can only be ordered by when they are stored as well, and
+// Firestore needs the first order by to be on the field with an inequality
+// filter, supporting a single such field here.
+//
+// Firestore leaves out documents that don't have an order by field at all, so
+// documents written before a field was added have to be backfilled to show up
+// in listings sorted by it, see BackfillUnitPrices.
+func (r *FirestoreRepository) CheckQuery(q Query) error {
+	keys := effectiveSort(q)
+	for _, key := range keys {
+		if key.Field.orderPath() == "" {
+			return queryErrorf("can't sort by %s", key.Field.Name)
+		}
+	}
+
+	inequalities := inequalityFields(q.Filters)
+	switch {
+	case len(inequalities) > 1:
+		return queryErrorf("range filters can only be on one field, got %s and %s", inequalities[0].Name, inequalities[1].Name)
+	case len(inequalities) == 1 && keys[0].Field.Name != inequalities[0].Name:
+		return queryErrorf("with a range filter on %s the sort has to start with %s", inequalities[0].Name, inequalities[0].Name)
+	}
+	return nil
+}
+
+// storedItem is an item as written to Firestore, along with its denormalized
+// fields
+type storedItem struct {
+	models.GroceryItem
+	UnitPrice float64
+}
+
+// StoredItem returns item as it has to be written to the groceryItems
+// collection, for writes that don't go through the repository
+func StoredItem(item models.GroceryItem) interface{} {
+	return storedItem{GroceryItem: item, UnitPrice: UnitPrice(item)}
+}
+
+// BackfillUnitPrices stores the unit price of the items written before it was
+// stored or with a stale one. It reads the whole collection, so it is meant to
+// run once after deploying, and returns how many items were updated.
+func (r *FirestoreRepository) BackfillUnitPrices(ctx context.Context) (int, error) {
+	iter := r.client.Collection(groceryItemsCollection).Select("Price", "Weight", "WeightUnit", "UnitPrice").Documents(ctx)
+	defer iter.Stop()
+
+	var refs []*firestore.DocumentRef
+	var prices []float64
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		var item storedItem
+		if err := doc.DataTo(&item); err != nil {
+			return 0, err
+		}
+		stored, err := doc.DataAt("UnitPrice")
+		if price := UnitPrice(item.GroceryItem); err != nil || stored != price {
+			refs = append(refs, doc.Ref)
+			prices = append(prices, price)
+		}
+	}
+
+	errs := make([]error, len(refs))
+	writeBatches(ctx, sequence(len(refs)), errs, r.writes, func(ctx context.Context, batch []int, attempt int) error {
+		writes := r.client.Batch()
+		for _, i := range batch {
+			writes.Update(refs[i], []firestore.Update{{Path: "UnitPrice", Value: prices[i]}})
+		}
+		_, err := writes.Commit(ctx)
+		return err
+	})
+	updated := 0
+	for _, err := range errs {
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// CheckQuery returns the *QueryError the repository would fail q with, for
+// callers that have to know before they start answering
+func CheckQuery(repo GroceryItemRepository, q Query) error {
+	if checker, ok := repo.(interface{ CheckQuery(Query) error }); ok {
+		return checker.CheckQuery(q)
+	}
+	return nil
+}
+
+// selectPaths are the Firestore fields to read for a projection: the
+// projected fields, the fields needed to sort and page, and ID
+func selectPaths(fields []Field, keys []SortKey) []string {
+	paths := []string{"ID"}
+	seen := map[string]bool{"ID": true}
+	add := func(f Field) {
+		for _, path := range f.paths() {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		add(f)
+	}
+	for _, key := range keys {
+		add(key.Field)
+	}
+	return paths
+}
+
+// compile turns filters into a Firestore query
+func (r *FirestoreRepository) compile(filters []Filter) firestore.Query {
+	query := r.client.Collection(groceryItemsCollection).Query
+	for _, f := range filters {
+		op := firestoreOperators[f.Op]
+		var value interface{} = f.Values
+		if f.Field.Kind == KindStringList {
+			op = "array-contains"
+			if f.Op == OpIn {
+				op = "array-contains-any"
+			}
+		}
+		if op != "in" && op != "array-contains-any" {
+			value = f.Values[0]
+		}
+		query = query.Where(f.Field.Path, op, value)
+	}
+	return query
+}
+
+func readItems(iter *firestore.DocumentIterator) ([]models.GroceryItem, error) {
+	defer iter.Stop()
+
+	items := []models.GroceryItem{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var item models.GroceryItem
+		if err := doc.DataTo(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/capstone/models"
+)
+
+// MemoryRepository keeps the catalog in memory. It is used for local
+// development and answers queries with the same semantics as Firestore.
+type MemoryRepository struct {
+	mu           sync.RWMutex
+	items        map[int]models.GroceryItem
+	priceChanges map[int][]models.PriceChange
+	nextID       int
+
+	writes   WriteOptions
+	latency  time.Duration // added to every commit
+	perWrite time.Duration // added to a commit for each of its items
+}
+
+func NewMemoryRepository(items ...models.GroceryItem) *MemoryRepository {
+	r := &MemoryRepository{
+		items:        make(map[int]models.GroceryItem, len(items)),
+		priceChanges: map[int][]models.PriceChange{},
+		writes:       DefaultWriteOptions,
+	}
+	for _, item := range items {
+		r.items[item.ID] = item
+	}
+	return r
+}
+
+// SetWriteOptions sets how CreateItems and UpdateItems write in bulk
+func (r *MemoryRepository) SetWriteOptions(opts WriteOptions) {
+	r.writes = opts
+}
+
+// SetCommitLatency makes every batch commit take latency plus perWrite for each
+// of its items, standing in for Firestore, e.g. when measuring bulk writes
+func (r *MemoryRepository) SetCommitLatency(latency, perWrite time.Duration) {
+	r.latency, r.perWrite = latency, perWrite
+}
+
+// Put stores item, replacing the item with the same ID
+func (r *MemoryRepository) Put(item models.GroceryItem) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[item.ID] = item
+}
+
+// PutPriceChange records a price change of an item
+func (r *MemoryRepository) PutPriceChange(change models.PriceChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changes := append(r.priceChanges[change.ItemID], change)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].EffectiveFrom.Before(changes[j].EffectiveFrom)
+	})
+	r.priceChanges[change.ItemID] = changes
+}
+
+// Delete removes the item with the given ID
+func (r *MemoryRepository) Delete(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.items, id)
+}
+
+func (r *MemoryRepository) List(ctx context.Context, q Query) ([]models.GroceryItem, error) {
+	r.mu.RLock()
+	items := []models.GroceryItem{}
+	for _, item := range r.items {
+		if Matches(item, q.Filters) {
+			items = append(items, item)
+		}
+	}
+	r.mu.RUnlock()
+
+	return applyQuery(items, q), nil
+}
+
+func (r *MemoryRepository) Count(ctx context.Context, filters []Filter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, item := range r.items {
+		if Matches(item, filters) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryRepository) Facets(ctx context.Context, filters []Filter, requests []FacetRequest) ([]FacetResult, error) {
+	items := r.matching(filters)
+	return CountFacets(items, requests), nil
+}
+
+func (r *MemoryRepository) PriceStats(ctx context.Context, filters []Filter, groupBy Field) (PriceStatsResult, error) {
+	return ComputePriceStats(r.matching(filters), groupBy), nil
+}
+
+func (r *MemoryRepository) ExpiryMonths(ctx context.Context, filters []Filter) ([]MonthCount, error) {
+	return CountExpiryMonths(r.matching(filters)), nil
+}
+
+// Get returns the whole item, fields only matter to backends that pay for reads
+func (r *MemoryRepository) Get(ctx context.Context, id int, fields ...Field) (models.GroceryItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	item, ok := r.items[id]
+	if !ok {
+		return models.GroceryItem{}, ErrNotFound
+	}
+	return item, nil
+}
+
+// GetMany returns the items with the given IDs that exist, in no particular order
+func (r *MemoryRepository) GetMany(ctx context.Context, ids []int, fields ...Field) ([]models.GroceryItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	items := []models.GroceryItem{}
+	for _, id := range ids {
+		if item, ok := r.items[id]; ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (r *MemoryRepository) PriceChanges(ctx context.Context, itemID int) ([]models.PriceChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]models.PriceChange(nil), r.priceChanges[itemID]...), nil
+}
+
+// AllocateIDs hands out IDs above every item stored or allocated before
+func (r *MemoryRepository) AllocateIDs(ctx context.Context, n int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id := range r.items {
+		r.nextID = max(r.nextID, id+1)
+	}
+	r.nextID = max(r.nextID, 1)
+
+	first := r.nextID
+	r.nextID += n
+	return first, nil
+}
+
+// CreateItems stores the items in batches like FirestoreRepository, a batch
+// is stored whole or not at all
+func (r *MemoryRepository) CreateItems(ctx context.Context, items []models.GroceryItem) []error {
+	errs := make([]error, len(items))
+	writeBatches(ctx, sequence(len(items)), errs, r.writes, func(ctx context.Context, batch []int, attempt int) error {
+		return r.commit(ctx, items, batch, func(id int, exists bool) error {
+			if exists {
+				return fmt.Errorf("item %d already exists", id)
+			}
+			return nil
+		})
+	})
+	return errs
+}
+
+// UpdateItems replaces the items in batches like FirestoreRepository
+func (r *MemoryRepository) UpdateItems(ctx context.Context, items []models.GroceryItem) []error {
+	errs := make([]error, len(items))
+	writeBatches(ctx, sequence(len(items)), errs, r.writes, func(ctx context.Context, batch []int, attempt int) error {
+		return r.commit(ctx, items, batch, func(id int, exists bool) error {
+			if !exists {
+				return ErrNotFound
+			}
+			return nil
+		})
+	})
+	return errs
+}
+
+// commit stores the items at batch once check passes for all of them, exists
+// telling whether an item with the ID is stored or earlier in the batch
+func (r *MemoryRepository) commit(ctx context.Context, items []models.GroceryItem, batch []int, check func(id int, exists bool) error) error {
+	if wait := r.latency + time.Duration(len(batch))*r.perWrite; wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[int]bool, len(batch))
+	for _, i := range batch {
+		_, exists := r.items[items[i].ID]
+		if err := check(items[i].ID, exists || seen[items[i].ID]); err != nil {
+			return err
+		}
+		seen[items[i].ID] = true
+	}
+	for _, i := range batch {
+		r.items[items[i].ID] = items[i]
+	}
+	return nil
+}
+
+func (r *MemoryRepository) matching(filters []Filter) []models.GroceryItem {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var items []models.GroceryItem
+	for _, item := range r.items {
+		if Matches(item, filters) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Close is a no-op, the items live as long as the process
+func (r *MemoryRepository) Close() error {
+	return nil
+}
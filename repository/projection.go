@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"strings"
+
+	"example.com/capstone/models"
+)
+
+// projectedOnlyFields can be returned in responses but not filtered or sorted on
+var projectedOnlyFields = []Field{
+	{"imageURL", "Image", KindOther, func(i models.GroceryItem) interface{} { return i.Image }},
+	{"imageHash", "imageHash", KindOther, func(i models.GroceryItem) interface{} { return i.ImageHash }},
+	{"thumbnailURL", "Thumbnail", KindOther, func(i models.GroceryItem) interface{} { return i.Thumbnail }},
+	{"mfgDate", "MfgDate", KindOther, func(i models.GroceryItem) interface{} { return i.MfgDate }},
+	{"expDate", "ExpDate", KindOther, func(i models.GroceryItem) interface{} { return i.ExpDate }},
+}
+
+// LookupProjectionField finds a field that can be returned in a response
+func LookupProjectionField(name string) (Field, bool) {
+	if f, ok := LookupField(name); ok {
+		return f, true
+	}
+	for _, f := range projectedOnlyFields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// ParseFields parses a comma separated list of fields to return, e.g.
+// id,productName,price,brand,expDate. An empty value selects defaults.
+func ParseFields(value string, defaults ...string) ([]Field, error) {
+	names := defaults
+	if strings.TrimSpace(value) != "" {
+		names = strings.Split(value, ",")
+	}
+
+	var fields []Field
+	seen := map[string]bool{}
+	for _, name := range names {
+		field, ok := LookupProjectionField(strings.TrimSpace(name))
+		if !ok {
+			return nil, queryErrorf("unknown field %q in fields", strings.TrimSpace(name))
+		}
+		if !seen[field.Name] {
+			seen[field.Name] = true
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// Project returns the given fields of item keyed by their API names
+func Project(item models.GroceryItem, fields []Field) map[string]interface{} {
+	projected := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		value := f.Value(item)
+		if f.Kind == KindInteger && value != nil {
+			value = int(value.(float64))
+		}
+		projected[f.Name] = value
+	}
+	return projected
+}
+
+// WithFields adds extra fields to fields, skipping the ones already there
+func WithFields(fields []Field, extra ...string) []Field {
+	result := append([]Field(nil), fields...)
+	for _, name := range extra {
+		f, ok := LookupProjectionField(name)
+		if !ok {
+			continue
+		}
+		present := false
+		for _, existing := range result {
+			if existing.Name == f.Name {
+				present = true
+				break
+			}
+		}
+		if !present {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"example.com/capstone/models"
+)
+
+func TestProject(t *testing.T) {
+	fields, err := ParseFields("id,productName,stock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stock := 12
+
+	tests := []struct {
+		name string
+		item models.GroceryItem
+		want map[string]interface{}
+	}{
+		{"tracked stock", models.GroceryItem{ID: 1, ProductName: "Ghee", Stock: &stock}, map[string]interface{}{"id": 1, "productName": "Ghee", "stock": 12}},
+		{"untracked stock is null", models.GroceryItem{ID: 2, ProductName: "Atta"}, map[string]interface{}{"id": 2, "productName": "Atta", "stock": nil}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Project(test.item, fields); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
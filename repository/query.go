@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/capstone/models"
+)
+
+// Operator is a comparison used in a filter
+type Operator string
+
+const (
+	OpEq  Operator = "eq"
+	OpNe  Operator = "ne"
+	OpGt  Operator = "gt"
+	OpGte Operator = "gte"
+	OpLt  Operator = "lt"
+	OpLte Operator = "lte"
+	OpIn  Operator = "in"
+)
+
+// maxInValues is the largest number of values Firestore accepts in an in filter
+const maxInValues = 30
+
+// Filter compares a field with one value, or with a list of values for OpIn.
+// On a KindStringList field eq means "contains" and in means "contains any".
+type Filter struct {
+	Field  Field
+	Op     Operator
+	Values []interface{}
+}
+
+// QueryError is returned for a query that can not be run, the message is safe
+// to show to the client
+type QueryError struct {
+	msg string
+}
+
+func (e *QueryError) Error() string {
+	return e.msg
+}
+
+func queryErrorf(format string, args ...interface{}) error {
+	return &QueryError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ParseFilters parses the filters of a listing request. Keys take the form
+// field or field[op], e.g. price[gte]=10&price[lt]=50&vegetarian=true&category[in]=Snacks,Dairy.
+// price_min and price_max are accepted as aliases of price[gte] and price[lte].
+// Keys listed in reserved are request options and are skipped; any other key
+// that is not a queryable field is an error.
+func ParseFilters(values url.Values, reserved ...string) ([]Filter, error) {
+	skip := make(map[string]bool, len(reserved))
+	for _, key := range reserved {
+		skip[key] = true
+	}
+
+	// sorted so the filters, and the Firestore query built from them, don't
+	// depend on map order
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if !skip[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var filters []Filter
+	listFilters := 0
+	for _, key := range keys {
+		name, op, err := parseKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		field, ok := LookupField(name)
+		if !ok {
+			return nil, queryErrorf("unknown field %q", name)
+		}
+		if err := checkOperator(field, op); err != nil {
+			return nil, err
+		}
+
+		for _, raw := range values[key] {
+			filter := Filter{Field: field, Op: op}
+			parts := []string{raw}
+			if op == OpIn {
+				parts = strings.Split(raw, ",")
+				if len(parts) > maxInValues {
+					return nil, queryErrorf("%s[in] accepts at most %d values", field.Name, maxInValues)
+				}
+			}
+			for _, part := range parts {
+				value, err := parseValue(field, strings.TrimSpace(part))
+				if err != nil {
+					return nil, err
+				}
+				filter.Values = append(filter.Values, value)
+			}
+			if field.Kind == KindStringList {
+				listFilters++
+			}
+			filters = append(filters, filter)
+		}
+	}
+
+	// Firestore allows a single array-contains or array-contains-any per query
+	if listFilters > 1 {
+		return nil, queryErrorf("only one tags filter can be used at a time")
+	}
+
+	return filters, nil
+}
+
+func parseKey(key string) (string, Operator, error) {
+	switch key {
+	case "price_min":
+		return "price", OpGte, nil
+	case "price_max":
+		return "price", OpLte, nil
+	}
+
+	open := strings.IndexByte(key, '[')
+	if open < 0 {
+		return key, OpEq, nil
+	}
+	if !strings.HasSuffix(key, "]") {
+		return "", "", queryErrorf("malformed filter %q", key)
+	}
+
+	op := Operator(key[open+1 : len(key)-1])
+	switch op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn:
+		return key[:open], op, nil
+	}
+	return "", "", queryErrorf("unknown operator %q in %q, use one of eq, ne, gt, gte, lt, lte or in", op, key)
+}
+
+func checkOperator(field Field, op Operator) error {
+	switch field.Kind {
+	case KindBool:
+		if op != OpEq && op != OpNe {
+			return queryErrorf("%s only supports eq and ne", field.Name)
+		}
+	case KindStringList:
+		if op != OpEq && op != OpIn {
+			return queryErrorf("%s only supports eq and in", field.Name)
+		}
+	}
+	return nil
+}
+
+func parseValue(field Field, raw string) (interface{}, error) {
+	switch field.Kind {
+	case KindNumber:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, queryErrorf("%s must be a number, got %q", field.Name, raw)
+		}
+		return value, nil
+	case KindInteger:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, queryErrorf("%s must be an integer, got %q", field.Name, raw)
+		}
+		return value, nil
+	case KindBool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, queryErrorf("%s must be true or false, got %q", field.Name, raw)
+		}
+		return value, nil
+	}
+	return raw, nil
+}
+
+// Matches reports whether item passes the filter. It is the reference
+// semantics the Firestore compilation has to agree with.
+func (f Filter) Matches(item models.GroceryItem) bool {
+	value := f.Field.Value(item)
+	if value == nil {
+		return false
+	}
+
+	if f.Field.Kind == KindStringList {
+		for _, element := range value.([]string) {
+			for _, want := range f.Values {
+				if element == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	switch f.Op {
+	case OpIn:
+		for _, want := range f.Values {
+			if compareValues(value, want) == 0 {
+				return true
+			}
+		}
+		return false
+	case OpNe:
+		return compareValues(value, f.Values[0]) != 0
+	case OpGt:
+		return compareValues(value, f.Values[0]) > 0
+	case OpGte:
+		return compareValues(value, f.Values[0]) >= 0
+	case OpLt:
+		return compareValues(value, f.Values[0]) < 0
+	case OpLte:
+		return compareValues(value, f.Values[0]) <= 0
+	}
+	return compareValues(value, f.Values[0]) == 0
+}
+
+// filtersKey is a canonical text form of filters, for cache keys and fingerprints
+func filtersKey(filters []Filter) string {
+	var b strings.Builder
+	for _, f := range filters {
+		fmt.Fprintf(&b, "%s[%s]=%v;", f.Field.Name, f.Op, f.Values)
+	}
+	return b.String()
+}
+
+// Matches reports whether item passes every filter
+func Matches(item models.GroceryItem, filters []Filter) bool {
+	for _, f := range filters {
+		if !f.Matches(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareValues orders two field values of the same kind. Integers are
+// compared as numbers, strings byte-wise like Firestore does. A missing value
+// (nil, e.g. an untracked stock) comes first, as null does in Firestore.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch x := a.(type) {
+	case float64:
+		y := toFloat(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case bool:
+		y, _ := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case string:
+		return strings.Compare(x, fmt.Sprint(b))
+	}
+	return 0
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+// Package repository reads grocery items from the catalog backend and writes
+// them in bulk. Listing queries are described once with Query and compiled by
+// each backend, so Firestore and the in-memory store return the same items for
+// the same query.
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"example.com/capstone/models"
+)
+
+// ErrNotFound is returned by Get when no item has the requested ID
+var ErrNotFound = errors.New("grocery item not found")
+
+// GroceryItemRepository is a catalog backend
+type GroceryItemRepository interface {
+	List(ctx context.Context, q Query) ([]models.GroceryItem, error)
+	Count(ctx context.Context, filters []Filter) (int64, error)
+	Facets(ctx context.Context, filters []Filter, requests []FacetRequest) ([]FacetResult, error)
+	PriceStats(ctx context.Context, filters []Filter, groupBy Field) (PriceStatsResult, error)
+	ExpiryMonths(ctx context.Context, filters []Filter) ([]MonthCount, error)
+	Get(ctx context.Context, id int, fields ...Field) (models.GroceryItem, error)
+	GetMany(ctx context.Context, ids []int, fields ...Field) ([]models.GroceryItem, error)
+	// PriceChanges returns the recorded and scheduled price changes of an
+	// item, ordered by effective time
+	PriceChanges(ctx context.Context, itemID int) ([]models.PriceChange, error)
+
+	// AllocateIDs reserves n consecutive item IDs and returns the first one
+	AllocateIDs(ctx context.Context, n int) (int, error)
+	// CreateItems stores new items, which already have their IDs. The error
+	// at index i is for items[i], nil when it was stored.
+	CreateItems(ctx context.Context, items []models.GroceryItem) []error
+	// UpdateItems replaces the stored items having the IDs of items. The
+	// error at index i is for items[i], ErrNotFound when there is no such item.
+	UpdateItems(ctx context.Context, items []models.GroceryItem) []error
+	Close() error
+}
+
+// Query selects grocery items. Every filter has to match.
+//
+// Results are always in a total order: the Sort keys, then id. After holds the
+// sort values of the last item of the previous page (see SortValues), the page
+// then starts right after that item.
+//
+// Fields, when set, lets the backend read only those fields; the others are
+// left at their zero value. id and the sort fields are always read.
+type Query struct {
+	Filters []Filter
+	Sort    []SortKey
+	After   []interface{}
+	Offset  int
+	Limit   int // 0 means no limit
+	Fields  []Field
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"example.com/capstone/models"
+)
+
+// SortKey orders results by a field
+type SortKey struct {
+	Field Field
+	Desc  bool
+}
+
+// ParseSort parses a comma separated list of fields, each optionally
+// prefixed with - for descending order, e.g. category,-price,productName
+func ParseSort(value string) ([]SortKey, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		key := SortKey{}
+		if strings.HasPrefix(part, "-") {
+			key.Desc = true
+			part = part[1:]
+		} else {
+			part = strings.TrimPrefix(part, "+")
+		}
+
+		field, ok := LookupSortField(part)
+		if !ok {
+			return nil, queryErrorf("can't sort on %q", part)
+		}
+		if seen[field.Name] {
+			return nil, queryErrorf("%s is listed twice in sort", field.Name)
+		}
+		seen[field.Name] = true
+
+		key.Field = field
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// isInequality reports whether Firestore treats the filter as a range or
+// inequality filter, which constrains the order by clauses of the query
+func (f Filter) isInequality() bool {
+	switch f.Op {
+	case OpNe, OpGt, OpGte, OpLt, OpLte:
+		return f.Field.Kind != KindStringList
+	}
+	return false
+}
+
+// inequalityFields returns the names of the fields with inequality filters,
+// in filter order
+func inequalityFields(filters []Filter) []Field {
+	var result []Field
+	seen := map[string]bool{}
+	for _, f := range filters {
+		if f.isInequality() && !seen[f.Field.Name] {
+			seen[f.Field.Name] = true
+			result = append(result, f.Field)
+		}
+	}
+	return result
+}
+
+// effectiveSort is the order the results of q are returned in. Without an
+// explicit sort, results are ordered by the fields with inequality filters,
+// which is also how Firestore orders them. id is always the last key so the
+// order is total and cursors are stable.
+func effectiveSort(q Query) []SortKey {
+	keys := append([]SortKey(nil), q.Sort...)
+	if len(keys) == 0 {
+		for _, field := range inequalityFields(q.Filters) {
+			keys = append(keys, SortKey{Field: field})
+		}
+	}
+
+	for _, key := range keys {
+		if key.Field.Name == "id" {
+			return keys
+		}
+	}
+	id, _ := LookupField("id")
+	return append(keys, SortKey{Field: id})
+}
+
+// SortValues returns the position of item in the order of q. Passed as After
+// it makes the next query start right after item.
+func SortValues(q Query, item models.GroceryItem) []interface{} {
+	keys := effectiveSort(q)
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = key.Field.Value(item)
+	}
+	return values
+}
+
+// compareItems orders two items by keys
+func compareItems(keys []SortKey, a, b models.GroceryItem) int {
+	for _, key := range keys {
+		c := compareValues(key.Field.Value(a), key.Field.Value(b))
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// comparePosition compares item with the sort values of a cursor
+func comparePosition(keys []SortKey, item models.GroceryItem, position []interface{}) int {
+	for i, key := range keys {
+		if i >= len(position) {
+			return 0
+		}
+		c := compareValues(key.Field.Value(item), position[i])
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// applyQuery sorts items, which already passed the filters of q, and cuts the
+// requested page out of them. Only the in-memory backend uses it. Firestore
+// sorts on the server and CheckQuery rejects orders it can't serve.
+func applyQuery(items []models.GroceryItem, q Query) []models.GroceryItem {
+	keys := effectiveSort(q)
+	sort.SliceStable(items, func(i, j int) bool {
+		return compareItems(keys, items[i], items[j]) < 0
+	})
+
+	if len(q.After) > 0 {
+		start := sort.Search(len(items), func(i int) bool {
+			return comparePosition(keys, items[i], q.After) > 0
+		})
+		items = items[start:]
+	}
+
+	if q.Offset > 0 {
+		if q.Offset >= len(items) {
+			return []models.GroceryItem{}
+		}
+		items = items[q.Offset:]
+	}
+
+	if q.Limit > 0 && len(items) > q.Limit {
+		items = items[:q.Limit]
+	}
+	return items
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"example.com/capstone/models"
+)
+
+func TestListSortsMissingStockFirst(t *testing.T) {
+	stock := func(n int) *int { return &n }
+	repo := NewMemoryRepository(
+		models.GroceryItem{ID: 1, Stock: stock(5)},
+		models.GroceryItem{ID: 2},
+		models.GroceryItem{ID: 3, Stock: stock(0)},
+		models.GroceryItem{ID: 4},
+	)
+
+	tests := []struct {
+		sort string
+		want []int
+	}{
+		{"stock", []int{2, 4, 3, 1}},
+		{"-stock", []int{1, 3, 2, 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.sort, func(t *testing.T) {
+			keys, err := ParseSort(test.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+			items, err := repo.List(context.Background(), Query{Sort: keys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, item := range items {
+				got = append(got, item.ID)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
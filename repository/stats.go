@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"example.com/capstone/models"
+)
+
+// statsGroupFields are the fields price statistics can be grouped by
+var statsGroupFields = []string{"category", "brand", "countryOfOrigin"}
+
+// maxStatsGroups is the most groups price statistics are computed for. Each
+// group costs Firestore several queries, so past it the first groups in value
+// order are kept and the result is marked truncated.
+const maxStatsGroups = 50
+
+// PriceStats summarizes the prices of a group of items
+type PriceStats struct {
+	Group  string  `json:"group,omitempty"` // value of the grouping field, empty for all items
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Avg    float64 `json:"avg"`
+	Median float64 `json:"median"`
+}
+
+// PriceStatsResult has the price statistics of all matching items and of each
+// group, largest group first
+type PriceStatsResult struct {
+	GroupBy   string       `json:"groupBy"`
+	Overall   PriceStats   `json:"overall"`
+	Groups    []PriceStats `json:"groups"`
+	Truncated bool         `json:"truncated,omitempty"` // more than maxStatsGroups groups, the rest are left out
+}
+
+// MonthCount is the number of items expiring in a month, written 2006-01
+type MonthCount struct {
+	Month string `json:"month"`
+	Count int    `json:"count"`
+}
+
+// LookupStatsGroupField finds a field price statistics can be grouped by
+func LookupStatsGroupField(name string) (Field, error) {
+	for _, allowed := range statsGroupFields {
+		if name == allowed {
+			field, _ := LookupField(name)
+			return field, nil
+		}
+	}
+	return Field{}, queryErrorf("can't group by %q, use one of category, brand or countryOfOrigin", name)
+}
+
+// ComputePriceStats computes price statistics over items, grouped by groupBy.
+// Both backends use it, so the numbers only depend on the items matched.
+func ComputePriceStats(items []models.GroceryItem, groupBy Field) PriceStatsResult {
+	all := make([]float64, 0, len(items))
+	groups := map[string][]float64{}
+	for _, item := range items {
+		all = append(all, item.Price)
+		group := fmt.Sprint(groupBy.Value(item))
+		groups[group] = append(groups[group], item.Price)
+	}
+
+	names := make([]string, 0, len(groups))
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+
+	result := PriceStatsResult{GroupBy: groupBy.Name, Overall: priceStats("", all), Groups: []PriceStats{}}
+	if len(names) > maxStatsGroups {
+		names = names[:maxStatsGroups]
+		result.Truncated = true
+	}
+	for _, group := range names {
+		result.Groups = append(result.Groups, priceStats(group, groups[group]))
+	}
+	sortPriceGroups(result.Groups)
+	return result
+}
+
+// sortPriceGroups orders groups largest first
+func sortPriceGroups(groups []PriceStats) {
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Group < b.Group
+	})
+}
+
+func priceStats(group string, prices []float64) PriceStats {
+	stats := PriceStats{Group: group, Count: len(prices)}
+	if len(prices) == 0 {
+		return stats
+	}
+
+	sort.Float64s(prices)
+	var sum float64
+	for _, price := range prices {
+		sum += price
+	}
+
+	stats.Min = prices[0]
+	stats.Max = prices[len(prices)-1]
+	stats.Avg = RoundPrice(sum / float64(len(prices)))
+	mid := len(prices) / 2
+	if len(prices)%2 == 1 {
+		stats.Median = prices[mid]
+	} else {
+		stats.Median = RoundPrice((prices[mid-1] + prices[mid]) / 2)
+	}
+	return stats
+}
+
+// CountExpiryMonths counts items per expiry month, earliest first. Items
+// without an expiry date are left out.
+func CountExpiryMonths(items []models.GroceryItem) []MonthCount {
+	counts := map[string]int{}
+	for _, item := range items {
+		if item.ExpDate.Month == 0 {
+			continue
+		}
+		counts[fmt.Sprintf("%04d-%02d", item.ExpDate.Year, int(item.ExpDate.Month))]++
+	}
+
+	months := make([]MonthCount, 0, len(counts))
+	for month, count := range counts {
+		months = append(months, MonthCount{Month: month, Count: count})
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Month < months[j].Month })
+	return months
+}
+
+// RoundPrice rounds a computed price, e.g. an average or a discounted price, to two decimals
+func RoundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+
+	"example.com/capstone/models"
+)
+
+func TestComputePriceStatsTruncatesGroups(t *testing.T) {
+	brand, err := LookupStatsGroupField("brand")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []models.GroceryItem
+	for i := 0; i <= maxStatsGroups; i++ {
+		items = append(items, models.GroceryItem{ID: i + 1, Brand: fmt.Sprintf("brand %03d", i), Price: 10})
+	}
+
+	result := ComputePriceStats(items, brand)
+	if !result.Truncated || len(result.Groups) != maxStatsGroups {
+		t.Fatalf("got %d groups, truncated %v, want %d truncated", len(result.Groups), result.Truncated, maxStatsGroups)
+	}
+	if result.Overall.Count != len(items) {
+		t.Errorf("overall count is %d, want every item, %d", result.Overall.Count, len(items))
+	}
+	for _, group := range result.Groups {
+		if group.Group == fmt.Sprintf("brand %03d", maxStatsGroups) {
+			t.Errorf("the last group by value was kept")
+		}
+	}
+
+	result = ComputePriceStats(items[:maxStatsGroups], brand)
+	if result.Truncated || len(result.Groups) != maxStatsGroups {
+		t.Errorf("got %d groups, truncated %v, want all %d", len(result.Groups), result.Truncated, maxStatsGroups)
+	}
+}
@@ -0,0 +1,235 @@
+// Package search is an in-memory full-text index over the grocery catalog.
+// It matches keywords anywhere in the indexed fields, completes prefixes,
+// tolerates typos and ranks results by relevance.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"example.com/capstone/models"
+)
+
+// fieldWeights is how much a match in each indexed field counts
+var fieldWeights = []struct {
+	weight float64
+	value  func(item models.GroceryItem) string
+}{
+	{3, func(i models.GroceryItem) string { return i.ProductName }},
+	{2, func(i models.GroceryItem) string { return i.Brand }},
+	{1.5, func(i models.GroceryItem) string { return i.Category }},
+	{1, func(i models.GroceryItem) string { return i.Manufacturer }},
+	{0.5, func(i models.GroceryItem) string { return i.PackageInformation }},
+}
+
+// how much weaker prefix and typo matches are than an exact match
+const (
+	prefixMatchFactor = 0.8
+	typoMatchFactor   = 0.5
+)
+
+// Hit is a matching item and its relevance
+type Hit struct {
+	Item  models.GroceryItem
+	Score float64
+}
+
+// Index is safe for concurrent use
+type Index struct {
+	mu       sync.RWMutex
+	items    map[int]models.GroceryItem
+	postings map[string]map[int]float64 // term -> item ID -> weighted term frequency
+	terms    []string                   // sorted vocabulary, for prefix lookups
+	dirty    bool                       // terms needs to be rebuilt from postings
+}
+
+func NewIndex() *Index {
+	return &Index{
+		items:    map[int]models.GroceryItem{},
+		postings: map[string]map[int]float64{},
+	}
+}
+
+// Rebuild replaces the content of the index with items
+func (x *Index) Rebuild(items []models.GroceryItem) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.items = make(map[int]models.GroceryItem, len(items))
+	x.postings = map[string]map[int]float64{}
+	for _, item := range items {
+		x.add(item)
+	}
+	x.dirty = true
+}
+
+// Add indexes item, replacing the item with the same ID
+func (x *Index) Add(item models.GroceryItem) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(item.ID)
+	x.add(item)
+	x.dirty = true
+}
+
+// Remove drops the item with the given ID from the index
+func (x *Index) Remove(id int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(id)
+	x.dirty = true
+}
+
+// Len is the number of indexed items
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.items)
+}
+
+func (x *Index) add(item models.GroceryItem) {
+	x.items[item.ID] = item
+	for _, field := range fieldWeights {
+		for _, term := range Tokenize(field.value(item)) {
+			if x.postings[term] == nil {
+				x.postings[term] = map[int]float64{}
+			}
+			x.postings[term][item.ID] += field.weight
+		}
+	}
+}
+
+func (x *Index) remove(id int) {
+	item, ok := x.items[id]
+	if !ok {
+		return
+	}
+	delete(x.items, id)
+
+	for _, field := range fieldWeights {
+		for _, term := range Tokenize(field.value(item)) {
+			delete(x.postings[term], id)
+			if len(x.postings[term]) == 0 {
+				delete(x.postings, term)
+			}
+		}
+	}
+}
+
+// Search returns the items matching query, best first. Items matching every
+// query term come first; when no item matches all of them, items matching
+// some are returned. keep, if not nil, filters the candidates.
+func (x *Index) Search(query string, keep func(item models.GroceryItem) bool) []Hit {
+	queryTerms := Tokenize(query)
+	if len(queryTerms) == 0 {
+		return []Hit{}
+	}
+
+	x.sortTerms()
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	scores := map[int]float64{}
+	matched := map[int]int{}
+	for _, queryTerm := range queryTerms {
+		for id, score := range x.scoreTerm(queryTerm) {
+			scores[id] += score
+			matched[id]++
+		}
+	}
+
+	all := false
+	for _, count := range matched {
+		if count == len(queryTerms) {
+			all = true
+			break
+		}
+	}
+
+	hits := []Hit{}
+	for id, score := range scores {
+		if all && matched[id] < len(queryTerms) {
+			continue
+		}
+		item := x.items[id]
+		if keep != nil && !keep(item) {
+			continue
+		}
+		hits = append(hits, Hit{Item: item, Score: math.Round(score*1000) / 1000})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if matched[hits[i].Item.ID] != matched[hits[j].Item.ID] {
+			return matched[hits[i].Item.ID] > matched[hits[j].Item.ID]
+		}
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Item.ID < hits[j].Item.ID
+	})
+	return hits
+}
+
+// scoreTerm scores every item containing queryTerm, a word starting with it
+// or a word within a few typos of it. An item only gets the score of its
+// best matching word.
+func (x *Index) scoreTerm(queryTerm string) map[int]float64 {
+	scores := map[int]float64{}
+	credit := func(term string, factor float64) {
+		postings := x.postings[term]
+		idf := math.Log(1 + float64(len(x.items))/float64(len(postings)))
+		for id, tf := range postings {
+			if score := factor * idf * tf; score > scores[id] {
+				scores[id] = score
+			}
+		}
+	}
+
+	credit(queryTerm, 1)
+
+	if len(queryTerm) >= 2 {
+		start := sort.SearchStrings(x.terms, queryTerm)
+		for _, term := range x.terms[start:] {
+			if !strings.HasPrefix(term, queryTerm) {
+				break
+			}
+			if term != queryTerm {
+				credit(term, prefixMatchFactor)
+			}
+		}
+	}
+
+	if limit := maxEdits(queryTerm); limit > 0 {
+		for _, term := range x.terms {
+			if term == queryTerm || strings.HasPrefix(term, queryTerm) {
+				continue
+			}
+			if d := editDistance(queryTerm, term, limit); d <= limit {
+				credit(term, typoMatchFactor/float64(d))
+			}
+		}
+	}
+
+	return scores
+}
+
+// sortTerms rebuilds the sorted vocabulary after changes
+func (x *Index) sortTerms() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if !x.dirty {
+		return
+	}
+	x.terms = x.terms[:0]
+	for term := range x.postings {
+		x.terms = append(x.terms, term)
+	}
+	sort.Strings(x.terms)
+	x.dirty = false
+}
@@ -0,0 +1,491 @@
+package search
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"example.com/capstone/models"
+)
+
+// Suggestion types
+const (
+	SuggestProduct  = "product"
+	SuggestBrand    = "brand"
+	SuggestCategory = "category"
+)
+
+const (
+	// MaxSuggestions is the most completions Suggest returns
+	MaxSuggestions = 20
+
+	// topCapacity is how many ranked entries a trie node caches. It is larger
+	// than MaxSuggestions so a few removals don't force a recount of the subtree.
+	topCapacity = 2 * MaxSuggestions
+
+	// maxTrieDepth caps the trie depth. Longer prefixes stop at this depth and
+	// the candidates below it are checked against the whole prefix, which keeps
+	// the trie small for long product names.
+	maxTrieDepth = 12
+)
+
+// Suggestion is a completion of a typed prefix
+type Suggestion struct {
+	Text      string `json:"text"`
+	Type      string `json:"type"`             // product, brand or category
+	ItemID    int    `json:"itemID,omitempty"` // the product, for product suggestions
+	ItemCount int    `json:"itemCount"`        // items of the brand or category, 1 for products
+}
+
+// suggestEntry is something that can be suggested. Its normalized text is
+// reachable in the trie from the start of every word, so "oil" completes to
+// "Baby Care Oil".
+type suggestEntry struct {
+	kind   string
+	text   string
+	norm   string
+	starts []int // byte offsets of the words of norm
+	itemID int
+	count  int
+}
+
+// suggestPosting is an entry reached through the word starting at offset
+type suggestPosting struct {
+	entry  *suggestEntry
+	offset int
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	postings []suggestPosting // keys ending here, at maxTrieDepth also longer keys
+
+	// top is the exact ranking of the best entries of the subtree, one posting
+	// per entry, and complete is set when it holds every entry of the subtree.
+	// Changes keep it exact; it is only recounted once removals leave it with
+	// fewer than MaxSuggestions entries while others are missing from it.
+	top      []suggestPosting
+	topValid bool
+	complete bool
+}
+
+// Suggester completes prefixes of product names, brands and categories. It is
+// safe for concurrent use.
+type Suggester struct {
+	mu      sync.Mutex
+	root    *trieNode
+	items   map[int]models.GroceryItem
+	entries map[string]*suggestEntry // by kind and normalized text, products by ID
+}
+
+func NewSuggester() *Suggester {
+	return &Suggester{
+		root:    &trieNode{},
+		items:   map[int]models.GroceryItem{},
+		entries: map[string]*suggestEntry{},
+	}
+}
+
+// Rebuild replaces the content of the suggester with items
+func (s *Suggester) Rebuild(items []models.GroceryItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.root = &trieNode{}
+	s.items = make(map[int]models.GroceryItem, len(items))
+	s.entries = map[string]*suggestEntry{}
+	for _, item := range items {
+		s.add(item)
+	}
+}
+
+// Add makes item suggestible, replacing the item with the same ID
+func (s *Suggester) Add(item models.GroceryItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(item.ID)
+	s.add(item)
+}
+
+// Remove forgets the item with the given ID
+func (s *Suggester) Remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+}
+
+// Suggest returns up to limit completions of prefix. Completions of the whole
+// text rank before completions of a later word, brands and categories before
+// products, then bigger brands and categories first.
+func (s *Suggester) Suggest(prefix string, limit int) []Suggestion {
+	if limit <= 0 || limit > MaxSuggestions {
+		limit = MaxSuggestions
+	}
+
+	query := normalize(prefix)
+	if query == "" {
+		return []Suggestion{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	node := s.root
+	depth := 0
+	for _, r := range query {
+		if depth == maxTrieDepth {
+			break
+		}
+		node = node.children[r]
+		if node == nil {
+			return []Suggestion{}
+		}
+		depth++
+	}
+
+	var best []suggestPosting
+	if len([]rune(query)) <= maxTrieDepth {
+		best = node.best()
+	} else {
+		// the trie only knows the first maxTrieDepth runes, check the rest
+		var candidates []suggestPosting
+		node.collect(func(p suggestPosting) {
+			if strings.HasPrefix(p.entry.norm[p.offset:], query) {
+				candidates = append(candidates, p)
+			}
+		})
+		best = rankPostings(candidates)
+	}
+
+	suggestions := make([]Suggestion, 0, limit)
+	for _, p := range best {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, Suggestion{
+			Text:      p.entry.text,
+			Type:      p.entry.kind,
+			ItemID:    p.entry.itemID,
+			ItemCount: p.entry.count,
+		})
+	}
+	return suggestions
+}
+
+func (s *Suggester) add(item models.GroceryItem) {
+	s.items[item.ID] = item
+
+	if norm := normalize(item.ProductName); norm != "" {
+		entry := newSuggestEntry(SuggestProduct, item.ProductName, norm)
+		entry.itemID = item.ID
+		entry.count = 1
+		s.entries[productKey(item.ID)] = entry
+		s.insert(entry)
+	}
+
+	s.addGroup(SuggestBrand, item.Brand)
+	s.addGroup(SuggestCategory, item.Category)
+}
+
+func (s *Suggester) remove(id int) {
+	item, ok := s.items[id]
+	if !ok {
+		return
+	}
+	delete(s.items, id)
+
+	if entry, ok := s.entries[productKey(id)]; ok {
+		delete(s.entries, productKey(id))
+		s.delete(entry)
+	}
+
+	s.removeGroup(SuggestBrand, item.Brand)
+	s.removeGroup(SuggestCategory, item.Category)
+}
+
+// addGroup counts one more item for a brand or category
+func (s *Suggester) addGroup(kind, text string) {
+	norm := normalize(text)
+	if norm == "" {
+		return
+	}
+
+	entry, ok := s.entries[kind+"|"+norm]
+	if !ok {
+		entry = newSuggestEntry(kind, strings.TrimSpace(text), norm)
+		entry.count = 1
+		s.entries[kind+"|"+norm] = entry
+		s.insert(entry)
+		return
+	}
+
+	// a bigger group ranks higher, merging it again keeps the cached tops right
+	entry.count++
+	s.insert(entry)
+}
+
+// removeGroup counts one item less for a brand or category
+func (s *Suggester) removeGroup(kind, text string) {
+	norm := normalize(text)
+	entry, ok := s.entries[kind+"|"+norm]
+	if !ok {
+		return
+	}
+
+	entry.count--
+	if entry.count > 0 {
+		s.walkKeys(entry, func(node *trieNode, last bool) {
+			node.demote(entry, true)
+		})
+		return
+	}
+
+	delete(s.entries, kind+"|"+norm)
+	s.delete(entry)
+}
+
+func (s *Suggester) insert(entry *suggestEntry) {
+	for _, offset := range entry.starts {
+		posting := suggestPosting{entry: entry, offset: offset}
+		node := s.root
+		depth := 0
+		for _, r := range entry.norm[offset:] {
+			if depth == maxTrieDepth {
+				break
+			}
+			if node.children == nil {
+				node.children = map[rune]*trieNode{}
+			}
+			child := node.children[r]
+			if child == nil {
+				// an empty subtree has an empty top, unless the parent isn't
+				// maintaining its top either, as during a rebuild
+				child = &trieNode{topValid: node.topValid, complete: true}
+				node.children[r] = child
+			}
+			node.promote(posting)
+			node = child
+			depth++
+		}
+		node.promote(posting)
+		if !containsPosting(node.postings, posting) {
+			node.postings = append(node.postings, posting)
+		}
+	}
+}
+
+func (s *Suggester) delete(entry *suggestEntry) {
+	s.walkKeys(entry, func(node *trieNode, last bool) {
+		node.demote(entry, false)
+		if last {
+			node.postings = removeEntry(node.postings, entry)
+		}
+	})
+}
+
+// walkKeys visits the nodes on the path of every key of entry, last is set
+// for the node holding the posting
+func (s *Suggester) walkKeys(entry *suggestEntry, visit func(node *trieNode, last bool)) {
+	for _, offset := range entry.starts {
+		node := s.root
+		depth := 0
+		for _, r := range entry.norm[offset:] {
+			if depth == maxTrieDepth {
+				break
+			}
+			visit(node, false)
+			node = node.children[r]
+			if node == nil {
+				break
+			}
+			depth++
+		}
+		if node != nil {
+			visit(node, true)
+		}
+	}
+}
+
+// best returns the ranked postings of the subtree, recounting them if
+// removals left too few cached
+func (n *trieNode) best() []suggestPosting {
+	if !n.topValid {
+		var all []suggestPosting
+		n.collect(func(p suggestPosting) { all = append(all, p) })
+		n.top = rankPostings(all)
+		n.complete = len(n.top) <= topCapacity
+		if !n.complete {
+			n.top = n.top[:topCapacity]
+		}
+		n.topValid = true
+	}
+	return n.top
+}
+
+// promote updates the cached top after the entry of p was added to the
+// subtree or started to rank higher
+func (n *trieNode) promote(p suggestPosting) {
+	if !n.topValid {
+		return
+	}
+
+	if i := indexOfEntry(n.top, p.entry); i >= 0 {
+		if n.top[i].offset < p.offset {
+			p = n.top[i]
+		}
+		n.top = append(n.top[:i], n.top[i+1:]...)
+	} else if !n.complete && (len(n.top) == 0 || !lessPosting(p, n.top[len(n.top)-1])) {
+		// entries missing from the top may rank above p
+		return
+	}
+
+	n.top = insertRanked(n.top, p)
+	if len(n.top) > topCapacity {
+		n.top = n.top[:topCapacity]
+		n.complete = false
+	}
+}
+
+// demote updates the cached top after entry was removed from the subtree, or
+// started to rank lower when still is set
+func (n *trieNode) demote(entry *suggestEntry, still bool) {
+	if !n.topValid {
+		return
+	}
+
+	i := indexOfEntry(n.top, entry)
+	if i < 0 {
+		// it already ranked below everything cached
+		return
+	}
+	p := n.top[i]
+	n.top = append(n.top[:i], n.top[i+1:]...)
+
+	// it stays in the top only where nothing missing from it can outrank it
+	if still && (n.complete || (len(n.top) > 0 && lessPosting(p, n.top[len(n.top)-1]))) {
+		n.top = insertRanked(n.top, p)
+	}
+
+	if !n.complete && len(n.top) < MaxSuggestions {
+		n.topValid = false
+	}
+}
+
+func (n *trieNode) collect(visit func(p suggestPosting)) {
+	for _, p := range n.postings {
+		visit(p)
+	}
+	for _, child := range n.children {
+		child.collect(visit)
+	}
+}
+
+// rankPostings keeps the best posting of every entry and orders them
+func rankPostings(postings []suggestPosting) []suggestPosting {
+	best := map[*suggestEntry]suggestPosting{}
+	for _, p := range postings {
+		if current, ok := best[p.entry]; !ok || p.offset < current.offset {
+			best[p.entry] = p
+		}
+	}
+
+	ranked := make([]suggestPosting, 0, len(best))
+	for _, p := range best {
+		ranked = append(ranked, p)
+	}
+	sort.Slice(ranked, func(i, j int) bool { return lessPosting(ranked[i], ranked[j]) })
+	return ranked
+}
+
+func insertRanked(ranked []suggestPosting, p suggestPosting) []suggestPosting {
+	i := sort.Search(len(ranked), func(i int) bool { return lessPosting(p, ranked[i]) })
+	ranked = append(ranked, suggestPosting{})
+	copy(ranked[i+1:], ranked[i:])
+	ranked[i] = p
+	return ranked
+}
+
+func lessPosting(a, b suggestPosting) bool {
+	if (a.offset == 0) != (b.offset == 0) {
+		return a.offset == 0
+	}
+	if kindRank(a.entry.kind) != kindRank(b.entry.kind) {
+		return kindRank(a.entry.kind) < kindRank(b.entry.kind)
+	}
+	if a.entry.count != b.entry.count {
+		return a.entry.count > b.entry.count
+	}
+	if len(a.entry.norm) != len(b.entry.norm) {
+		return len(a.entry.norm) < len(b.entry.norm)
+	}
+	if a.entry.norm != b.entry.norm {
+		return a.entry.norm < b.entry.norm
+	}
+	return a.entry.itemID < b.entry.itemID
+}
+
+func kindRank(kind string) int {
+	switch kind {
+	case SuggestBrand:
+		return 0
+	case SuggestCategory:
+		return 1
+	}
+	return 2
+}
+
+func indexOfEntry(postings []suggestPosting, entry *suggestEntry) int {
+	for i, p := range postings {
+		if p.entry == entry {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsPosting(postings []suggestPosting, posting suggestPosting) bool {
+	for _, p := range postings {
+		if p == posting {
+			return true
+		}
+	}
+	return false
+}
+
+func removeEntry(postings []suggestPosting, entry *suggestEntry) []suggestPosting {
+	kept := postings[:0]
+	for _, p := range postings {
+		if p.entry != entry {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func newSuggestEntry(kind, text, norm string) *suggestEntry {
+	entry := &suggestEntry{kind: kind, text: text, norm: norm}
+	for i := 0; i < len(norm); i++ {
+		if i == 0 || norm[i-1] == ' ' {
+			entry.starts = append(entry.starts, i)
+		}
+	}
+	return entry
+}
+
+func productKey(id int) string {
+	return SuggestProduct + "|" + strconv.Itoa(id)
+}
+
+// apostrophes are dropped so haldirams completes to Haldiram's
+var apostrophes = strings.NewReplacer("'", "", "’", "")
+
+// normalize lower cases text and keeps its words separated by single spaces
+func normalize(text string) string {
+	words := strings.FieldsFunc(apostrophes.Replace(strings.ToLower(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/capstone/models"
+)
+
+// benchmarkItems is about the size of a large catalog
+const benchmarkItems = 100000
+
+func catalogOf(n int) []models.GroceryItem {
+	brands := []string{"Amul", "Haldiram's", "Tata", "Britannia", "Nestle", "Fortune", "Aashirvaad", "Patanjali"}
+	categories := []string{"Dairy", "Snacks", "Beverages", "Staples", "Personal Care", "Baby Care"}
+	products := []string{"Bhujia", "Basmati Rice", "Green Tea", "Ghee", "Baby Care Oil", "Digestive Biscuits", "Paneer", "Atta"}
+
+	items := make([]models.GroceryItem, n)
+	for i := range items {
+		items[i] = models.GroceryItem{
+			ID:          i + 1,
+			ProductName: fmt.Sprintf("%s %s %d", brands[i%len(brands)], products[i%len(products)], i),
+			Brand:       brands[i%len(brands)],
+			Category:    categories[i%len(categories)],
+		}
+	}
+	return items
+}
+
+func BenchmarkSuggesterRebuild(b *testing.B) {
+	items := catalogOf(benchmarkItems)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		NewSuggester().Rebuild(items)
+	}
+}
+
+func BenchmarkSuggest(b *testing.B) {
+	s := NewSuggester()
+	s.Rebuild(catalogOf(benchmarkItems))
+	prefixes := []string{"h", "hal", "baby c", "tata green", "amul ghee 12"}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		s.Suggest(prefixes[n%len(prefixes)], 8)
+	}
+}
+
+// BenchmarkSuggestDuringRebuild measures suggestions while the catalog is
+// rebuilt over and over, and reports the slowest one. Rebuilding in place
+// holds the suggester for the whole rebuild, building a new one and swapping
+// it in doesn't.
+func BenchmarkSuggestDuringRebuild(b *testing.B) {
+	items := catalogOf(benchmarkItems)
+
+	b.Run("in place", func(b *testing.B) {
+		s := NewSuggester()
+		s.Rebuild(items)
+		benchmarkDuringRebuild(b, func() *Suggester { return s }, func() { s.Rebuild(items) })
+	})
+
+	b.Run("swapped", func(b *testing.B) {
+		var current atomic.Pointer[Suggester]
+		current.Store(NewSuggester())
+		current.Load().Rebuild(items)
+		benchmarkDuringRebuild(b, current.Load, func() {
+			next := NewSuggester()
+			next.Rebuild(items)
+			current.Store(next)
+		})
+	})
+}
+
+func benchmarkDuringRebuild(b *testing.B, load func() *Suggester, rebuild func()) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				rebuild()
+			}
+		}
+	}()
+
+	var slowest time.Duration
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		start := time.Now()
+		load().Suggest("hal", 8)
+		slowest = max(slowest, time.Since(start))
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(slowest.Microseconds()), "slowest-µs")
+
+	close(stop)
+	<-done
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"example.com/capstone/exporter"
+	"example.com/capstone/importer"
+)
+
+// DataBucket is the bucket holding catalog data files
+const DataBucket = "cloudbucketanaghaaaa"
+
+var (
+	memoryJobStore     *importer.MemoryJobStore
+	memoryJobStoreOnce sync.Once
+
+	memoryIngestLedger     *importer.MemoryIngestLedger
+	memoryIngestLedgerOnce sync.Once
+
+	memoryExportJobStore     *exporter.MemoryJobStore
+	memoryExportJobStoreOnce sync.Once
+)
+
+// NewImporter sets up an importer writing to the catalog backend, release
+// closes the backend
+func NewImporter() (*importer.Importer, func(), error) {
+	repo, err := CreateGroceryRepository()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &importer.Importer{Repo: repo}, func() { repo.Close() }, nil
+}
+
+// CreateImportJobStore returns where import jobs are kept: Firestore, or
+// memory when CATALOG_BACKEND is memory
+func CreateImportJobStore() (importer.JobStore, error) {
+	if os.Getenv("CATALOG_BACKEND") == "memory" {
+		memoryJobStoreOnce.Do(func() {
+			memoryJobStore = importer.NewMemoryJobStore()
+		})
+		return memoryJobStore, nil
+	}
+
+	client, err := CreateFirestoreClient()
+	if err != nil {
+		return nil, err
+	}
+	return importer.NewFirestoreJobStore(client), nil
+}
+
+// CreateBlobStore returns where import and export files are kept: the data
+// bucket, or the directory in BLOB_DIR. With CATALOG_BACKEND set to memory it
+// defaults to a directory under the system temp directory.
+func CreateBlobStore() (importer.BlobStore, error) {
+	dir := os.Getenv("BLOB_DIR")
+	if dir == "" && os.Getenv("CATALOG_BACKEND") == "memory" {
+		dir = filepath.Join(os.TempDir(), "capstone-blobs")
+	}
+	if dir != "" {
+		return importer.NewDirBlobStore(dir), nil
+	}
+
+	client, err := CreateStorageClient()
+	if err != nil {
+		return nil, err
+	}
+	return importer.NewBucketBlobStore(client, DataBucket), nil
+}
+
+// CreateIngestLedger returns the record of the data files ingested so far:
+// Firestore, or memory when CATALOG_BACKEND is memory
+func CreateIngestLedger() (importer.IngestLedger, error) {
+	if os.Getenv("CATALOG_BACKEND") == "memory" {
+		memoryIngestLedgerOnce.Do(func() {
+			memoryIngestLedger = importer.NewMemoryIngestLedger()
+		})
+		return memoryIngestLedger, nil
+	}
+
+	client, err := CreateFirestoreClient()
+	if err != nil {
+		return nil, err
+	}
+	return importer.NewFirestoreIngestLedger(client), nil
+}
+
+// CreateExportJobStore returns where export jobs are kept: Firestore, or
+// memory when CATALOG_BACKEND is memory
+func CreateExportJobStore() (exporter.JobStore, error) {
+	if os.Getenv("CATALOG_BACKEND") == "memory" {
+		memoryExportJobStoreOnce.Do(func() {
+			memoryExportJobStore = exporter.NewMemoryJobStore()
+		})
+		return memoryExportJobStore, nil
+	}
+
+	client, err := CreateFirestoreClient()
+	if err != nil {
+		return nil, err
+	}
+	return exporter.NewFirestoreJobStore(client), nil
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+
+	"example.com/capstone/models"
+	"example.com/capstone/repository"
+)
+
+var (
+	memoryRepository     *repository.MemoryRepository
+	memoryRepositoryErr  error
+	memoryRepositoryOnce sync.Once
+)
+
+// CreateGroceryRepository returns the catalog backend. It is Firestore unless
+// CATALOG_BACKEND is set to memory, in which case a single in-memory catalog is
+// shared by the whole process, seeded from the JSON array in CATALOG_SEED_FILE
+// if that is set. WRITE_CONCURRENCY sets how many batches Firestore bulk
+// writes commit at a time.
+func CreateGroceryRepository() (repository.GroceryItemRepository, error) {
+	if os.Getenv("CATALOG_BACKEND") == "memory" {
+		memoryRepositoryOnce.Do(func() {
+			memoryRepository, memoryRepositoryErr = loadMemoryRepository(os.Getenv("CATALOG_SEED_FILE"))
+		})
+		if memoryRepositoryErr != nil {
+			return nil, memoryRepositoryErr
+		}
+		return memoryRepository, nil
+	}
+
+	client, err := CreateFirestoreClient()
+	if err != nil {
+		return nil, err
+	}
+	repo := repository.NewFirestoreRepository(client)
+	if concurrency, err := strconv.Atoi(os.Getenv("WRITE_CONCURRENCY")); err == nil && concurrency > 0 {
+		opts := repository.DefaultWriteOptions
+		opts.Concurrency = concurrency
+		repo.SetWriteOptions(opts)
+	}
+	return repo, nil
+}
+
+func loadMemoryRepository(seedFile string) (*repository.MemoryRepository, error) {
+	if seedFile == "" {
+		return repository.NewMemoryRepository(), nil
+	}
+
+	data, err := os.ReadFile(seedFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.GroceryItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	return repository.NewMemoryRepository(items...), nil
+}